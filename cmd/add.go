@@ -2,71 +2,89 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/ignore"
 	"github.com/tejastn10/quill/pkg/index"
 	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/worktree"
 )
 
 var addCmd = &cobra.Command{
-	Use:   "add [files...]",
+	Use:   "add [-A | -u] [files...]",
 	Short: "Add file contents to the staging area",
-	Long:  "Add file contents to the staging area to be included in the next commit.",
-	Args:  cobra.MinimumNArgs(1),
+	Long:  "Add file contents to the staging area to be included in the next commit. Adding a directory also stages the removal of tracked files below it that no longer exist. With -A every change in the working tree is staged, including new and removed files; with -u only tracked files are updated and removed. Paths matched by .quillignore are skipped unless --force is given.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		update, _ := cmd.Flags().GetBool("update")
+
+		// -A and -u default to the whole working tree
+		if all || update {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fmt.Errorf("failed to get all flag: %v", err)
+		}
+
+		update, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			return fmt.Errorf("failed to get update flag: %v", err)
+		}
+
+		if all && update {
+			return fmt.Errorf("-A and -u cannot be used together")
+		}
+
 		// Locate the repository root.
 		repoPath, err := repo.FindRepoRoot()
 		if err != nil {
 			return fmt.Errorf("failed to locate repository: %v", err)
 		}
 
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		// Load the index.
 		idx, err := index.LoadIndex(repoPath)
 		if err != nil {
 			return fmt.Errorf("failed to load index: %v", err)
 		}
 
+		ignores := ignore.NewMatcher(repoPath)
+
+		if len(args) == 0 {
+			args = []string{repoPath}
+		}
+
 		// Process each file or directory.
 		for _, arg := range args {
-			// Resolve the absolute path.
-			absPath, err := filepath.Abs(arg)
+			// Resolve the path relative to the repository root, the repository metadata can never be staged
+			relPath, err := resolvePathspec(repoPath, arg)
 			if err != nil {
-				return fmt.Errorf("failed to resolve path for %q: %v", arg, err)
+				return err
 			}
 
-			// Add file or directory to the index.
-			info, err := os.Stat(absPath)
+			removed, err := worktree.AddPath(repoPath, idx, ignores, relPath, worktree.AddOptions{Force: force, Update: update})
 			if err != nil {
-				return fmt.Errorf("failed to stat %q: %v", absPath, err)
+				return err
 			}
 
-			if info.IsDir() {
-				// Recursively add files in the directory.
-				err = filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-
-					if !info.IsDir() {
-						err = idx.AddFile(repoPath, path)
-						if err != nil {
-							return fmt.Errorf("failed to add %q: %v", path, err)
-						}
-					}
-
-					return nil
-				})
-				if err != nil {
-					return fmt.Errorf("failed to add directory %q: %v", absPath, err)
-				}
-			} else {
-				// Add a single file.
-				err = idx.AddFile(repoPath, absPath)
-				if err != nil {
-					return fmt.Errorf("failed to add %q: %v", absPath, err)
-				}
+			for _, path := range removed {
+				fmt.Printf("Removed %q from staging area\n", path)
 			}
 		}
 
@@ -81,7 +99,16 @@ var addCmd = &cobra.Command{
 	},
 }
 
+// isQuillPath reports whether a path relative to the repository root lies inside the .quill directory
+func isQuillPath(relPath string) bool {
+	first, _, _ := strings.Cut(filepath.ToSlash(relPath), "/")
+	return first == ".quill"
+}
+
 func init() {
 	// Registering the add command with the root command
 	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().BoolP("force", "f", false, "Allow adding otherwise ignored files")
+	addCmd.Flags().BoolP("all", "A", false, "Stage all changes in the working tree, including new and removed files")
+	addCmd.Flags().BoolP("update", "u", false, "Stage modifications and removals of tracked files only")
 }
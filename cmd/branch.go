@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch [<name> [<start-point>]]",
+	Short: "List, create, rename or delete branches",
+	Long:  "With no arguments, list existing branches. With a name, create a branch at HEAD or at the given revision. Use -d to delete and -m to rename branches.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		deleteBranch, err := cmd.Flags().GetBool("delete")
+		if err != nil {
+			return fmt.Errorf("failed to get delete flag: %v", err)
+		}
+
+		moveBranch, err := cmd.Flags().GetBool("move")
+		if err != nil {
+			return fmt.Errorf("failed to get move flag: %v", err)
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		switch {
+		case deleteBranch && moveBranch:
+			return fmt.Errorf("-d and -m cannot be used together")
+		case deleteBranch:
+			return deleteBranches(repoPath, args)
+		case moveBranch:
+			return renameBranch(repoPath, args, force)
+		case len(args) == 0:
+			return listBranches(repoPath)
+		case len(args) <= 2:
+			return createBranch(repoPath, args, force)
+		default:
+			return fmt.Errorf("too many arguments")
+		}
+	},
+}
+
+// listBranches prints every branch, marking the checked out one
+func listBranches(repoPath string) error {
+	branches, err := refs.ListBranches(repoPath)
+	if err != nil {
+		return err
+	}
+
+	current, err := refs.CurrentBranch(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %v", err)
+	}
+
+	// A detached HEAD is listed first, like git does
+	if current == "" {
+		headHash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %v", err)
+		}
+		fmt.Printf("* \033[32m(HEAD detached at %s)\033[0m\n", objects.ShortHash(headHash))
+	}
+
+	for _, branch := range branches {
+		if branch == current {
+			fmt.Printf("* \033[32m%s\033[0m\n", branch)
+		} else {
+			fmt.Printf("  %s\n", branch)
+		}
+	}
+
+	return nil
+}
+
+// createBranch creates a branch at HEAD or at the given start commit
+func createBranch(repoPath string, args []string, force bool) error {
+	name := args[0]
+
+	var startHash string
+	if len(args) == 2 {
+		commitHash, err := revparse.ResolveCommit(repoPath, args[1])
+		if err != nil {
+			return fmt.Errorf("not a valid commit: %s: %v", args[1], err)
+		}
+		startHash = commitHash
+	} else {
+		headHash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %v", err)
+		}
+
+		if headHash == "" {
+			return fmt.Errorf("cannot create branch %q: no commits yet", name)
+		}
+		startHash = headHash
+	}
+
+	// Refuse to move the checked out branch out from under the working tree
+	current, err := refs.CurrentBranch(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %v", err)
+	}
+
+	if force && current == name {
+		return fmt.Errorf("cannot force update the current branch %q", name)
+	}
+
+	err = refs.CreateBranch(repoPath, name, startHash, force)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created branch %s at %s\n", name, objects.ShortHash(startHash))
+	return nil
+}
+
+// deleteBranches removes each of the named branches
+func deleteBranches(repoPath string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("branch name required")
+	}
+
+	for _, name := range names {
+		hash, err := refs.ReadBranch(repoPath, name)
+		if err != nil {
+			return err
+		}
+
+		err = refs.DeleteBranch(repoPath, name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleted branch %s (was %s)\n", name, objects.ShortHash(hash))
+	}
+
+	return nil
+}
+
+// renameBranch renames the given branch, or the current one when only a new name is given
+func renameBranch(repoPath string, args []string, force bool) error {
+	var oldName, newName string
+
+	switch len(args) {
+	case 1:
+		current, err := refs.CurrentBranch(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read HEAD: %v", err)
+		}
+
+		if current == "" {
+			return fmt.Errorf("cannot rename: HEAD is detached")
+		}
+		oldName, newName = current, args[0]
+	case 2:
+		oldName, newName = args[0], args[1]
+	default:
+		return fmt.Errorf("-m takes an optional old name and a new name")
+	}
+
+	err := refs.RenameBranch(repoPath, oldName, newName, force)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Renamed branch %s to %s\n", oldName, newName)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(branchCmd)
+	branchCmd.Flags().BoolP("delete", "d", false, "Delete the named branches")
+	branchCmd.Flags().BoolP("move", "m", false, "Rename a branch")
+	branchCmd.Flags().BoolP("force", "f", false, "Overwrite an existing branch when creating or renaming")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+var catFileCmd = &cobra.Command{
+	Use:   "cat-file (-t | -s | -p) <object>",
+	Short: "Show the type, size or content of a repository object",
+	Long:  "Inspect any blob, tree, commit or tag in the object store. Objects can be named by any revision, including abbreviated hashes as long as they are unique.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showType, err := cmd.Flags().GetBool("type")
+		if err != nil {
+			return fmt.Errorf("failed to get type flag: %v", err)
+		}
+
+		showSize, err := cmd.Flags().GetBool("size")
+		if err != nil {
+			return fmt.Errorf("failed to get size flag: %v", err)
+		}
+
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			return fmt.Errorf("failed to get pretty flag: %v", err)
+		}
+
+		selected := 0
+		for _, flag := range []bool{showType, showSize, pretty} {
+			if flag {
+				selected++
+			}
+		}
+		if selected != 1 {
+			return fmt.Errorf("exactly one of -t, -s or -p is required")
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		objectHash, err := revparse.Resolve(repoPath, args[0])
+		if err != nil {
+			return err
+		}
+
+		objType, data, err := storage.ReadObject(repoPath, objectHash)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %v", objectHash, err)
+		}
+
+		switch {
+		case showType:
+			fmt.Println(objType)
+		case showSize:
+			fmt.Println(len(data))
+		default:
+			return prettyPrintObject(repoPath, objectHash, objType, data)
+		}
+
+		return nil
+	},
+}
+
+// prettyPrintObject prints an object in a human readable form based on its type
+func prettyPrintObject(repoPath, objectHash, objType string, data []byte) error {
+	switch objType {
+	case constants.BlobObject:
+		_, err := os.Stdout.Write(data)
+		return err
+	case constants.TreeObject:
+		tree, err := objects.ReadTree(repoPath, objectHash)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range tree.Entries {
+			fmt.Printf("%s %s %s\t%s\n", entry.Mode, entry.Type, entry.Hash, entry.Path)
+		}
+	case constants.CommitObject:
+		commit, err := objects.ReadCommit(repoPath, objectHash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("tree %s\n", commit.Tree)
+		for _, parent := range commit.Parents {
+			fmt.Printf("parent %s\n", parent)
+		}
+		fmt.Printf("author %s %s\n", commit.Author, commit.Timestamp)
+		if commit.Committer != "" {
+			fmt.Printf("committer %s %s\n", commit.Committer, commit.CommitTimestamp)
+		}
+		fmt.Printf("\n%s\n", commit.Message)
+	case constants.TagObject:
+		tag, err := objects.ReadTag(repoPath, objectHash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("object %s\n", tag.Object)
+		fmt.Printf("type %s\n", tag.Type)
+		fmt.Printf("tag %s\n", tag.Name)
+		fmt.Printf("tagger %s %s\n", tag.Tagger, tag.Timestamp)
+		fmt.Printf("\n%s\n", tag.Message)
+	default:
+		return fmt.Errorf("unknown object type %q", objType)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(catFileCmd)
+	catFileCmd.Flags().BoolP("type", "t", false, "Show the object type")
+	catFileCmd.Flags().BoolP("size", "s", false, "Show the object size")
+	catFileCmd.Flags().BoolP("pretty", "p", false, "Pretty-print the object content")
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var checkoutCmd = &cobra.Command{
+	Use:   "checkout [-b <new-branch>] <branch|commit>",
+	Short: "Switch branches or restore a commit into the working tree",
+	Long:  "Update the working tree and the index to match the given branch or commit. Checking out a branch makes it the current branch, checking out a commit detaches HEAD. Refuses to overwrite local changes unless --force is given.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newBranch, err := cmd.Flags().GetString("branch")
+		if err != nil {
+			return fmt.Errorf("failed to get branch flag: %v", err)
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if newBranch != "" {
+			startPoint := ""
+			if len(args) == 1 {
+				startPoint = args[0]
+			}
+			return createAndSwitch(repoPath, newBranch, startPoint, force)
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("a branch or commit to check out is required")
+		}
+
+		return switchTo(repoPath, args[0], false, force)
+	},
+}
+
+// resolveSwitchTarget returns the commit a branch name or revision refers to, and the branch when it named one
+func resolveSwitchTarget(repoPath, target string, detach bool) (string, string, error) {
+	if !detach && refs.BranchExists(repoPath, target) {
+		commitHash, err := refs.ReadBranch(repoPath, target)
+		if err != nil {
+			return "", "", err
+		}
+		return commitHash, target, nil
+	}
+
+	commitHash, err := revparse.ResolveCommit(repoPath, target)
+	if err != nil {
+		if errors.Is(err, revparse.ErrUnknownRevision) {
+			return "", "", fmt.Errorf("%q did not match any branch or commit", target)
+		}
+		return "", "", err
+	}
+
+	return commitHash, "", nil
+}
+
+// switchTo updates the working tree, index and HEAD to the given branch or commit
+func switchTo(repoPath, target string, detach, force bool) error {
+	commitHash, branch, err := resolveSwitchTarget(repoPath, target, detach)
+	if err != nil {
+		return err
+	}
+
+	commit, err := objects.ReadCommit(repoPath, commitHash)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %v", commitHash, err)
+	}
+
+	headTree, _, err := getHEADTreeEntries(repoPath)
+	if err != nil {
+		return err
+	}
+
+	err = worktree.Checkout(repoPath, headTree, commit.Tree, force)
+	if err != nil {
+		var conflict *worktree.ConflictError
+		if errors.As(err, &conflict) {
+			return err
+		}
+		return fmt.Errorf("failed to check out %s: %v", target, err)
+	}
+
+	if branch != "" {
+		err = refs.SetHEADToBranch(repoPath, branch)
+		if err != nil {
+			return fmt.Errorf("failed to update HEAD: %v", err)
+		}
+
+		fmt.Printf("Switched to branch '%s'\n", branch)
+		return nil
+	}
+
+	err = refs.DetachHEAD(repoPath, commitHash)
+	if err != nil {
+		return fmt.Errorf("failed to update HEAD: %v", err)
+	}
+
+	fmt.Printf("HEAD is now at %s %s\n", objects.ShortHash(commitHash), objects.FirstLine(commit.Message))
+	return nil
+}
+
+// createAndSwitch creates a branch at the start point (HEAD by default) and switches to it
+func createAndSwitch(repoPath, branch, startPoint string, force bool) error {
+	err := refs.ValidateBranchName(branch)
+	if err != nil {
+		return err
+	}
+
+	if refs.BranchExists(repoPath, branch) {
+		return fmt.Errorf("a branch named %q already exists", branch)
+	}
+
+	startHash := ""
+	if startPoint != "" {
+		startHash, _, err = resolveSwitchTarget(repoPath, startPoint, false)
+		if err != nil {
+			return err
+		}
+	} else {
+		startHash, err = refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %v", err)
+		}
+	}
+
+	// Without any commits there is nothing to check out, only HEAD moves
+	if startHash == "" {
+		err = refs.SetHEADToBranch(repoPath, branch)
+		if err != nil {
+			return fmt.Errorf("failed to update HEAD: %v", err)
+		}
+
+		fmt.Printf("Switched to a new branch '%s'\n", branch)
+		return nil
+	}
+
+	// Make sure the working tree can be updated before creating the branch
+	commit, err := objects.ReadCommit(repoPath, startHash)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %v", startHash, err)
+	}
+
+	headTree, _, err := getHEADTreeEntries(repoPath)
+	if err != nil {
+		return err
+	}
+
+	err = worktree.Checkout(repoPath, headTree, commit.Tree, force)
+	if err != nil {
+		return err
+	}
+
+	err = refs.CreateBranch(repoPath, branch, startHash, false)
+	if err != nil {
+		return err
+	}
+
+	err = refs.SetHEADToBranch(repoPath, branch)
+	if err != nil {
+		return fmt.Errorf("failed to update HEAD: %v", err)
+	}
+
+	fmt.Printf("Switched to a new branch '%s'\n", branch)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkoutCmd)
+	checkoutCmd.Flags().StringP("branch", "b", "", "Create a new branch and switch to it")
+	checkoutCmd.Flags().BoolP("force", "f", false, "Discard local changes that would be overwritten")
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/rebase"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/sequencer"
+)
+
+var cherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick <revision>... | cherry-pick (--continue | --abort)",
+	Short: "Apply the changes introduced by existing commits",
+	Long:  "For each given commit, apply the change it made relative to its parent onto HEAD and record it as a new commit with the original author and message. When a change conflicts, the conflicts are left in the working tree; fix them, add the files and run --continue, or run --abort to return to where you started.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSequencer(cmd, args, sequencer.Pick)
+	},
+}
+
+// runSequencer starts, continues or aborts a cherry-pick or revert of the given revisions
+func runSequencer(cmd *cobra.Command, args []string, action sequencer.Action) error {
+	continueFlag, err := cmd.Flags().GetBool("continue")
+	if err != nil {
+		return fmt.Errorf("failed to get continue flag: %v", err)
+	}
+
+	abort, err := cmd.Flags().GetBool("abort")
+	if err != nil {
+		return fmt.Errorf("failed to get abort flag: %v", err)
+	}
+
+	// Find repository root
+	repoPath, err := repo.FindRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to locate repository: %v", err)
+	}
+
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	switch {
+	case continueFlag && abort:
+		return fmt.Errorf("--continue and --abort cannot be used together")
+	case (continueFlag || abort) && len(args) > 0:
+		return fmt.Errorf("--continue and --abort don't take revisions")
+	case abort:
+		return sequencer.Abort(repoPath)
+	case !continueFlag && len(args) == 0:
+		return fmt.Errorf("no commits given to %s", action)
+	}
+
+	_, committer, err := commitSignatures(repoPath, "", "")
+	if err != nil {
+		return err
+	}
+
+	var outcomes []*sequencer.Outcome
+	if continueFlag {
+		outcomes, err = sequencer.Continue(repoPath, committer)
+	} else {
+		var steps []sequencer.Step
+		steps, err = sequencerSteps(repoPath, args, action)
+		if err != nil {
+			return err
+		}
+
+		outcomes, err = sequencer.Run(repoPath, steps, committer)
+	}
+
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.Stopped():
+			for _, line := range outcome.Result.Messages {
+				fmt.Println(line)
+			}
+
+			name := "cherry-pick"
+			if outcome.Step.Action == sequencer.Revert {
+				name = "revert"
+			}
+			return fmt.Errorf("could not %s %s; fix the conflicts, add the files and run \"quill %s --continue\", or run \"quill %s --abort\" to cancel", outcome.Step.Action, objects.ShortHash(outcome.Step.Hash), name, name)
+		case outcome.Commit == "":
+			fmt.Printf("Skipped %s, its change is already in HEAD\n", objects.ShortHash(outcome.Step.Hash))
+		default:
+			fmt.Printf("Created commit %s: %s\n", objects.ShortHash(outcome.Commit), outcome.Subject)
+		}
+	}
+
+	return err
+}
+
+// sequencerSteps resolves the revisions to steps after checking the tree is ready for them
+func sequencerSteps(repoPath string, args []string, action sequencer.Action) ([]sequencer.Step, error) {
+	mergeHead, _, err := merge.ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if mergeHead != "" {
+		return nil, fmt.Errorf("a merge is in progress, resolve the conflicts and commit first")
+	}
+
+	if rebase.InProgress(repoPath) {
+		return nil, fmt.Errorf("a rebase is in progress, use \"quill rebase --continue\" or \"quill rebase --abort\" first")
+	}
+
+	// Each step rewrites tracked files, so local changes to them must be committed first
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %v", err)
+	}
+
+	status, err := getRepoStatus(repoPath, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(status.Unmerged)+len(status.Staged)+len(status.Modified)+len(status.Deleted) > 0 {
+		return nil, fmt.Errorf("your local changes would be overwritten by %s, commit them first", action)
+	}
+
+	steps := make([]sequencer.Step, 0, len(args))
+	for _, arg := range args {
+		hash, err := revparse.ResolveCommit(repoPath, arg)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, sequencer.Step{Action: action, Hash: hash})
+	}
+
+	return steps, nil
+}
+
+func init() {
+	rootCmd.AddCommand(cherryPickCmd)
+	cherryPickCmd.Flags().Bool("continue", false, "Commit the resolved conflicts and carry on with the remaining commits")
+	cherryPickCmd.Flags().Bool("abort", false, "Cancel and return to the commit you started on")
+}
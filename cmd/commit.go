@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
 	"github.com/tejastn10/quill/pkg/objects"
 	"github.com/tejastn10/quill/pkg/repo"
 )
@@ -21,8 +22,14 @@ var commitCmd = &cobra.Command{
 			return fmt.Errorf("failed to get message flag: %v", err)
 		}
 
-		if message == "" {
-			return fmt.Errorf("commit message cannot be empty")
+		authorFlag, err := cmd.Flags().GetString("author")
+		if err != nil {
+			return fmt.Errorf("failed to get author flag: %v", err)
+		}
+
+		dateFlag, err := cmd.Flags().GetString("date")
+		if err != nil {
+			return fmt.Errorf("failed to get date flag: %v", err)
 		}
 
 		// Find repository root
@@ -31,52 +38,76 @@ var commitCmd = &cobra.Command{
 			return fmt.Errorf("failed to locate repository: %v", err)
 		}
 
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// A merge stopped by conflicts is concluded by the next commit
+		mergeHead, mergeMessage, err := merge.ReadState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if message == "" {
+			message = mergeMessage
+		}
+
+		if message == "" {
+			return fmt.Errorf("commit message cannot be empty")
+		}
+
 		// Load the index
 		idx, err := index.LoadIndex(repoPath)
 		if err != nil {
 			return fmt.Errorf("failed to load index: %v", err)
 		}
 
-		// Check if there are any staged changes
-		hasChanges := false
-		for _, entry := range idx.Entries {
-			if entry.Staged {
-				hasChanges = true
-				break
-			}
+		if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+			return fmt.Errorf("cannot commit, fix conflicts in the following paths and add them first:\n\t%s", strings.Join(conflicts, "\n\t"))
 		}
 
-		if !hasChanges {
+		// Check if the index differs from HEAD, removals included
+		hasChanges, err := objects.HasStagedChanges(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if !hasChanges && mergeHead == "" {
 			return fmt.Errorf("no changes staged for commit")
 		}
 
-		// Get user info from config
-		name, email, err := repo.ReadUserConfig(repoPath)
+		// Work out who wrote the change and who is recording it
+		author, committer, err := commitSignatures(repoPath, authorFlag, dateFlag)
 		if err != nil {
-			return fmt.Errorf("failed to read user config: %v", err)
+			return err
 		}
 
-		// Create author string
-		author := fmt.Sprintf("%s <%s>", name, email)
+		// Create commit object, recording the merged commit as a second parent
+		var mergeParents []string
+		if mergeHead != "" {
+			mergeParents = []string{mergeHead}
+		}
 
-		// Create commit object
-		commitHash, err := objects.CreateCommit(repoPath, message, author)
+		commitHash, err := objects.CreateMergeCommit(repoPath, message, author, committer, mergeParents)
 		if err != nil {
 			return fmt.Errorf("failed to create commit: %v", err)
 		}
 
-		fmt.Printf("Created commit %s: %s\n", commitHash[:8], message)
+		err = merge.ClearState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created commit %s: %s\n", objects.ShortHash(commitHash), message)
 		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(commitCmd)
-	commitCmd.Flags().StringP("message", "m", "", "Commit message")
-	err := commitCmd.MarkFlagRequired("message")
-
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
-		os.Exit(1)
-	}
+	commitCmd.Flags().StringP("message", "m", "", "Commit message, required unless concluding a merge")
+	commitCmd.Flags().String("author", "", "Override the commit author, given as \"Name <email>\"")
+	commitCmd.Flags().String("date", "", "Override the author date, e.g. \"2024-05-01 13:45:00 +0200\" or \"2 days ago\"")
 }
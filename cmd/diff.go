@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/diff"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// fileVersion is one side of a file comparison
+type fileVersion struct {
+	Hash string
+	Mode string
+
+	// Working is set when the content lives in the working tree instead of the object store
+	Working bool
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [<revision> <revision>]",
+	Short: "Show changes between commits, the index and the working tree",
+	Long:  "Show changes between the working tree and the index, between the index and HEAD with --staged, or between two commits.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("diff accepts either no commits or exactly two")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		staged, err := cmd.Flags().GetBool("staged")
+		if err != nil {
+			return fmt.Errorf("failed to get staged flag: %v", err)
+		}
+
+		if staged && len(args) > 0 {
+			return fmt.Errorf("--staged cannot be combined with commits")
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		var oldFiles, newFiles map[string]fileVersion
+
+		switch {
+		case len(args) == 2:
+			// Commit vs commit
+			oldFiles, err = getCommitVersions(repoPath, args[0])
+			if err != nil {
+				return err
+			}
+
+			newFiles, err = getCommitVersions(repoPath, args[1])
+			if err != nil {
+				return err
+			}
+		case staged:
+			// Index vs HEAD
+			_, headEntries, err := getHEADTreeEntries(repoPath)
+			if err != nil {
+				return err
+			}
+			oldFiles = treeVersions(headEntries)
+
+			newFiles, err = getIndexVersions(repoPath)
+			if err != nil {
+				return err
+			}
+		default:
+			// Working tree vs index
+			oldFiles, err = getIndexVersions(repoPath)
+			if err != nil {
+				return err
+			}
+
+			newFiles, err = getWorkingVersions(repoPath, oldFiles)
+			if err != nil {
+				return err
+			}
+		}
+
+		return printDiff(repoPath, oldFiles, newFiles)
+	},
+}
+
+// getCommitVersions returns the files recorded in the tree of the commit a revision names
+func getCommitVersions(repoPath, rev string) (map[string]fileVersion, error) {
+	commitHash, err := revparse.ResolveCommit(repoPath, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := objects.ReadCommit(repoPath, commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %v", commitHash, err)
+	}
+
+	entries, err := objects.GetTreeEntries(repoPath, commit.Tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of commit %s: %v", commitHash, err)
+	}
+
+	return treeVersions(entries), nil
+}
+
+// treeVersions converts tree entries into comparable file versions
+func treeVersions(entries map[string]objects.TreeEntry) map[string]fileVersion {
+	files := make(map[string]fileVersion, len(entries))
+	for path, entry := range entries {
+		files[path] = fileVersion{Hash: entry.Hash, Mode: entry.Mode}
+	}
+	return files
+}
+
+// getIndexVersions returns the files recorded in the index
+func getIndexVersions(repoPath string) (map[string]fileVersion, error) {
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %v", err)
+	}
+
+	files := make(map[string]fileVersion, len(idx.Entries))
+	for path, entry := range idx.Entries {
+		files[path] = fileVersion{Hash: entry.Hash, Mode: entry.Mode}
+	}
+	return files, nil
+}
+
+// getWorkingVersions returns the working tree state of every tracked file
+func getWorkingVersions(repoPath string, tracked map[string]fileVersion) (map[string]fileVersion, error) {
+	files := make(map[string]fileVersion, len(tracked))
+
+	for path := range tracked {
+		fileHash, mode, err := worktree.HashFile(repoPath, path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // Deleted in the working tree
+			}
+			return nil, err
+		}
+
+		files[path] = fileVersion{
+			Hash:    fileHash,
+			Mode:    mode,
+			Working: true,
+		}
+	}
+
+	return files, nil
+}
+
+// readFileVersion loads the content of a file version
+func readFileVersion(repoPath, path string, version fileVersion) ([]byte, error) {
+	if version.Working {
+		return worktree.ReadFile(repoPath, path)
+	}
+
+	return objects.ReadBlob(repoPath, version.Hash)
+}
+
+// printDiff prints a unified diff for every path that differs between the two sides
+func printDiff(repoPath string, oldFiles, newFiles map[string]fileVersion) error {
+	// Collect every path from both sides
+	pathSet := make(map[string]bool)
+	for path := range oldFiles {
+		pathSet[path] = true
+	}
+	for path := range newFiles {
+		pathSet[path] = true
+	}
+
+	paths := make([]string, 0, len(pathSet))
+	for path := range pathSet {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		oldVersion, inOld := oldFiles[path]
+		newVersion, inNew := newFiles[path]
+
+		if inOld && inNew && oldVersion.Hash == newVersion.Hash && oldVersion.Mode == newVersion.Mode {
+			continue
+		}
+
+		slashPath := filepath.ToSlash(path)
+		oldName, newName := "a/"+slashPath, "b/"+slashPath
+
+		var oldData, newData []byte
+		var err error
+
+		fmt.Printf("\033[1mdiff --quill a/%s b/%s\033[0m\n", slashPath, slashPath)
+
+		switch {
+		case !inOld:
+			fmt.Printf("\033[1mnew file mode %s\033[0m\n", newVersion.Mode)
+			oldName = "/dev/null"
+		case !inNew:
+			fmt.Printf("\033[1mdeleted file mode %s\033[0m\n", oldVersion.Mode)
+			newName = "/dev/null"
+		case oldVersion.Mode != newVersion.Mode:
+			fmt.Printf("\033[1mold mode %s\033[0m\n", oldVersion.Mode)
+			fmt.Printf("\033[1mnew mode %s\033[0m\n", newVersion.Mode)
+		}
+
+		if inOld {
+			oldData, err = readFileVersion(repoPath, path, oldVersion)
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %v", path, err)
+			}
+		}
+
+		if inNew {
+			newData, err = readFileVersion(repoPath, path, newVersion)
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %v", path, err)
+			}
+		}
+
+		printColoredDiff(diff.Unified(oldName, newName, oldData, newData, diff.DefaultContext))
+	}
+
+	return nil
+}
+
+// printColoredDiff prints the unified diff of one file with terminal colors. The file header only comes
+// before the first hunk, after it a line starting with "--- " or "+++ " is a removed or added line.
+func printColoredDiff(output string) {
+	inHunks := false
+	for _, line := range strings.SplitAfter(output, "\n") {
+		switch {
+		case line == "":
+			continue
+		case !inHunks && (strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")):
+			fmt.Printf("\033[1m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "@@"):
+			inHunks = true
+			fmt.Printf("\033[36m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "+"):
+			fmt.Printf("\033[32m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "-"):
+			fmt.Printf("\033[31m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		default:
+			fmt.Print(line)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().Bool("staged", false, "Show changes between the index and HEAD")
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/rebase"
+)
+
+const (
+	// sequenceEditorEnv names the editor for todo lists, so scripts can edit them without touching messages
+	sequenceEditorEnv = "QUILL_SEQUENCE_EDITOR"
+
+	// editorEnv names the editor quill uses, ahead of $VISUAL and $EDITOR
+	editorEnv = "QUILL_EDITOR"
+
+	// messageFile is where commit messages are put for the user to edit
+	messageFile = "COMMIT_EDITMSG"
+)
+
+// messageHelp is appended to commit messages opened in the editor
+const messageHelp = `
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+`
+
+// launchEditor opens a file in the editor named by the first of the given variables, QUILL_EDITOR, VISUAL and
+// EDITOR that is set, falling back to vi, and waits for it to exit. The editor is run by the shell, so it can
+// include arguments.
+func launchEditor(path string, variables ...string) error {
+	editor := "vi"
+	for _, name := range append(variables, editorEnv, "VISUAL", "EDITOR") {
+		if value := os.Getenv(name); value != "" {
+			editor = value
+			break
+		}
+	}
+
+	command := exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	err := command.Run()
+	if err != nil {
+		return fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+
+	return nil
+}
+
+// messageEditor returns a function letting the user edit commit messages in .quill/COMMIT_EDITMSG.
+// Comment lines are dropped from the result.
+func messageEditor(repoPath string) rebase.EditMessage {
+	return func(message string) (string, error) {
+		path := filepath.Join(repoPath, ".quill", messageFile)
+
+		err := lockfile.WriteFile(path, []byte(strings.TrimRight(message, "\n")+"\n"+messageHelp))
+		if err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", messageFile, err)
+		}
+
+		err = launchEditor(path)
+		if err != nil {
+			return "", err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", messageFile, err)
+		}
+
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				lines = append(lines, strings.TrimRight(line, " \t\r"))
+			}
+		}
+
+		return strings.Trim(strings.Join(lines, "\n"), "\n"), nil
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/fsck"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck [--unreachable]",
+	Short: "Verify the integrity of the object store",
+	Long:  "Re-hash every object in the repository, parse commits, trees and tags, and check that every object they refer to exists, as do the objects pointed at by refs, HEAD and the index. Dangling objects, which nothing refers to, are listed as well. Exits with an error when problems are found.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unreachable, err := cmd.Flags().GetBool("unreachable")
+		if err != nil {
+			return fmt.Errorf("failed to get unreachable flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		report, err := fsck.Check(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to check repository: %v", err)
+		}
+
+		for _, problem := range report.Problems {
+			fmt.Printf("error: %s\n", problem)
+		}
+
+		if unreachable {
+			for _, object := range report.Unreachable {
+				fmt.Printf("unreachable %s\n", object)
+			}
+		} else {
+			for _, object := range report.Dangling {
+				fmt.Printf("dangling %s\n", object)
+			}
+		}
+
+		if !report.OK() {
+			return fmt.Errorf("fsck found %d problem(s)", len(report.Problems))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+	fsckCmd.Flags().Bool("unreachable", false, "List every unreachable object instead of only dangling ones")
+}
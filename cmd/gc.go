@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/date"
+	"github.com/tejastn10/quill/pkg/gc"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc [--prune=<date>] [--dry-run]",
+	Short: "Clean up unreachable objects",
+	Long:  "Remove loose objects that can't be reached from any ref, HEAD or the index, such as blobs of edits that were staged but never committed. Objects newer than the --prune date are kept so commands running at the same time don't lose their work. Use --prune=now to remove every unreachable object and --prune=never to keep them all.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expire, err := cmd.Flags().GetString("prune")
+		if err != nil {
+			return fmt.Errorf("failed to get prune flag: %v", err)
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("failed to get dry-run flag: %v", err)
+		}
+
+		return pruneObjects(expire, dryRun)
+	},
+}
+
+// pruneObjects removes unreachable objects written before the expiry date and prints what was reclaimed
+func pruneObjects(expire string, dryRun bool) error {
+	// Find repository root
+	repoPath, err := repo.FindRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to locate repository: %v", err)
+	}
+
+	// Nothing is older than the zero time, so "never" keeps every object
+	var expireTime time.Time
+	if expire != "never" {
+		expireTime, err = date.Parse(expire, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := gc.Prune(repoPath, expireTime, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune objects: %v", err)
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+
+	if dryRun {
+		for _, object := range result.Pruned {
+			fmt.Printf("%s %s\n", object.Hash, object.Type)
+		}
+	}
+
+	fmt.Printf("%s %d unreachable object(s), freeing %s", verb, len(result.Pruned), formatBytes(result.Reclaimed()))
+	if result.Kept > 0 {
+		fmt.Printf(", kept %d newer than %s", result.Kept, expire)
+	}
+	fmt.Println(".")
+
+	return nil
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d bytes", size)
+	}
+
+	value, suffix := float64(size)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB", "TiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().String("prune", "2.weeks.ago", "Only remove unreachable objects older than this date")
+	gcCmd.Flags().BoolP("dry-run", "n", false, "List the objects that would be removed without removing them")
+}
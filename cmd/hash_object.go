@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+var hashObjectCmd = &cobra.Command{
+	Use:   "hash-object [-w] [-t <type>] <file>",
+	Short: "Compute an object hash and optionally store the object",
+	Long:  "Compute the object hash of a file's contents with its typed header. With -w the object is also written to the object store.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		write, err := cmd.Flags().GetBool("write")
+		if err != nil {
+			return fmt.Errorf("failed to get write flag: %v", err)
+		}
+
+		objType, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return fmt.Errorf("failed to get type flag: %v", err)
+		}
+
+		switch objType {
+		case constants.BlobObject, constants.TreeObject, constants.CommitObject, constants.TagObject:
+		default:
+			return fmt.Errorf("unknown object type %q", objType)
+		}
+
+		// Resolve and read the file
+		absPath, err := filepath.Abs(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to resolve path for %q: %v", args[0], err)
+		}
+
+		// #nosec G304 - Reading a user supplied file is the purpose of this command
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %v", args[0], err)
+		}
+
+		// Outside a repository objects can still be hashed with the default format
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			if write {
+				return fmt.Errorf("failed to locate repository: %v", err)
+			}
+
+			hasher, err := hash.NewHasher(hash.DefaultAlgorithm)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(storage.HashObject(hasher, objType, data))
+			return nil
+		}
+
+		if !write {
+			hasher, err := storage.ObjectHasher(repoPath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(storage.HashObject(hasher, objType, data))
+			return nil
+		}
+
+		// Storing malformed content would leave an object fsck reports as corrupt
+		err = objects.Validate(objType, data)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s object: %v", args[0], objType, err)
+		}
+
+		objectHash, err := storage.CreateObject(repoPath, objType, data)
+		if err != nil {
+			return fmt.Errorf("failed to store object: %v", err)
+		}
+
+		fmt.Println(objectHash)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hashObjectCmd)
+	hashObjectCmd.Flags().BoolP("write", "w", false, "Write the object into the object store")
+	hashObjectCmd.Flags().StringP("type", "t", constants.BlobObject, "Type of object to create")
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/date"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+// commitSignatures works out the author and committer of a new commit. Both default to the user config and
+// the current time, the QUILL_AUTHOR_* and QUILL_COMMITTER_* variables override that, and the --author and
+// --date values, when given, override the author once more.
+func commitSignatures(repoPath, author, authorDate string) (objects.Signature, objects.Signature, error) {
+	now := time.Now()
+	user := objects.Signature{When: now}
+
+	// A missing config only matters if the environment doesn't provide the identity either
+	name, email, configErr := repo.ReadUserConfig(repoPath)
+	if configErr == nil {
+		user.Name, user.Email = name, email
+	}
+
+	authorSignature, err := objects.AuthorFromEnv(user)
+	if err != nil {
+		return objects.Signature{}, objects.Signature{}, err
+	}
+
+	committerSignature, err := objects.CommitterFromEnv(user)
+	if err != nil {
+		return objects.Signature{}, objects.Signature{}, err
+	}
+
+	if author != "" {
+		authorSignature.Name, authorSignature.Email, err = objects.ParseIdentity(author)
+		if err != nil {
+			return objects.Signature{}, objects.Signature{}, fmt.Errorf("invalid --author: %v", err)
+		}
+	}
+
+	if authorDate != "" {
+		authorSignature.When, err = date.Parse(authorDate, now)
+		if err != nil {
+			return objects.Signature{}, objects.Signature{}, fmt.Errorf("invalid --date: %v", err)
+		}
+	}
+
+	for _, signature := range []objects.Signature{authorSignature, committerSignature} {
+		if signature.Name == "" || signature.Email == "" {
+			return objects.Signature{}, objects.Signature{}, fmt.Errorf("failed to read user config: %v", configErr)
+		}
+	}
+
+	return authorSignature, committerSignature, nil
+}
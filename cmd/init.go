@@ -6,14 +6,26 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/hash"
 	"github.com/tejastn10/quill/pkg/repo"
 )
 
 var initCmd = &cobra.Command{
-	Use:   "init",
+	Use:   "init [--object-format=<sha256|blake2b>]",
 	Short: "Initialize a new Quill repository",
-	Long:  "Create a new Quill repository by initializing .quill directory in the current directory.",
+	Long:  "Create a new Quill repository by initializing .quill directory in the current directory. The object format selects the hash algorithm used for every object and cannot be changed later.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		objectFormat, err := cmd.Flags().GetString("object-format")
+		if err != nil {
+			return fmt.Errorf("failed to get object-format flag: %v", err)
+		}
+
+		// Validate the object format before touching the filesystem
+		_, err = hash.NewHasher(objectFormat)
+		if err != nil {
+			return err
+		}
+
 		// Get the current working Directory
 		workingDir, err := os.Getwd()
 		if err != nil {
@@ -35,6 +47,12 @@ var initCmd = &cobra.Command{
 		// Defer cleanup in case of failure
 		defer repo.CleanupRepository(workingDir, &err)
 
+		// Record the object format every object will be hashed with
+		err = repo.CreateCoreConfig(workingDir, objectFormat)
+		if err != nil {
+			return fmt.Errorf("failed to create core config file: %w", err)
+		}
+
 		// Ask for user details
 		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("Enter your name: ")
@@ -61,4 +79,5 @@ var initCmd = &cobra.Command{
 func init() {
 	// Registering the init command with the root command
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().String("object-format", hash.DefaultAlgorithm, "Hash algorithm for objects (sha256 or blake2b)")
 }
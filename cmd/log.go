@@ -2,95 +2,368 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/date"
+	"github.com/tejastn10/quill/pkg/history"
 	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
 	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
 )
 
 var logCmd = &cobra.Command{
-	Use:   "log",
+	Use:   "log [<revision>] [-- <path>...]",
 	Short: "Show commit logs",
-	Long:  "Display the commit history with details about changes in each commit.",
+	Long:  "Display the commit history starting at HEAD or the given revision, with details about changes in each commit. The history can be limited by count, date, author, message and the paths a commit changes, and each commit can be shown on one line or through a --format template using " + history.Placeholders + ". Commits are shown with the branches and tags pointing at them, and --graph draws the lines of history beside them.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		maxCount, err := cmd.Flags().GetInt("max-count")
+		if err != nil {
+			return fmt.Errorf("failed to get max-count flag: %v", err)
+		}
+
+		skip, err := cmd.Flags().GetInt("skip")
+		if err != nil {
+			return fmt.Errorf("failed to get skip flag: %v", err)
+		}
+
+		since, err := cmd.Flags().GetString("since")
+		if err != nil {
+			return fmt.Errorf("failed to get since flag: %v", err)
+		}
+
+		until, err := cmd.Flags().GetString("until")
+		if err != nil {
+			return fmt.Errorf("failed to get until flag: %v", err)
+		}
+
+		author, err := cmd.Flags().GetString("author")
+		if err != nil {
+			return fmt.Errorf("failed to get author flag: %v", err)
+		}
+
+		grep, err := cmd.Flags().GetString("grep")
+		if err != nil {
+			return fmt.Errorf("failed to get grep flag: %v", err)
+		}
+
+		oneline, err := cmd.Flags().GetBool("oneline")
+		if err != nil {
+			return fmt.Errorf("failed to get oneline flag: %v", err)
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag: %v", err)
+		}
+
+		dateFlag, err := cmd.Flags().GetString("date")
+		if err != nil {
+			return fmt.Errorf("failed to get date flag: %v", err)
+		}
+
+		graph, err := cmd.Flags().GetBool("graph")
+		if err != nil {
+			return fmt.Errorf("failed to get graph flag: %v", err)
+		}
+
+		topoOrder, err := cmd.Flags().GetBool("topo-order")
+		if err != nil {
+			return fmt.Errorf("failed to get topo-order flag: %v", err)
+		}
+
+		dateOrder, err := cmd.Flags().GetBool("date-order")
+		if err != nil {
+			return fmt.Errorf("failed to get date-order flag: %v", err)
+		}
+
+		reverse, err := cmd.Flags().GetBool("reverse")
+		if err != nil {
+			return fmt.Errorf("failed to get reverse flag: %v", err)
+		}
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fmt.Errorf("failed to get all flag: %v", err)
+		}
+
+		if oneline && format != "" {
+			return fmt.Errorf("--oneline and --format cannot be used together")
+		}
+
+		if topoOrder && dateOrder {
+			return fmt.Errorf("--topo-order and --date-order cannot be used together")
+		}
+
+		if graph && reverse {
+			return fmt.Errorf("--graph and --reverse cannot be used together")
+		}
+
+		dateStyle, err := date.ParseStyle(dateFlag)
+		if err != nil {
+			return err
+		}
+
+		// Revisions come before "--", paths after it
+		revisions, pathArgs := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			revisions, pathArgs = args[:dash], args[dash:]
+		}
+
+		if len(revisions) > 1 {
+			return fmt.Errorf("only one revision can be given, put paths after \"--\"")
+		}
+
 		// Find repository root
 		repoPath, err := repo.FindRepoRoot()
 		if err != nil {
 			return fmt.Errorf("failed to locate repository: %v", err)
 		}
 
-		// Get current HEAD commit hash
-		headHash, err := repo.GetHEAD(repoPath)
+		options, err := logOptions(repoPath, since, until, author, grep, pathArgs)
 		if err != nil {
-			return fmt.Errorf("failed to get HEAD: %v", err)
+			return err
+		}
+		options.MaxCount, options.Skip, options.Reverse = maxCount, skip, reverse
+
+		// A graph needs children before parents, so it keeps each line of history together unless asked otherwise
+		switch {
+		case topoOrder, graph && !dateOrder:
+			options.Order = history.TopoOrder
+		case dateOrder:
+			options.Order = history.DateOrder
+		}
+
+		starts, err := logStarts(repoPath, revisions, all)
+		if err != nil {
+			return err
 		}
 
 		// If no commits yet
-		if headHash == "" {
+		if len(starts) == 0 {
 			fmt.Println("No commits yet.")
 			return nil
 		}
 
-		// Retrieve and display commit history
-		currentHash := headHash
-		for currentHash != "" {
-			// Read commit
-			commit, err := objects.ReadCommit(repoPath, currentHash)
-			if err != nil {
-				return fmt.Errorf("failed to read commit %s: %v", currentHash, err)
-			}
+		commits, err := history.Walk(repoPath, starts, options)
+		if err != nil {
+			return err
+		}
 
-			// Parse timestamp
-			timestamp, err := time.Parse(time.RFC3339, commit.Timestamp)
+		decorations, err := history.Decorations(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read refs: %v", err)
+		}
+
+		var lanes *history.Graph
+		if graph {
+			lanes, err = history.NewGraph(repoPath, commits)
 			if err != nil {
-				return fmt.Errorf("failed to parse timestamp: %v", err)
+				return err
 			}
+		}
 
-			// Display commit header
-			fmt.Printf("\033[33mcommit %s\033[0m\n", currentHash)
-			fmt.Printf("Author: %s\n", commit.Author)
-			fmt.Printf("Date:   %s\n\n", timestamp.Format("Mon Jan 2 15:04:05 2006 -0700"))
-			fmt.Printf("    %s\n\n", commit.Message)
+		now := time.Now()
+		for _, commit := range commits {
+			var out strings.Builder
 
-			// Get changes in this commit
-			if commit.Parent != "" {
-				changes, err := getCommitChanges(repoPath, commit.Tree, commit.Parent)
+			switch {
+			case format != "":
+				line, err := history.Format(format, commit, dateStyle, now)
 				if err != nil {
-					return fmt.Errorf("failed to get commit changes: %v", err)
+					return err
 				}
-
-				// Display changes
-				if len(changes) > 0 {
-					fmt.Println("Changes:")
-					for _, change := range changes {
-						fmt.Printf("    %s\n", change)
-					}
-					fmt.Println()
-				}
-			} else {
-				// First commit - show all files
-				files, err := objects.GetTreeFiles(repoPath, commit.Tree)
+				fmt.Fprintln(&out, line)
+			case oneline:
+				subject, _ := history.SplitMessage(commit.Message)
+				fmt.Fprintf(&out, "\033[33m%s%s\033[0m %s\n", objects.ShortHash(commit.Hash), decoration(decorations[commit.Hash]), subject)
+			default:
+				err = printCommit(&out, repoPath, commit, decorations[commit.Hash], dateStyle, now)
 				if err != nil {
-					return fmt.Errorf("failed to get files: %v", err)
+					return err
 				}
+			}
 
-				fmt.Println("Files:")
-				for _, file := range files {
-					fmt.Printf("    %s\n", file)
-				}
-				fmt.Println()
+			if lanes == nil {
+				fmt.Print(out.String())
+				continue
 			}
 
-			// Move to parent commit
-			currentHash = commit.Parent
+			printGraph(lanes.Next(commit), lanes.Padding(), out.String())
 		}
 
 		return nil
 	},
 }
 
+// logOptions turns the filtering flags of log into walk options
+func logOptions(repoPath, since, until, author, grep string, pathArgs []string) (history.Options, error) {
+	var options history.Options
+	var err error
+	now := time.Now()
+
+	if since != "" {
+		options.Since, err = date.Parse(since, now)
+		if err != nil {
+			return options, fmt.Errorf("invalid --since: %v", err)
+		}
+	}
+
+	if until != "" {
+		options.Until, err = date.Parse(until, now)
+		if err != nil {
+			return options, fmt.Errorf("invalid --until: %v", err)
+		}
+	}
+
+	if author != "" {
+		options.Author, err = regexp.Compile(author)
+		if err != nil {
+			return options, fmt.Errorf("invalid --author pattern: %v", err)
+		}
+	}
+
+	if grep != "" {
+		options.Grep, err = regexp.Compile(grep)
+		if err != nil {
+			return options, fmt.Errorf("invalid --grep pattern: %v", err)
+		}
+	}
+
+	for _, arg := range pathArgs {
+		spec, err := resolvePathspec(repoPath, arg)
+		if err != nil {
+			return options, err
+		}
+		options.Paths = append(options.Paths, spec)
+	}
+
+	return options, nil
+}
+
+// logStarts returns the commits log walks from: the given revision, every ref with --all, or else HEAD
+func logStarts(repoPath string, revisions []string, all bool) ([]string, error) {
+	var starts []string
+
+	if len(revisions) == 1 {
+		hash, err := revparse.ResolveCommit(repoPath, revisions[0])
+		if err != nil {
+			return nil, err
+		}
+		starts = append(starts, hash)
+	}
+
+	if all {
+		tips, err := history.Tips(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read refs: %v", err)
+		}
+		starts = append(starts, tips...)
+	}
+
+	if len(revisions) == 0 && !all {
+		// Get current HEAD commit hash
+		headHash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD: %v", err)
+		}
+
+		if headHash != "" {
+			starts = append(starts, headHash)
+		}
+	}
+
+	return starts, nil
+}
+
+// decoration shows the names pointing at a commit as " (HEAD -> main, tag: v1.0)", or nothing when there are none
+func decoration(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(names, ", ") + ")"
+}
+
+// printGraph shows the rows of the graph for a commit beside the lines showing the commit, continuing the
+// columns beside any lines left over once the rows run out
+func printGraph(rows []string, padding, text string) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	width := len(padding)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	for i := 0; i < max(len(rows), len(lines)); i++ {
+		prefix, line := padding, ""
+		if i < len(rows) {
+			prefix = rows[i]
+		}
+		if i < len(lines) {
+			line = lines[i]
+		}
+
+		fmt.Println(strings.TrimRight(fmt.Sprintf("%-*s %s", width, prefix, line), " "))
+	}
+}
+
+// printCommit writes a commit with the names pointing at it, its full message and the files it changed
+func printCommit(out io.Writer, repoPath string, commit *objects.Commit, names []string, dateStyle date.Style, now time.Time) error {
+	// Dates are shown in the author's time zone
+	author, err := commit.AuthorSignature()
+	if err != nil {
+		return fmt.Errorf("failed to read author of %s: %v", commit.Hash, err)
+	}
+
+	// Display commit header
+	fmt.Fprintf(out, "\033[33mcommit %s%s\033[0m\n", commit.Hash, decoration(names))
+	if len(commit.Parents) > 1 {
+		short := make([]string, len(commit.Parents))
+		for i, parent := range commit.Parents {
+			short[i] = objects.ShortHash(parent)
+		}
+		fmt.Fprintf(out, "Merge: %s\n", strings.Join(short, " "))
+	}
+	fmt.Fprintf(out, "Author: %s\n", commit.Author)
+	fmt.Fprintf(out, "Date:   %s\n\n", date.Format(author.When, dateStyle, now))
+	fmt.Fprintf(out, "    %s\n\n", commit.Message)
+
+	// Get changes in this commit, relative to the first parent for merges
+	if parent := commit.FirstParent(); parent != "" {
+		changes, err := getCommitChanges(repoPath, commit.Tree, parent)
+		if err != nil {
+			return fmt.Errorf("failed to get commit changes: %v", err)
+		}
+
+		// Display changes
+		if len(changes) > 0 {
+			fmt.Fprintln(out, "Changes:")
+			for _, change := range changes {
+				fmt.Fprintf(out, "    %s\n", change)
+			}
+			fmt.Fprintln(out)
+		}
+	} else {
+		// First commit - show all files
+		files, err := objects.GetTreeFiles(repoPath, commit.Tree)
+		if err != nil {
+			return fmt.Errorf("failed to get files: %v", err)
+		}
+
+		fmt.Fprintln(out, "Files:")
+		for _, file := range files {
+			fmt.Fprintf(out, "    %s\n", file)
+		}
+		fmt.Fprintln(out)
+	}
+
+	return nil
+}
+
 // getCommitChanges gets the list of changes between current and parent commits
 func getCommitChanges(repoPath, currentTree, parentHash string) ([]string, error) {
 	// Get parent commit
@@ -153,4 +426,18 @@ func getCommitChanges(repoPath, currentTree, parentHash string) ([]string, error
 
 func init() {
 	rootCmd.AddCommand(logCmd)
+	logCmd.Flags().IntP("max-count", "n", -1, "Show at most this many commits")
+	logCmd.Flags().Int("skip", 0, "Leave out this many commits before showing any")
+	logCmd.Flags().String("since", "", "Show commits more recent than a date, e.g. \"2024-05-01\" or \"2 weeks ago\"")
+	logCmd.Flags().String("until", "", "Show commits older than a date")
+	logCmd.Flags().String("author", "", "Show commits whose author matches a regular expression")
+	logCmd.Flags().String("grep", "", "Show commits whose message matches a regular expression")
+	logCmd.Flags().Bool("oneline", false, "Show each commit as its short hash and subject")
+	logCmd.Flags().String("format", "", "Show each commit through a template, e.g. \"%h %an %s\"")
+	logCmd.Flags().String("date", string(date.Default), "Show dates as default, iso, relative, unix or short")
+	logCmd.Flags().Bool("graph", false, "Draw the lines of history beside the commits")
+	logCmd.Flags().Bool("topo-order", false, "Show no commit before its children and each line of history in one piece")
+	logCmd.Flags().Bool("date-order", false, "Show no commit before its children, otherwise by commit date")
+	logCmd.Flags().Bool("reverse", false, "Show the oldest commits first")
+	logCmd.Flags().Bool("all", false, "Show the history of every branch and tag, not only HEAD")
 }
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <branch>",
+	Short: "Join the history of another branch into the current branch",
+	Long:  "Fast-forward the current branch when it is an ancestor of the given branch, otherwise merge the changes of both sides since their merge base and record a merge commit. Conflicting changes are written with conflict markers and the paths are recorded in the index; fix them, add them and commit to conclude the merge.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		mergeHead, _, err := merge.ReadState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if mergeHead != "" {
+			return fmt.Errorf("a merge is already in progress, resolve the conflicts and commit first")
+		}
+
+		theirsHash, err := revparse.ResolveCommit(repoPath, args[0])
+		if err != nil {
+			return err
+		}
+
+		theirs, err := objects.ReadCommit(repoPath, theirsHash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %v", theirsHash, err)
+		}
+
+		// Merging rewrites tracked files, so local changes to them must be committed first
+		idx, err := index.LoadIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to load index: %v", err)
+		}
+
+		status, err := getRepoStatus(repoPath, idx)
+		if err != nil {
+			return err
+		}
+
+		if len(status.Unmerged)+len(status.Staged)+len(status.Modified)+len(status.Deleted) > 0 {
+			return fmt.Errorf("your local changes would be overwritten by merge, commit them first")
+		}
+
+		oursHash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %v", err)
+		}
+
+		// Without any commits the branch simply takes the merged history
+		if oursHash == "" {
+			return fastForward(repoPath, "", oursHash, theirs)
+		}
+
+		ours, err := objects.ReadCommit(repoPath, oursHash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %v", oursHash, err)
+		}
+
+		baseHash, err := merge.MergeBase(repoPath, oursHash, theirsHash)
+		if err != nil {
+			return err
+		}
+
+		switch baseHash {
+		case "":
+			return fmt.Errorf("refusing to merge unrelated histories")
+		case theirsHash:
+			fmt.Println("Already up to date.")
+			return nil
+		case oursHash:
+			return fastForward(repoPath, ours.Tree, oursHash, theirs)
+		}
+
+		base, err := objects.ReadCommit(repoPath, baseHash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %v", baseHash, err)
+		}
+
+		result, err := merge.MergeTrees(repoPath, base.Tree, ours.Tree, theirs.Tree, merge.Labels{Ours: "HEAD", Theirs: args[0]})
+		if err != nil {
+			return fmt.Errorf("failed to merge: %v", err)
+		}
+
+		err = merge.Apply(repoPath, ours.Tree, result)
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("Merge commit '%s'", args[0])
+		if refs.BranchExists(repoPath, args[0]) {
+			message = fmt.Sprintf("Merge branch '%s'", args[0])
+		}
+
+		if !result.Clean() {
+			err = merge.WriteState(repoPath, theirsHash, message)
+			if err != nil {
+				return err
+			}
+
+			for _, line := range result.Messages {
+				fmt.Println(line)
+			}
+			return fmt.Errorf("automatic merge failed; fix conflicts and then commit the result")
+		}
+
+		author, committer, err := commitSignatures(repoPath, "", "")
+		if err != nil {
+			return err
+		}
+
+		commitHash, err := objects.CreateMergeCommit(repoPath, message, author, committer, []string{theirsHash})
+		if err != nil {
+			return fmt.Errorf("failed to create merge commit: %v", err)
+		}
+
+		fmt.Printf("Merge made by the three-way strategy.\nCreated commit %s: %s\n", objects.ShortHash(commitHash), message)
+		return nil
+	},
+}
+
+// fastForward moves the current branch to a descendant commit and checks out its tree
+func fastForward(repoPath, headTree, oursHash string, theirs *objects.Commit) error {
+	err := worktree.Checkout(repoPath, headTree, theirs.Tree, false)
+	if err != nil {
+		return err
+	}
+
+	err = refs.AdvanceHEAD(repoPath, oursHash, theirs.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to update HEAD: %v", err)
+	}
+
+	if oursHash != "" {
+		fmt.Printf("Updating %s..%s\n", objects.ShortHash(oursHash), objects.ShortHash(theirs.Hash))
+	}
+	fmt.Println("Fast-forward")
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+}
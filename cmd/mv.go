@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var mvCmd = &cobra.Command{
+	Use:   "mv [-f] <source> <destination>",
+	Short: "Move or rename a file or directory",
+	Long:  "Move a tracked file or directory in the working tree and update its index entries in one step. If the destination is an existing directory the source is moved into it. An existing destination file is only overwritten with --force.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		src, err := resolvePathspec(repoPath, args[0])
+		if err != nil {
+			return err
+		}
+
+		dst, err := resolvePathspec(repoPath, args[1])
+		if err != nil {
+			return err
+		}
+
+		if src == "." {
+			return fmt.Errorf("cannot move the repository root")
+		}
+
+		idx, err := index.LoadIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to load index: %v", err)
+		}
+
+		sources := idx.PathsUnder(src)
+		if len(sources) == 0 {
+			return fmt.Errorf("cannot move %q: not under version control", args[0])
+		}
+
+		for _, path := range sources {
+			if idx.Entries[path].Conflict != nil {
+				return fmt.Errorf("cannot move %q: %q is unmerged", args[0], path)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(repoPath, src)); err != nil {
+			return fmt.Errorf("cannot move %q: %v", args[0], err)
+		}
+
+		// Moving onto a directory puts the source inside it
+		info, err := os.Stat(filepath.Join(repoPath, dst))
+		if err == nil && info.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(src))
+			info, err = os.Stat(filepath.Join(repoPath, dst))
+		}
+
+		if dst == src || matchesPathspec(dst, src) {
+			return fmt.Errorf("cannot move %q into itself", args[0])
+		}
+
+		if err == nil {
+			if info.IsDir() || !force {
+				return fmt.Errorf("cannot move %q: destination %q exists", args[0], dst)
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat %q: %v", dst, err)
+		}
+
+		for _, path := range idx.PathsUnder(dst) {
+			if path != dst || !force {
+				return fmt.Errorf("cannot move %q: destination %q is tracked", args[0], dst)
+			}
+		}
+
+		err = worktree.MoveFile(repoPath, src, dst)
+		if err != nil {
+			return err
+		}
+
+		idx.MovePath(src, dst)
+
+		err = idx.SaveIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to save index: %v", err)
+		}
+
+		fmt.Printf("Renamed %q to %q\n", src, dst)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+	mvCmd.Flags().BoolP("force", "f", false, "Overwrite an existing destination file")
+}
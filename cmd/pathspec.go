@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolvePathspec converts a path given on the command line into a path relative to the repository root
+func resolvePathspec(repoPath, arg string) (string, error) {
+	absPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path for %q: %v", arg, err)
+	}
+
+	relPath, err := filepath.Rel(repoPath, absPath)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return "", fmt.Errorf("%q is outside the repository", arg)
+	}
+
+	if isQuillPath(relPath) {
+		return "", fmt.Errorf("%q is inside the .quill directory", arg)
+	}
+
+	return relPath, nil
+}
+
+// matchesPathspec reports whether a repository path is the pathspec itself or lies below it
+func matchesPathspec(path, spec string) bool {
+	return spec == "." || path == spec || strings.HasPrefix(path, spec+string(os.PathSeparator))
+}
+
+// expandPathspecs returns the sorted candidate paths matched by the command line arguments.
+// Every argument has to match at least one candidate.
+func expandPathspecs(repoPath string, args []string, candidates map[string]bool) ([]string, error) {
+	matched := make(map[string]bool)
+
+	for _, arg := range args {
+		spec, err := resolvePathspec(repoPath, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for path := range candidates {
+			if matchesPathspec(path, spec) {
+				matched[path] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("pathspec %q did not match any file known to quill", arg)
+		}
+	}
+
+	paths := make([]string, 0, len(matched))
+	for path := range matched {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [--expire=<date>] [--dry-run]",
+	Short: "Remove unreachable objects",
+	Long:  "Remove loose objects that can't be reached from any ref, HEAD or the index. Unlike gc, every unreachable object is removed unless --expire gives a date that newer objects are kept from.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expire, err := cmd.Flags().GetString("expire")
+		if err != nil {
+			return fmt.Errorf("failed to get expire flag: %v", err)
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("failed to get dry-run flag: %v", err)
+		}
+
+		return pruneObjects(expire, dryRun)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().String("expire", "now", "Only remove unreachable objects older than this date")
+	pruneCmd.Flags().BoolP("dry-run", "n", false, "List the objects that would be removed without removing them")
+}
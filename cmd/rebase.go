@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/rebase"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/sequencer"
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase [-i] <upstream> | rebase (--continue | --skip | --abort)",
+	Short: "Reapply commits on top of another base",
+	Long:  "Replay the commits of the current branch that upstream doesn't have on top of upstream, one at a time, and move the branch to the result. With --interactive the list of commits is opened in an editor first ($QUILL_SEQUENCE_EDITOR, $QUILL_EDITOR, $VISUAL or $EDITOR), where each can be picked, reworded, edited, squashed or fixed up into the one before it, dropped or reordered. When a commit conflicts or an edit stops the rebase, fix things up and run --continue, drop the commit with --skip, or return to where you started with --abort.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interactive, err := cmd.Flags().GetBool("interactive")
+		if err != nil {
+			return fmt.Errorf("failed to get interactive flag: %v", err)
+		}
+
+		continueFlag, err := cmd.Flags().GetBool("continue")
+		if err != nil {
+			return fmt.Errorf("failed to get continue flag: %v", err)
+		}
+
+		skip, err := cmd.Flags().GetBool("skip")
+		if err != nil {
+			return fmt.Errorf("failed to get skip flag: %v", err)
+		}
+
+		abort, err := cmd.Flags().GetBool("abort")
+		if err != nil {
+			return fmt.Errorf("failed to get abort flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		actions := 0
+		for _, set := range []bool{continueFlag, skip, abort} {
+			if set {
+				actions++
+			}
+		}
+
+		switch {
+		case actions > 1:
+			return fmt.Errorf("only one of --continue, --skip and --abort can be used")
+		case actions == 1 && (len(args) > 0 || interactive):
+			return fmt.Errorf("--continue, --skip and --abort don't take an upstream")
+		case abort:
+			return rebase.Abort(repoPath)
+		case actions == 0 && len(args) == 0:
+			return fmt.Errorf("no upstream given to rebase onto")
+		}
+
+		_, committer, err := commitSignatures(repoPath, "", "")
+		if err != nil {
+			return err
+		}
+		edit := messageEditor(repoPath)
+
+		var stop *rebase.Stop
+		switch {
+		case continueFlag:
+			idx, err := index.LoadIndex(repoPath)
+			if err != nil {
+				return fmt.Errorf("failed to load index: %v", err)
+			}
+
+			status, err := getRepoStatus(repoPath, idx)
+			if err != nil {
+				return err
+			}
+
+			if len(status.Modified)+len(status.Deleted) > 0 {
+				return fmt.Errorf("you have unstaged changes, add them or restore them before continuing")
+			}
+
+			stop, err = rebase.Continue(repoPath, committer, edit)
+			if err != nil {
+				return err
+			}
+		case skip:
+			stop, err = rebase.Skip(repoPath, committer, edit)
+			if err != nil {
+				return err
+			}
+		default:
+			var started bool
+			started, err = beginRebase(repoPath, args[0], interactive)
+			if err != nil || !started {
+				return err
+			}
+
+			stop, err = rebase.Start(repoPath, committer, edit)
+			if err != nil {
+				return err
+			}
+		}
+
+		return reportRebase(repoPath, stop)
+	},
+}
+
+// beginRebase records the todo list for rebasing HEAD onto upstream, letting the user edit it when interactive.
+// It reports false when there is nothing to do.
+func beginRebase(repoPath, upstream string, interactive bool) (bool, error) {
+	mergeHead, _, err := merge.ReadState(repoPath)
+	if err != nil {
+		return false, err
+	}
+
+	if mergeHead != "" {
+		return false, fmt.Errorf("a merge is in progress, resolve the conflicts and commit first")
+	}
+
+	if sequencer.InProgress(repoPath) {
+		return false, fmt.Errorf("a cherry-pick or revert is in progress, use --continue or --abort on it first")
+	}
+
+	// Every commit is replayed onto a fresh checkout, so local changes must be committed first
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to load index: %v", err)
+	}
+
+	status, err := getRepoStatus(repoPath, idx)
+	if err != nil {
+		return false, err
+	}
+
+	if len(status.Unmerged)+len(status.Staged)+len(status.Modified)+len(status.Deleted) > 0 {
+		return false, fmt.Errorf("cannot rebase with local changes, commit or stash them first")
+	}
+
+	onto, err := revparse.ResolveCommit(repoPath, upstream)
+	if err != nil {
+		return false, err
+	}
+
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %v", err)
+	}
+
+	if !interactive && head != "" {
+		upToDate, err := merge.IsAncestor(repoPath, onto, head)
+		if err != nil {
+			return false, err
+		}
+
+		if upToDate {
+			branch, err := refs.CurrentBranch(repoPath)
+			if err != nil || branch == "" {
+				branch = "HEAD"
+			}
+
+			fmt.Printf("Current branch %s is up to date.\n", branch)
+			return false, nil
+		}
+	}
+
+	todo, err := rebase.Plan(repoPath, onto)
+	if err != nil {
+		return false, err
+	}
+
+	err = rebase.Begin(repoPath, onto, todo)
+	if err != nil {
+		return false, err
+	}
+
+	if !interactive {
+		return true, nil
+	}
+
+	state, err := editTodo(repoPath)
+	if err != nil {
+		return false, errors.Join(err, rebase.Abort(repoPath))
+	}
+
+	if len(state.Todo) == 0 {
+		fmt.Println("Nothing to do")
+		return false, rebase.Abort(repoPath)
+	}
+
+	return true, nil
+}
+
+// editTodo opens the todo list of a rebase that hasn't started yet in the sequence editor and reads it back
+func editTodo(repoPath string) (*rebase.State, error) {
+	err := launchEditor(rebase.TodoPath(repoPath), sequenceEditorEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rebase.ReadState(repoPath)
+}
+
+// reportRebase explains why a rebase stopped, or that it finished
+func reportRebase(repoPath string, stop *rebase.Stop) error {
+	if stop == nil {
+		fmt.Printf("Successfully rebased and updated %s.\n", describeHEAD(repoPath))
+		return nil
+	}
+
+	commit, err := objects.ReadCommit(repoPath, stop.Instruction.Hash)
+	if err != nil {
+		return err
+	}
+
+	if stop.Conflicted() {
+		for _, line := range stop.Result.Messages {
+			fmt.Println(line)
+		}
+
+		return fmt.Errorf("could not apply %s... %s; fix the conflicts, add the files and run \"quill rebase --continue\", run \"quill rebase --skip\" to leave the commit out, or \"quill rebase --abort\" to cancel", objects.ShortHash(commit.Hash), objects.FirstLine(commit.Message))
+	}
+
+	fmt.Printf("Stopped at %s... %s\n", objects.ShortHash(commit.Hash), objects.FirstLine(commit.Message))
+	fmt.Println("You can amend the commit now by staging your changes, then run \"quill rebase --continue\"")
+	fmt.Println("to amend it and carry on with the rest of the rebase.")
+	return nil
+}
+
+// describeHEAD names the ref of the checked out branch, or says HEAD is detached
+func describeHEAD(repoPath string) string {
+	target, err := refs.ReadHEAD(repoPath)
+	if err != nil || target == "" {
+		return "detached HEAD"
+	}
+
+	return target
+}
+
+func init() {
+	rootCmd.AddCommand(rebaseCmd)
+	rebaseCmd.Flags().BoolP("interactive", "i", false, "Edit the list of commits to rebase before starting")
+	rebaseCmd.Flags().Bool("continue", false, "Commit the resolved conflicts or staged amendments and carry on")
+	rebaseCmd.Flags().Bool("skip", false, "Leave out the commit the rebase stopped at and carry on")
+	rebaseCmd.Flags().Bool("abort", false, "Cancel and return to the commit you started on")
+}
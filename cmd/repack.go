@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/gc"
+	"github.com/tejastn10/quill/pkg/pack"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+var repackCmd = &cobra.Command{
+	Use:   "repack [--window=<n>] [--depth=<n>]",
+	Short: "Pack reachable objects into a single pack file",
+	Long:  "Write every object reachable from refs, HEAD and the index into one pack file, storing similar objects as deltas against each other, then remove the loose copies and any older packs. Unreachable objects are left loose so gc can expire them.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		window, err := cmd.Flags().GetInt("window")
+		if err != nil {
+			return fmt.Errorf("failed to get window flag: %v", err)
+		}
+
+		depth, err := cmd.Flags().GetInt("depth")
+		if err != nil {
+			return fmt.Errorf("failed to get depth flag: %v", err)
+		}
+
+		if window < 0 || depth < 0 {
+			return fmt.Errorf("--window and --depth cannot be negative")
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		result, err := gc.Repack(repoPath, pack.Options{Window: window, Depth: depth})
+		if err != nil {
+			return fmt.Errorf("failed to repack: %v", err)
+		}
+
+		if result.Pack == "" {
+			fmt.Println("Nothing to pack.")
+			return nil
+		}
+
+		fmt.Printf("Packed %d object(s) into %s, %d stored as deltas.\n", result.Objects, result.Pack, result.Deltas)
+		fmt.Printf("Removed %d loose object(s)", result.Removed)
+		if result.Loosened > 0 {
+			fmt.Printf(", unpacked %d unreachable object(s)", result.Loosened)
+		}
+		fmt.Println(".")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repackCmd)
+	repackCmd.Flags().Int("window", pack.DefaultOptions.Window, "Number of preceding objects tried as delta bases")
+	repackCmd.Flags().Int("depth", pack.DefaultOptions.Depth, "Maximum length of delta chains")
+}
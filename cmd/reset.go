@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset [--soft | --mixed | --hard] [<revision>]",
+	Short: "Move the current branch to another commit",
+	Long:  "Point the current branch (or a detached HEAD) at the given revision, HEAD by default. --soft only moves the branch, --mixed (the default) also resets the index to the commit's tree, and --hard resets the index and the tracked files in the working tree, discarding local changes.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		soft, err := cmd.Flags().GetBool("soft")
+		if err != nil {
+			return fmt.Errorf("failed to get soft flag: %v", err)
+		}
+
+		mixed, err := cmd.Flags().GetBool("mixed")
+		if err != nil {
+			return fmt.Errorf("failed to get mixed flag: %v", err)
+		}
+
+		hard, err := cmd.Flags().GetBool("hard")
+		if err != nil {
+			return fmt.Errorf("failed to get hard flag: %v", err)
+		}
+
+		selected := 0
+		for _, flag := range []bool{soft, mixed, hard} {
+			if flag {
+				selected++
+			}
+		}
+		if selected > 1 {
+			return fmt.Errorf("--soft, --mixed and --hard cannot be used together")
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		rev := "HEAD"
+		if len(args) == 1 {
+			rev = args[0]
+		}
+
+		targetHash, err := revparse.ResolveCommit(repoPath, rev)
+		if err != nil {
+			return err
+		}
+
+		target, err := objects.ReadCommit(repoPath, targetHash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %v", targetHash, err)
+		}
+
+		mergeHead, _, err := merge.ReadState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if soft && mergeHead != "" {
+			return fmt.Errorf("cannot do a soft reset in the middle of a merge")
+		}
+
+		headHash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %v", err)
+		}
+
+		switch {
+		case soft:
+			// Only the branch moves
+		case hard:
+			headTree, _, err := getHEADTreeEntries(repoPath)
+			if err != nil {
+				return err
+			}
+
+			err = worktree.ResetHard(repoPath, headTree, target.Tree)
+			if err != nil {
+				return fmt.Errorf("failed to reset working tree: %v", err)
+			}
+		default:
+			err = worktree.ResetIndex(repoPath, target.Tree)
+			if err != nil {
+				return fmt.Errorf("failed to reset index: %v", err)
+			}
+		}
+
+		err = refs.AdvanceHEAD(repoPath, headHash, targetHash)
+		if err != nil {
+			return fmt.Errorf("failed to update HEAD: %v", err)
+		}
+
+		// Resetting abandons any merge in progress
+		err = merge.ClearState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if hard {
+			fmt.Printf("HEAD is now at %s %s\n", objects.ShortHash(targetHash), objects.FirstLine(target.Message))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resetCmd)
+	resetCmd.Flags().Bool("soft", false, "Only move the current branch")
+	resetCmd.Flags().Bool("mixed", false, "Move the current branch and reset the index (default)")
+	resetCmd.Flags().Bool("hard", false, "Move the current branch and reset the index and working tree")
+}
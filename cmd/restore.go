@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [--staged] <paths>...",
+	Short: "Discard working tree changes or unstage paths",
+	Long:  "Restore files in the working tree to their staged version, discarding local changes. With --staged, reset the index entries of the paths to HEAD instead, unstaging them while keeping the working tree as it is. Directories restore every file below them.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		staged, err := cmd.Flags().GetBool("staged")
+		if err != nil {
+			return fmt.Errorf("failed to get staged flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		idx, err := index.LoadIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to load index: %v", err)
+		}
+
+		candidates := make(map[string]bool)
+		for path := range idx.Entries {
+			candidates[path] = true
+		}
+
+		if !staged {
+			paths, err := expandPathspecs(repoPath, args, candidates)
+			if err != nil {
+				return err
+			}
+
+			return worktree.RestoreFiles(repoPath, paths)
+		}
+
+		// Paths deleted from the index can be unstaged too
+		headTree, headEntries, err := getHEADTreeEntries(repoPath)
+		if err != nil {
+			return err
+		}
+
+		for path := range headEntries {
+			candidates[path] = true
+		}
+
+		paths, err := expandPathspecs(repoPath, args, candidates)
+		if err != nil {
+			return err
+		}
+
+		return worktree.RestoreStaged(repoPath, headTree, paths)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().Bool("staged", false, "Unstage the paths by restoring their index entries from HEAD")
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/sequencer"
+)
+
+var revertCmd = &cobra.Command{
+	Use:   "revert <revision>... | revert (--continue | --abort)",
+	Short: "Undo the changes introduced by existing commits",
+	Long:  "For each given commit, apply the inverse of the change it made relative to its parent onto HEAD and record it as a new commit whose message names the reverted commit. When a change conflicts, the conflicts are left in the working tree; fix them, add the files and run --continue, or run --abort to return to where you started.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSequencer(cmd, args, sequencer.Revert)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(revertCmd)
+	revertCmd.Flags().Bool("continue", false, "Commit the resolved conflicts and carry on with the remaining commits")
+	revertCmd.Flags().Bool("abort", false, "Cancel and return to the commit you started on")
+}
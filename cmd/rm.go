@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm [--cached] [-r] [-f] <paths>...",
+	Short: "Remove files from the working tree and the index",
+	Long:  "Remove files from the index and the working tree so the deletion is recorded by the next commit. With --cached the files are only removed from the index and stay in the working tree as untracked files. Directories need -r. Files whose content differs from HEAD or from the index are kept unless --force is given.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cached, err := cmd.Flags().GetBool("cached")
+		if err != nil {
+			return fmt.Errorf("failed to get cached flag: %v", err)
+		}
+
+		recursive, err := cmd.Flags().GetBool("recursive")
+		if err != nil {
+			return fmt.Errorf("failed to get recursive flag: %v", err)
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		idx, err := index.LoadIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to load index: %v", err)
+		}
+
+		_, headEntries, err := getHEADTreeEntries(repoPath)
+		if err != nil {
+			return err
+		}
+
+		var paths []string
+		for _, arg := range args {
+			spec, err := resolvePathspec(repoPath, arg)
+			if err != nil {
+				return err
+			}
+
+			matched := idx.PathsUnder(spec)
+			if len(matched) == 0 {
+				return fmt.Errorf("pathspec %q did not match any file known to quill", arg)
+			}
+
+			if !recursive && (len(matched) > 1 || matched[0] != spec) {
+				return fmt.Errorf("not removing %q recursively without -r", arg)
+			}
+
+			paths = append(paths, matched...)
+		}
+
+		if !force {
+			for _, path := range paths {
+				err = checkRemovable(repoPath, idx, headEntries, path, cached)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, path := range paths {
+			if _, exists := idx.Entries[path]; !exists {
+				continue // Named twice
+			}
+
+			delete(idx.Entries, path)
+
+			if !cached {
+				err = worktree.RemoveFile(repoPath, path)
+				if err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("rm %q\n", path)
+		}
+
+		err = idx.SaveIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to save index: %v", err)
+		}
+
+		return nil
+	},
+}
+
+// checkRemovable refuses to remove a path whose content would be lost, mirroring git's safety checks
+func checkRemovable(repoPath string, idx *index.Index, headEntries map[string]objects.TreeEntry, path string, cached bool) error {
+	entry := idx.Entries[path]
+
+	headEntry, inHead := headEntries[path]
+	stagedChanges := !inHead || headEntry.Hash != entry.Hash || headEntry.Mode != entry.Mode
+
+	fileHash, mode, err := worktree.HashFile(repoPath, path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	localChanges := err == nil && (fileHash != entry.Hash || mode != entry.Mode)
+
+	switch {
+	case stagedChanges && localChanges:
+		return fmt.Errorf("%q has staged content different from both the file and HEAD (use -f to force removal)", path)
+	case cached:
+		return nil
+	case stagedChanges:
+		return fmt.Errorf("%q has changes staged in the index (use --cached to keep the file, or -f to force removal)", path)
+	case localChanges:
+		return fmt.Errorf("%q has local modifications (use --cached to keep the file, or -f to force removal)", path)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+	rmCmd.Flags().Bool("cached", false, "Only remove the paths from the index, keeping the working tree files")
+	rmCmd.Flags().BoolP("recursive", "r", false, "Allow removing directories recursively")
+	rmCmd.Flags().BoolP("force", "f", false, "Remove files even when they have changes")
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/storage"
 )
 
 var rootCmd = &cobra.Command{
@@ -25,3 +26,7 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&storage.VerifyOnRead, "verify-objects", false, "Re-hash every object read from the store and fail on corruption")
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/stash"
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash [push [-m <message>] [-u]] | stash list | stash show [-p] [<stash>] | stash (apply | pop | drop) [<stash>]",
+	Short: "Shelve local changes to get a clean working tree",
+	Long:  "Save the staged and unstaged changes to tracked files, and with --include-untracked the untracked files, as a new entry on the stash stack and reset the working tree to HEAD. Entries are named stash@{0} for the newest, stash@{1} for the one before and so on, and can be listed, shown, applied back onto the working tree, popped (applied and dropped) or dropped. Without a subcommand, stash runs push.",
+	Args:  cobra.NoArgs,
+	RunE:  runStashPush,
+}
+
+var stashPushCmd = &cobra.Command{
+	Use:   "push [-m <message>] [-u]",
+	Short: "Save local changes as a new stash entry",
+	Long:  "Record the index and the changes to tracked files as stash@{0}, then reset the index and working tree to HEAD. With --include-untracked, untracked files that aren't ignored are saved and removed too.",
+	Args:  cobra.NoArgs,
+	RunE:  runStashPush,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stash entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		entries, err := stash.List(repoPath)
+		if err != nil {
+			return err
+		}
+
+		for n, hash := range entries {
+			commit, err := objects.ReadCommit(repoPath, hash)
+			if err != nil {
+				return fmt.Errorf("failed to read stash@{%d}: %v", n, err)
+			}
+			fmt.Printf("stash@{%d}: %s\n", n, objects.FirstLine(commit.Message))
+		}
+
+		return nil
+	},
+}
+
+var stashShowCmd = &cobra.Command{
+	Use:   "show [-p] [<stash>]",
+	Short: "Show the changes recorded in a stash entry",
+	Long:  "List the files a stash entry changes compared to the commit it was made on, or show the full diff with --patch. Defaults to stash@{0}.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		patch, err := cmd.Flags().GetBool("patch")
+		if err != nil {
+			return fmt.Errorf("failed to get patch flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		n, err := parseStashName(args)
+		if err != nil {
+			return err
+		}
+
+		hash, err := stash.Get(repoPath, n)
+		if err != nil {
+			return err
+		}
+
+		parts, err := stash.ReadParts(repoPath, hash)
+		if err != nil {
+			return err
+		}
+
+		if patch {
+			baseEntries, err := objects.GetTreeEntries(repoPath, parts.Base.Tree)
+			if err != nil {
+				return fmt.Errorf("failed to read tree of %s: %v", parts.Base.Hash, err)
+			}
+
+			stashEntries, err := objects.GetTreeEntries(repoPath, parts.Working.Tree)
+			if err != nil {
+				return fmt.Errorf("failed to read tree of stash@{%d}: %v", n, err)
+			}
+
+			return printDiff(repoPath, treeVersions(baseEntries), treeVersions(stashEntries))
+		}
+
+		changes, err := getCommitChanges(repoPath, parts.Working.Tree, parts.Base.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to get stash changes: %v", err)
+		}
+		sort.Strings(changes)
+
+		for _, change := range changes {
+			fmt.Printf("    %s\n", change)
+		}
+
+		return nil
+	},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [<stash>]",
+	Short: "Apply a stash entry on top of the working tree",
+	Long:  "Restore the staged and unstaged changes of a stash entry, stash@{0} unless another is given, and keep the entry on the stack.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return applyStash(args, false)
+	},
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:   "pop [<stash>]",
+	Short: "Apply a stash entry and remove it from the stack",
+	Long:  "Restore the staged and unstaged changes of a stash entry, stash@{0} unless another is given, then drop it. An entry whose changes conflict is kept.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return applyStash(args, true)
+	},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [<stash>]",
+	Short: "Remove a stash entry from the stack",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		n, err := parseStashName(args)
+		if err != nil {
+			return err
+		}
+
+		hash, err := stash.Drop(repoPath, n)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Dropped stash@{%d} (%s)\n", n, objects.ShortHash(hash))
+		return nil
+	},
+}
+
+// runStashPush saves the local changes as stash@{0}
+func runStashPush(cmd *cobra.Command, args []string) error {
+	message, err := cmd.Flags().GetString("message")
+	if err != nil {
+		return fmt.Errorf("failed to get message flag: %v", err)
+	}
+
+	includeUntracked, err := cmd.Flags().GetBool("include-untracked")
+	if err != nil {
+		return fmt.Errorf("failed to get include-untracked flag: %v", err)
+	}
+
+	// Find repository root
+	repoPath, err := repo.FindRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to locate repository: %v", err)
+	}
+
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	author, committer, err := commitSignatures(repoPath, "", "")
+	if err != nil {
+		return err
+	}
+
+	hash, err := stash.Push(repoPath, message, includeUntracked, author, committer)
+	if errors.Is(err, stash.ErrNoChanges) {
+		fmt.Println("No local changes to save.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	commit, err := objects.ReadCommit(repoPath, hash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved working directory and index state %s\n", commit.Message)
+	return nil
+}
+
+// applyStash applies the named stash entry and, with drop, removes it once it applied without conflicts
+func applyStash(args []string, drop bool) error {
+	// Find repository root
+	repoPath, err := repo.FindRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to locate repository: %v", err)
+	}
+
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	n, err := parseStashName(args)
+	if err != nil {
+		return err
+	}
+
+	result, err := stash.Apply(repoPath, n)
+	if err != nil {
+		return err
+	}
+
+	if !result.Clean() {
+		for _, line := range result.Messages {
+			fmt.Println(line)
+		}
+
+		if drop {
+			fmt.Println("The stash entry is kept in case you need it again.")
+		}
+		return fmt.Errorf("conflicts in stash@{%d}; fix them and add the files", n)
+	}
+
+	fmt.Printf("Applied stash@{%d}\n", n)
+
+	if drop {
+		hash, err := stash.Drop(repoPath, n)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dropped stash@{%d} (%s)\n", n, objects.ShortHash(hash))
+	}
+
+	return nil
+}
+
+// parseStashName reads an optional stash entry given as stash@{n} or n, defaulting to stash@{0}
+func parseStashName(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	name := args[0]
+	if inner, found := strings.CutPrefix(name, "stash@{"); found {
+		name = strings.TrimSuffix(inner, "}")
+	}
+
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a stash entry, use stash@{n}", args[0])
+	}
+
+	return n, nil
+}
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+	stashCmd.AddCommand(stashPushCmd, stashListCmd, stashShowCmd, stashApplyCmd, stashPopCmd, stashDropCmd)
+
+	for _, cmd := range []*cobra.Command{stashCmd, stashPushCmd} {
+		cmd.Flags().StringP("message", "m", "", "Describe the stash entry")
+		cmd.Flags().BoolP("include-untracked", "u", false, "Also stash untracked files that aren't ignored")
+	}
+	stashShowCmd.Flags().BoolP("patch", "p", false, "Show the full diff")
+}
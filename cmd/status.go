@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/ignore"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/rebase"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/sequencer"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// repoStatus holds the differences between HEAD, the index and the working tree
+type repoStatus struct {
+	Unmerged  []string
+	Staged    []string
+	Modified  []string
+	Deleted   []string
+	Untracked []string
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the working tree status",
+	Long:  "Display paths that differ between the index and the HEAD commit, paths that differ between the working tree and the index, and paths that are not tracked by Quill.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		// Load the index
+		idx, err := index.LoadIndex(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to load index: %v", err)
+		}
+
+		status, err := getRepoStatus(repoPath, idx)
+		if err != nil {
+			return err
+		}
+
+		// Show which branch is checked out
+		branch, err := refs.CurrentBranch(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read HEAD: %v", err)
+		}
+
+		if branch != "" {
+			fmt.Printf("On branch %s\n\n", branch)
+		} else {
+			headHash, err := refs.ResolveHEAD(repoPath)
+			if err != nil {
+				return fmt.Errorf("failed to get HEAD: %v", err)
+			}
+			fmt.Printf("HEAD detached at %s\n\n", objects.ShortHash(headHash))
+		}
+
+		mergeHead, _, err := merge.ReadState(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if mergeHead != "" {
+			if len(status.Unmerged) > 0 {
+				fmt.Println("You have unmerged paths.")
+				fmt.Println("  (fix conflicts, add the files and run \"quill commit\")")
+			} else {
+				fmt.Println("All conflicts fixed but you are still merging.")
+				fmt.Println("  (use \"quill commit\" to conclude merge)")
+			}
+			fmt.Println()
+		}
+
+		if sequencer.InProgress(repoPath) {
+			_, steps, err := sequencer.ReadState(repoPath)
+			if err != nil {
+				return err
+			}
+
+			if len(steps) > 0 {
+				name, doing := "cherry-pick", "cherry-picking"
+				if steps[0].Action == sequencer.Revert {
+					name, doing = "revert", "reverting"
+				}
+
+				fmt.Printf("You are currently %s commit %s.\n", doing, objects.ShortHash(steps[0].Hash))
+				fmt.Printf("  (fix conflicts, add the files and run \"quill %s --continue\")\n", name)
+				fmt.Printf("  (use \"quill %s --abort\" to cancel)\n\n", name)
+			}
+		}
+
+		if rebase.InProgress(repoPath) {
+			state, err := rebase.ReadState(repoPath)
+			if err != nil {
+				return err
+			}
+
+			rebasing := "detached HEAD"
+			if state.HeadName != "" {
+				rebasing = "branch '" + strings.TrimPrefix(state.HeadName, refs.HeadsPrefix) + "'"
+			}
+
+			fmt.Printf("You are currently rebasing %s onto %s.\n", rebasing, objects.ShortHash(state.Onto))
+			if len(state.Done) > 0 {
+				fmt.Printf("Last command done: %s\n", state.Done[len(state.Done)-1])
+			}
+
+			if len(status.Unmerged) > 0 {
+				fmt.Println("  (fix conflicts, add the files and run \"quill rebase --continue\")")
+			} else {
+				fmt.Println("  (use \"quill rebase --continue\" once you are done)")
+			}
+			fmt.Println("  (use \"quill rebase --skip\" to leave out this commit)")
+			fmt.Println("  (use \"quill rebase --abort\" to return to where you started)")
+			fmt.Println()
+		}
+
+		if len(status.Unmerged)+len(status.Staged)+len(status.Modified)+len(status.Deleted)+len(status.Untracked) == 0 {
+			fmt.Println("Nothing to commit, working tree clean.")
+			return nil
+		}
+
+		printStatusSection("Unmerged paths:", "\033[31m", status.Unmerged)
+		printStatusSection("Changes staged for commit:", "\033[32m", status.Staged)
+		printStatusSection("Changes not staged for commit:", "\033[31m", status.Modified)
+		printStatusSection("Deleted files:", "\033[31m", status.Deleted)
+		printStatusSection("Untracked files:", "\033[31m", status.Untracked)
+
+		return nil
+	},
+}
+
+// getRepoStatus compares the working tree, the index and the HEAD commit tree
+func getRepoStatus(repoPath string, idx *index.Index) (*repoStatus, error) {
+	// Get the tree of the current HEAD commit
+	headTree, headEntries, err := getHEADTreeEntries(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the files present in the working tree, leaving out ignored ones
+	workingFiles, err := worktree.ListFiles(repoPath, ignore.NewMatcher(repoPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan working tree: %v", err)
+	}
+
+	status := &repoStatus{}
+
+	// Entries that were not staged since the last commit still match HEAD,
+	// as long as the index was last reconciled against the same tree
+	trustStaged := headTree != "" && idx.LastCommitTree == headTree
+
+	// Index vs HEAD
+	for path, entry := range idx.Entries {
+		if entry.Conflict != nil {
+			status.Unmerged = append(status.Unmerged, conflictLabel(entry.Conflict)+path)
+			continue
+		}
+
+		if trustStaged && !entry.Staged {
+			continue
+		}
+
+		headEntry, exists := headEntries[path]
+		if !exists {
+			status.Staged = append(status.Staged, fmt.Sprintf("new file:   %s", path))
+		} else if headEntry.Hash != entry.Hash || headEntry.Mode != entry.Mode {
+			status.Staged = append(status.Staged, fmt.Sprintf("modified:   %s", path))
+		}
+	}
+
+	for path := range headEntries {
+		if _, exists := idx.Entries[path]; !exists {
+			status.Staged = append(status.Staged, fmt.Sprintf("deleted:    %s", path))
+		}
+	}
+
+	// Working tree vs index
+	for path, entry := range idx.Entries {
+		// Conflicted files are reported as unmerged until they are added
+		if entry.Conflict != nil {
+			continue
+		}
+
+		// Tracked files are compared even when they match an ignore pattern
+		fileHash, mode, err := worktree.HashFile(repoPath, path)
+		if errors.Is(err, os.ErrNotExist) {
+			status.Deleted = append(status.Deleted, fmt.Sprintf("deleted:    %s", path))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if fileHash != entry.Hash || mode != entry.Mode {
+			status.Modified = append(status.Modified, fmt.Sprintf("modified:   %s", path))
+		}
+	}
+
+	// Files in the working tree that the index doesn't know about
+	for path := range workingFiles {
+		if _, exists := idx.Entries[path]; !exists {
+			status.Untracked = append(status.Untracked, path)
+		}
+	}
+
+	sort.Strings(status.Unmerged)
+	sort.Strings(status.Staged)
+	sort.Strings(status.Modified)
+	sort.Strings(status.Deleted)
+	sort.Strings(status.Untracked)
+
+	return status, nil
+}
+
+// conflictLabel describes how both sides of a merge changed a conflicted path
+func conflictLabel(conflict *index.Conflict) string {
+	switch {
+	case conflict.Ours == "":
+		return "deleted by us:   "
+	case conflict.Theirs == "":
+		return "deleted by them: "
+	case conflict.Base == "":
+		return "both added:      "
+	default:
+		return "both modified:   "
+	}
+}
+
+// getHEADTreeEntries returns the tree hash and entries of the current HEAD commit
+func getHEADTreeEntries(repoPath string) (string, map[string]objects.TreeEntry, error) {
+	headHash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get HEAD: %v", err)
+	}
+
+	// No commits yet
+	if headHash == "" {
+		return "", map[string]objects.TreeEntry{}, nil
+	}
+
+	commit, err := objects.ReadCommit(repoPath, headHash)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read HEAD commit: %v", err)
+	}
+
+	entries, err := objects.GetTreeEntries(repoPath, commit.Tree)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read HEAD tree: %v", err)
+	}
+
+	return commit.Tree, entries, nil
+}
+
+// printStatusSection prints a titled, colored list of paths when it isn't empty
+func printStatusSection(title, color string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Println(title)
+	for _, line := range lines {
+		fmt.Printf("    %s%s\033[0m\n", color, line)
+	}
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+var switchCmd = &cobra.Command{
+	Use:   "switch [-c <new-branch>] <branch>",
+	Short: "Switch branches",
+	Long:  "Switch to the given branch, updating the working tree and the index to match it. Use --detach to switch to a commit without a branch. Refuses to overwrite local changes unless --force is given.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newBranch, err := cmd.Flags().GetString("create")
+		if err != nil {
+			return fmt.Errorf("failed to get create flag: %v", err)
+		}
+
+		detach, err := cmd.Flags().GetBool("detach")
+		if err != nil {
+			return fmt.Errorf("failed to get detach flag: %v", err)
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		unlock, err := index.Lock(repoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if newBranch != "" {
+			if detach {
+				return fmt.Errorf("--create and --detach cannot be used together")
+			}
+
+			startPoint := ""
+			if len(args) == 1 {
+				startPoint = args[0]
+			}
+			return createAndSwitch(repoPath, newBranch, startPoint, force)
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("a branch to switch to is required")
+		}
+
+		// Unlike checkout, switch only detaches HEAD when asked to
+		if !detach && !refs.BranchExists(repoPath, args[0]) {
+			return fmt.Errorf("a branch is expected, got %q (use --detach to switch to a commit)", args[0])
+		}
+
+		return switchTo(repoPath, args[0], detach, force)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(switchCmd)
+	switchCmd.Flags().StringP("create", "c", "", "Create a new branch and switch to it")
+	switchCmd.Flags().Bool("detach", false, "Switch to a commit with a detached HEAD")
+	switchCmd.Flags().BoolP("force", "f", false, "Discard local changes that would be overwritten")
+}
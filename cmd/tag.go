@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/revparse"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [-a -m <message>] [-f] <name> [<revision>] | tag -l [<pattern>...] | tag -d <name>...",
+	Short: "Create, list or delete tags",
+	Long:  "With no arguments, list existing tags. With a name, create a lightweight tag at HEAD or at the given revision, or an annotated tag object with -a and -m. Use -l with glob patterns to filter the listing and -d to delete tags.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		annotate, err := cmd.Flags().GetBool("annotate")
+		if err != nil {
+			return fmt.Errorf("failed to get annotate flag: %v", err)
+		}
+
+		message, err := cmd.Flags().GetString("message")
+		if err != nil {
+			return fmt.Errorf("failed to get message flag: %v", err)
+		}
+
+		list, err := cmd.Flags().GetBool("list")
+		if err != nil {
+			return fmt.Errorf("failed to get list flag: %v", err)
+		}
+
+		deleteTag, err := cmd.Flags().GetBool("delete")
+		if err != nil {
+			return fmt.Errorf("failed to get delete flag: %v", err)
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %v", err)
+		}
+
+		// Find repository root
+		repoPath, err := repo.FindRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to locate repository: %v", err)
+		}
+
+		switch {
+		case list && deleteTag:
+			return fmt.Errorf("-l and -d cannot be used together")
+		case deleteTag:
+			return deleteTags(repoPath, args)
+		case list || len(args) == 0:
+			return listTags(repoPath, args)
+		case len(args) <= 2:
+			// A message always makes an annotated tag, as with git
+			if annotate && message == "" {
+				return fmt.Errorf("annotated tags require a message, use -m")
+			}
+			return createTag(repoPath, args, message, force)
+		default:
+			return fmt.Errorf("too many arguments")
+		}
+	},
+}
+
+// listTags prints every tag matching any of the glob patterns, or all tags without patterns
+func listTags(repoPath string, patterns []string) error {
+	tags, err := refs.ListTags(repoPath)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if len(patterns) == 0 {
+			fmt.Println(tag)
+			continue
+		}
+
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, tag)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+
+			if matched {
+				fmt.Println(tag)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// createTag creates a lightweight tag, or an annotated tag object when a message is given
+func createTag(repoPath string, args []string, message string, force bool) error {
+	name := args[0]
+
+	err := refs.ValidateTagName(name)
+	if err != nil {
+		return err
+	}
+
+	if refs.TagExists(repoPath, name) && !force {
+		return fmt.Errorf("tag %q already exists", name)
+	}
+
+	rev := "HEAD"
+	if len(args) == 2 {
+		rev = args[1]
+	}
+
+	targetHash, err := revparse.Resolve(repoPath, rev)
+	if err != nil {
+		return err
+	}
+
+	if message != "" {
+		userName, email, err := repo.ReadUserConfig(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read user config: %v", err)
+		}
+
+		tagger := fmt.Sprintf("%s <%s>", userName, email)
+
+		targetHash, err = objects.CreateTag(repoPath, name, targetHash, tagger, message)
+		if err != nil {
+			return fmt.Errorf("failed to create tag object: %v", err)
+		}
+	}
+
+	err = refs.CreateTag(repoPath, name, targetHash, force)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created tag %s at %s\n", name, objects.ShortHash(targetHash))
+	return nil
+}
+
+// deleteTags removes each of the named tags
+func deleteTags(repoPath string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("tag name required")
+	}
+
+	for _, name := range names {
+		hash, err := refs.ReadTag(repoPath, name)
+		if err != nil {
+			return err
+		}
+
+		err = refs.DeleteTag(repoPath, name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleted tag %s (was %s)\n", name, objects.ShortHash(hash))
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.Flags().BoolP("annotate", "a", false, "Create an annotated tag object")
+	tagCmd.Flags().StringP("message", "m", "", "Message for an annotated tag")
+	tagCmd.Flags().BoolP("list", "l", false, "List tags, optionally filtered by glob patterns")
+	tagCmd.Flags().BoolP("delete", "d", false, "Delete the named tags")
+	tagCmd.Flags().BoolP("force", "f", false, "Replace an existing tag")
+}
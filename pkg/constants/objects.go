@@ -0,0 +1,9 @@
+package constants
+
+// Object types recorded in the header of every stored object
+const (
+	BlobObject   = "blob"
+	TreeObject   = "tree"
+	CommitObject = "commit"
+	TagObject    = "tag"
+)
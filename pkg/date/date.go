@@ -0,0 +1,125 @@
+package date
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layouts are the absolute date formats Parse accepts, tried in order
+var layouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.UnixDate,
+}
+
+// units maps the singular names of relative date units to their length
+var units = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+}
+
+// Parse understands absolute dates such as "2024-05-01" or "2024-05-01 13:45:00 +0200", unix timestamps
+// written as "@1714567890" or "1714567890 +0200", and relative dates such as "now", "yesterday",
+// "2 weeks ago" or "2.weeks.ago". Dates without a time zone are taken in the local zone.
+func Parse(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if seconds, isUnix := strings.CutPrefix(value, "@"); isUnix {
+		return parseUnix(value, seconds)
+	}
+
+	if fields := strings.Fields(value); len(fields) == 2 && isNumber(fields[0]) {
+		return parseUnix(value, value)
+	}
+
+	for _, layout := range layouts {
+		parsed, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	relative, err := parseRelative(value, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return relative, nil
+}
+
+// parseUnix reads seconds since the epoch, optionally followed by a "+hhmm" zone to present the time in
+func parseUnix(value, timestamp string) (time.Time, error) {
+	seconds, zone, hasZone := strings.Cut(strings.TrimSpace(timestamp), " ")
+
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix timestamp %q", value)
+	}
+
+	if !hasZone {
+		return time.Unix(unix, 0), nil
+	}
+
+	offset, err := time.Parse("-0700", strings.TrimSpace(zone))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time zone in %q", value)
+	}
+
+	return time.Unix(unix, 0).In(offset.Location()), nil
+}
+
+// isNumber reports whether value is made of decimal digits only
+func isNumber(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+// parseRelative handles "now", "yesterday" and "<n> <unit> ago", with dots or underscores allowed as separators
+func parseRelative(value string, now time.Time) (time.Time, error) {
+	fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ' ' || r == '.' || r == '_'
+	})
+
+	switch {
+	case len(fields) == 1 && fields[0] == "now":
+		return now, nil
+	case len(fields) == 1 && fields[0] == "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case len(fields) != 3 || fields[2] != "ago":
+		return time.Time{}, fmt.Errorf("unrecognized date")
+	}
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count < 0 {
+		return time.Time{}, fmt.Errorf("unrecognized count %q", fields[0])
+	}
+
+	unit := strings.TrimSuffix(fields[1], "s")
+	switch unit {
+	case "month":
+		return now.AddDate(0, -count, 0), nil
+	case "year":
+		return now.AddDate(-count, 0, 0), nil
+	}
+
+	length, known := units[unit]
+	if !known {
+		return time.Time{}, fmt.Errorf("unrecognized unit %q", fields[1])
+	}
+
+	return now.Add(-time.Duration(count) * length), nil
+}
@@ -0,0 +1,113 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"now", now},
+		{"yesterday", now.AddDate(0, 0, -1)},
+		{"2 weeks ago", now.Add(-14 * 24 * time.Hour)},
+		{"2.weeks.ago", now.Add(-14 * 24 * time.Hour)},
+		{"1 hour ago", now.Add(-time.Hour)},
+		{"3 months ago", time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"1.year.ago", time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)},
+		{"@1700000000", time.Unix(1700000000, 0)},
+		{"@1700000000 +0530", time.Unix(1700000000, 0)},
+		{"1700000000 -0800", time.Unix(1700000000, 0)},
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02 03:04:05 +0200", time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC)},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value, now)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	// Unix timestamps keep the zone they were given in
+	zoned, err := Parse("1700000000 +0530", now)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if _, offset := zoned.Zone(); offset != 5*3600+30*60 {
+		t.Errorf("Expected a +0530 offset, got %d seconds", offset)
+	}
+
+	for _, value := range []string{"", "soon", "two weeks ago", "3 fortnights ago", "@abc", "@1700000000 CET"} {
+		if _, err := Parse(value, now); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", value)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 2*3600))
+	now := when.Add(3 * 24 * time.Hour)
+
+	tests := map[Style]string{
+		Default:  "Tue Jan 2 03:04:05 2024 +0200",
+		ISO:      "2024-01-02 03:04:05 +0200",
+		Relative: "3 days ago",
+		Unix:     "1704157445",
+		Short:    "2024-01-02",
+	}
+
+	for style, want := range tests {
+		parsed, err := ParseStyle(string(style))
+		if err != nil {
+			t.Fatalf("ParseStyle(%q) failed: %v", style, err)
+		}
+
+		if got := Format(when, parsed, now); got != want {
+			t.Errorf("Format in %s = %q, want %q", style, got, want)
+		}
+	}
+
+	if _, err := ParseStyle("rfc"); err == nil {
+		t.Errorf("Expected an unknown style to be rejected")
+	}
+}
+
+func TestAgo(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{time.Second, "1 second ago"},
+		{45 * time.Second, "45 seconds ago"},
+		{10 * time.Minute, "10 minutes ago"},
+		{5 * time.Hour, "5 hours ago"},
+		{3 * 24 * time.Hour, "3 days ago"},
+		{15 * 24 * time.Hour, "2 weeks ago"},
+		{100 * 24 * time.Hour, "3 months ago"},
+		{400 * 24 * time.Hour, "1 year, 1 month ago"},
+		{3 * 365 * 24 * time.Hour, "3 years ago"},
+		{8 * 365 * 24 * time.Hour, "8 years ago"},
+		{-time.Hour, "in the future"},
+	}
+
+	for _, tt := range tests {
+		if got := Ago(now.Add(-tt.elapsed), now); got != tt.want {
+			t.Errorf("Ago(%v before) = %q, want %q", tt.elapsed, got, tt.want)
+		}
+	}
+}
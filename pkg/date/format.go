@@ -0,0 +1,98 @@
+package date
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Style is a way of showing a date
+type Style string
+
+const (
+	// Default shows the date the way log has always shown it, "Mon Jan 2 15:04:05 2006 -0700"
+	Default Style = "default"
+
+	// ISO shows "2006-01-02 15:04:05 -0700"
+	ISO Style = "iso"
+
+	// Relative shows how long ago the date was, such as "3 days ago"
+	Relative Style = "relative"
+
+	// Unix shows seconds since the epoch
+	Unix Style = "unix"
+
+	// Short shows the day only, "2006-01-02"
+	Short Style = "short"
+)
+
+// ParseStyle returns the style with the given name
+func ParseStyle(name string) (Style, error) {
+	switch style := Style(name); style {
+	case Default, ISO, Relative, Unix, Short:
+		return style, nil
+	}
+
+	return "", fmt.Errorf("unknown date format %q, use default, iso, relative, unix or short", name)
+}
+
+// Format shows a date in the given style. Dates keep their own time zone, and now is what relative dates are measured against.
+func Format(when time.Time, style Style, now time.Time) string {
+	switch style {
+	case ISO:
+		return when.Format("2006-01-02 15:04:05 -0700")
+	case Relative:
+		return Ago(when, now)
+	case Unix:
+		return strconv.FormatInt(when.Unix(), 10)
+	case Short:
+		return when.Format("2006-01-02")
+	}
+
+	return when.Format("Mon Jan 2 15:04:05 2006 -0700")
+}
+
+// Ago describes how long before now a date was, in the largest unit that still reads naturally
+func Ago(when, now time.Time) string {
+	elapsed := now.Sub(when)
+	if elapsed < 0 {
+		return "in the future"
+	}
+
+	seconds := int64(elapsed / time.Second)
+	switch {
+	case seconds < 90:
+		return plural(seconds, "second") + " ago"
+	case seconds < 90*60:
+		return plural((seconds+30)/60, "minute") + " ago"
+	case seconds < 36*3600:
+		return plural((seconds+1800)/3600, "hour") + " ago"
+	}
+
+	days := (seconds + 43200) / 86400
+	switch {
+	case days < 14:
+		return plural(days, "day") + " ago"
+	case days < 70:
+		return plural((days+3)/7, "week") + " ago"
+	case days < 365:
+		return plural((days+15)/30, "month") + " ago"
+	}
+
+	// Years alone are too coarse for the first few
+	months := (days*12 + 182) / 365
+	years, rest := months/12, months%12
+	if years < 5 && rest > 0 {
+		return plural(years, "year") + ", " + plural(rest, "month") + " ago"
+	}
+
+	return plural((days+182)/365, "year") + " ago"
+}
+
+// plural writes a count with its unit, adding an s unless there is exactly one
+func plural(count int64, unit string) string {
+	if count == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
@@ -0,0 +1,173 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Operation describes what happened to a line between two versions
+type Operation int
+
+const (
+	Equal Operation = iota
+	Insert
+	Delete
+)
+
+// Edit is a single line-level step in a diff script
+type Edit struct {
+	Op   Operation
+	Line string
+}
+
+// binaryProbeSize is how much of a file is inspected when looking for binary content
+const binaryProbeSize = 8000
+
+// IsBinary reports whether data looks like binary content, using the same NUL byte heuristic as git
+func IsBinary(data []byte) bool {
+	probe := data
+	if len(probe) > binaryProbeSize {
+		probe = probe[:binaryProbeSize]
+	}
+
+	return bytes.IndexByte(probe, 0) != -1
+}
+
+// SplitLines splits data into lines, keeping the trailing newline on every line that has one
+func SplitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// SplitAfter leaves an empty element after a trailing newline
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// Diff computes the shortest edit script turning a into b using the Myers algorithm
+func Diff(a, b []string) []Edit {
+	// Strip the common prefix and suffix, they never take part in the edit script
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+
+	return edits
+}
+
+// myers runs the greedy O(ND) forward search and backtracks through the recorded frontiers
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the frontier before step d, restricted to diagonals -d..d
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Move down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // Move right: deletion
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrack walks the recorded frontiers from the end of both sequences back to the start
+func backtrack(trace [][]int, a, b []string) []Edit {
+	var reversed []Edit
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		frontier := trace[d]
+		at := func(k int) int {
+			return frontier[k+d]
+		}
+
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		// Follow the snake of equal lines back to where this step started
+		for x > prevX && y > prevY && x > 0 && y > 0 {
+			reversed = append(reversed, Edit{Op: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Edit{Op: Insert, Line: b[y-1]})
+			} else {
+				reversed = append(reversed, Edit{Op: Delete, Line: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+
+	return edits
+}
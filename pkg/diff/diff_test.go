@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both sides of a diff from its edit script
+func apply(edits []Edit) (string, string) {
+	var oldText, newText strings.Builder
+	for _, edit := range edits {
+		if edit.Op != Insert {
+			oldText.WriteString(edit.Line)
+		}
+		if edit.Op != Delete {
+			newText.WriteString(edit.Line)
+		}
+	}
+	return oldText.String(), newText.String()
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		changes int
+	}{
+		{"Identical", "a\nb\nc\n", "a\nb\nc\n", 0},
+		{"BothEmpty", "", "", 0},
+		{"AddAll", "", "a\nb\n", 2},
+		{"DeleteAll", "a\nb\n", "", 2},
+		{"InsertMiddle", "a\nc\n", "a\nb\nc\n", 1},
+		{"DeleteMiddle", "a\nb\nc\n", "a\nc\n", 1},
+		{"Replace", "a\nb\nc\n", "a\nx\nc\n", 2},
+		{"Classic", "a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 5},
+		{"MissingNewline", "a\nb", "a\nb\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := Diff(SplitLines([]byte(tt.old)), SplitLines([]byte(tt.new)))
+
+			oldText, newText := apply(edits)
+			if oldText != tt.old || newText != tt.new {
+				t.Fatalf("edit script does not rebuild inputs: got (%q, %q)", oldText, newText)
+			}
+
+			changes := 0
+			for _, edit := range edits {
+				if edit.Op != Equal {
+					changes++
+				}
+			}
+			if changes != tt.changes {
+				t.Errorf("expected %d changed lines, got %d", tt.changes, changes)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	oldData := []byte("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n")
+	newData := []byte("one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n")
+
+	got := Unified("a/file.txt", "b/file.txt", oldData, newData, DefaultContext)
+	want := "--- a/file.txt\n" +
+		"+++ b/file.txt\n" +
+		"@@ -1,6 +1,6 @@\n" +
+		" one\n two\n-three\n+THREE\n four\n five\n six\n" +
+		"@@ -8,3 +8,4 @@\n" +
+		" eight\n nine\n ten\n+eleven\n"
+
+	if got != want {
+		t.Errorf("unexpected unified diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedNewFile(t *testing.T) {
+	got := Unified("/dev/null", "b/file.txt", nil, []byte("hello"), DefaultContext)
+	want := "--- /dev/null\n+++ b/file.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n"
+
+	if got != want {
+		t.Errorf("unexpected unified diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedIdentical(t *testing.T) {
+	data := []byte("same\n")
+	if got := Unified("a/x", "b/x", data, data, DefaultContext); got != "" {
+		t.Errorf("expected no output for identical files, got %q", got)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary([]byte("plain text\n")) {
+		t.Errorf("text detected as binary")
+	}
+
+	if !IsBinary([]byte{0x89, 'P', 'N', 'G', 0x00, 0x01}) {
+		t.Errorf("binary data not detected")
+	}
+
+	got := Unified("a/img", "b/img", []byte{0x00, 0x01}, []byte{0x00, 0x02}, DefaultContext)
+	if got != "Binary files differ\n" {
+		t.Errorf("unexpected output for binary files: %q", got)
+	}
+}
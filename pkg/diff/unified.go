@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// Hunk is a group of nearby edits together with their surrounding context
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Edits    []Edit
+}
+
+// Header returns the "@@ -a,b +c,d @@" line for the hunk
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+}
+
+// formatRange formats a hunk range the way unified diffs do, omitting a count of one
+func formatRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Hunks groups an edit script into hunks with the given amount of context
+func Hunks(edits []Edit, context int) []Hunk {
+	// Line numbers (zero based) in the old and new versions before each edit
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		oldPos[i+1] = oldPos[i]
+		newPos[i+1] = newPos[i]
+		if edit.Op != Insert {
+			oldPos[i+1]++
+		}
+		if edit.Op != Delete {
+			newPos[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+
+	i := 0
+	for i < len(edits) {
+		// Skip ahead to the next change
+		for i < len(edits) && edits[i].Op == Equal {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		start := max(i-context, 0)
+		end := i
+
+		for {
+			// Consume the run of changes
+			for end < len(edits) && edits[end].Op != Equal {
+				end++
+			}
+
+			// Merge with the next change if the gap fits inside both contexts
+			next := end
+			for next < len(edits) && edits[next].Op == Equal {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+
+			end = min(end+context, len(edits))
+			break
+		}
+
+		hunk := Hunk{
+			OldStart: oldPos[start],
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start],
+			NewLines: newPos[end] - newPos[start],
+			Edits:    edits[start:end],
+		}
+
+		// Ranges are one based unless they are empty
+		if hunk.OldLines > 0 {
+			hunk.OldStart++
+		}
+		if hunk.NewLines > 0 {
+			hunk.NewStart++
+		}
+
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// Unified renders a unified diff between two versions of a file, or an empty string when they are identical
+func Unified(oldName, newName string, oldData, newData []byte, context int) string {
+	if IsBinary(oldData) || IsBinary(newData) {
+		if string(oldData) == string(newData) {
+			return ""
+		}
+		return "Binary files differ\n"
+	}
+
+	hunks := Hunks(Diff(SplitLines(oldData), SplitLines(newData)), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n", oldName)
+	fmt.Fprintf(&builder, "+++ %s\n", newName)
+
+	for _, hunk := range hunks {
+		builder.WriteString(hunk.Header())
+		builder.WriteString("\n")
+
+		for _, edit := range hunk.Edits {
+			switch edit.Op {
+			case Equal:
+				builder.WriteString(" ")
+			case Insert:
+				builder.WriteString("+")
+			case Delete:
+				builder.WriteString("-")
+			}
+
+			builder.WriteString(edit.Line)
+			if !strings.HasSuffix(edit.Line, "\n") {
+				builder.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,332 @@
+package fsck
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/stash"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// link is a reference from one object to another along with the type the target has to be
+type link struct {
+	hash    string
+	objType string
+}
+
+// Report lists what a check of the object store found
+type Report struct {
+	// Problems are corrupt objects, broken links and refs pointing at missing objects
+	Problems []string
+
+	// Unreachable holds "<type> <hash>" for every object no root leads to
+	Unreachable []string
+
+	// Dangling is the subset of unreachable objects that no other object refers to
+	Dangling []string
+}
+
+// OK reports whether the check found no problems. Unreachable objects are not problems.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// links returns the objects referenced by the content of an object
+func links(hash, objType string, data []byte) ([]link, error) {
+	err := objects.Validate(objType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch objType {
+	case constants.TreeObject:
+		tree, err := objects.ParseTree(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var result []link
+		for _, entry := range tree.Entries {
+			entryType := constants.BlobObject
+			if entry.Type == objects.TreeType {
+				entryType = constants.TreeObject
+			}
+			result = append(result, link{entry.Hash, entryType})
+		}
+		return result, nil
+
+	case constants.CommitObject:
+		commit, err := objects.ParseCommit(hash, data)
+		if err != nil {
+			return nil, err
+		}
+
+		result := []link{{commit.Tree, constants.TreeObject}}
+		for _, parent := range commit.Parents {
+			result = append(result, link{parent, constants.CommitObject})
+		}
+		return result, nil
+
+	case constants.TagObject:
+		tag, err := objects.ParseTag(hash, data)
+		if err != nil {
+			return nil, err
+		}
+		return []link{{tag.Object, tag.Type}}, nil
+	}
+
+	// Blobs refer to nothing
+	return nil, nil
+}
+
+// Roots returns the objects that keep everything else alive, keyed by hash and mapped to where they are referenced:
+// every ref, a detached HEAD, the index entries, the commit of an interrupted merge and the stash entries.
+func Roots(repoPath string) (map[string]string, error) {
+	roots := make(map[string]string)
+
+	names, err := refs.ListRefs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range append(names, "HEAD") {
+		hash, err := refs.ReadRef(repoPath, name)
+		if errors.Is(err, refs.ErrRefNotFound) {
+			continue // HEAD on a branch without commits
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := roots[hash]; !exists {
+			roots[hash] = name
+		}
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+
+	for path, entry := range idx.Entries {
+		hashes := []string{entry.Hash}
+		if entry.Conflict != nil {
+			hashes = append(hashes, entry.Conflict.Base, entry.Conflict.Ours, entry.Conflict.Theirs)
+		}
+
+		for _, hash := range hashes {
+			if _, exists := roots[hash]; hash != "" && !exists {
+				roots[hash] = "index entry " + path
+			}
+		}
+	}
+
+	mergeHead, _, err := merge.ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists := roots[mergeHead]; mergeHead != "" && !exists {
+		roots[mergeHead] = "MERGE_HEAD"
+	}
+
+	// Only the newest stash entry has a ref, the older ones are kept alive by the stash list
+	stashes, err := stash.List(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, hash := range stashes {
+		if _, exists := roots[hash]; !exists {
+			roots[hash] = fmt.Sprintf("stash@{%d}", n)
+		}
+	}
+
+	return roots, nil
+}
+
+// Check verifies every loose and packed object: its content has to hash to its name and parse,
+// everything it refers to has to exist with the expected type, and so do the objects refs and the index point at.
+func Check(repoPath string) (*Report, error) {
+	report := &Report{}
+
+	hashes, err := storage.ListObjects(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	packed, err := storage.ListPackedObjects(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := storage.ObjectHasher(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	packs, err := storage.Packs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range packs {
+		err = p.Verify(hasher)
+		if err != nil {
+			report.Problems = append(report.Problems, err.Error())
+		}
+	}
+
+	// Objects may be both loose and packed, check each once
+	hashes = append(hashes, packed...)
+	sort.Strings(hashes)
+	hashes = slices.Compact(hashes)
+	types := make(map[string]string, len(hashes))
+	graph := make(map[string][]link, len(hashes))
+	corrupt := make(map[string]bool)
+
+	for _, hash := range hashes {
+		objType, data, err := storage.ReadVerifiedObject(repoPath, hash)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("corrupt object %s: %v", hash, err))
+			corrupt[hash] = true
+			continue
+		}
+		types[hash] = objType
+
+		objectLinks, err := links(hash, objType, data)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("invalid %s %s: %v", objType, hash, err))
+			continue
+		}
+		graph[hash] = objectLinks
+	}
+
+	// Every link has to lead to an intact object of the right type
+	referenced := make(map[string]bool)
+	for _, hash := range hashes {
+		for _, target := range graph[hash] {
+			referenced[target.hash] = true
+
+			targetType, exists := types[target.hash]
+			switch {
+			case corrupt[target.hash]:
+				// Already reported
+			case !exists:
+				report.Problems = append(report.Problems, fmt.Sprintf("broken link from %s %s to %s %s", types[hash], hash, target.objType, target.hash))
+			case targetType != target.objType:
+				report.Problems = append(report.Problems, fmt.Sprintf("%s %s refers to %s as a %s, but it is a %s", types[hash], hash, target.hash, target.objType, targetType))
+			}
+		}
+	}
+
+	roots, err := Roots(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []string
+	for hash, source := range roots {
+		objType, exists := types[hash]
+		switch {
+		case corrupt[hash]:
+			continue
+		case !exists:
+			report.Problems = append(report.Problems, fmt.Sprintf("%s points to missing object %s", source, hash))
+			continue
+		case objType != constants.CommitObject && (source == "HEAD" || source == "MERGE_HEAD" || isBranch(source)):
+			report.Problems = append(report.Problems, fmt.Sprintf("%s points to a %s, not a commit", source, objType))
+		}
+
+		queue = append(queue, hash)
+	}
+
+	// Walk everything the roots lead to
+	reachable := make(map[string]bool)
+	for len(queue) > 0 {
+		hash := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if reachable[hash] {
+			continue
+		}
+		reachable[hash] = true
+
+		for _, target := range graph[hash] {
+			if _, exists := types[target.hash]; exists && !reachable[target.hash] {
+				queue = append(queue, target.hash)
+			}
+		}
+	}
+
+	for _, hash := range hashes {
+		objType, intact := types[hash]
+		if !intact || reachable[hash] {
+			continue
+		}
+
+		report.Unreachable = append(report.Unreachable, objType+" "+hash)
+		if !referenced[hash] {
+			report.Dangling = append(report.Dangling, objType+" "+hash)
+		}
+	}
+
+	sort.Strings(report.Problems)
+	return report, nil
+}
+
+// isBranch reports whether a ref name is a branch, which always has to point at a commit
+func isBranch(name string) bool {
+	return strings.HasPrefix(name, refs.HeadsPrefix)
+}
+
+// Reachable returns the hashes of every object reachable from the roots, reading objects as it goes.
+// A missing or unreadable object is an error, since callers rely on the set being complete.
+func Reachable(repoPath string) (map[string]bool, error) {
+	roots, err := Roots(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []string
+	for hash := range roots {
+		queue = append(queue, hash)
+	}
+
+	reachable := make(map[string]bool)
+	for len(queue) > 0 {
+		hash := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if reachable[hash] {
+			continue
+		}
+
+		objType, data, err := storage.ReadObject(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		reachable[hash] = true
+
+		objectLinks, err := links(hash, objType, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %w", objType, hash, err)
+		}
+
+		for _, target := range objectLinks {
+			if !reachable[target.hash] {
+				queue = append(queue, target.hash)
+			}
+		}
+	}
+
+	return reachable, nil
+}
@@ -0,0 +1,139 @@
+package fsck
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/stash"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// commitFile writes and commits a single file, returning the blob hash of its content
+func commitFile(t *testing.T, repoPath, name, content string) string {
+	t.Helper()
+
+	testutil.Stage(t, repoPath, name, content)
+
+	_, err := objects.CreateCommit(repoPath, "test", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	return idx.Entries[name].Hash
+}
+
+func TestCheck(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	blobHash := commitFile(t, repoPath, "a.txt", "a\n")
+
+	danglingHash, err := storage.CreateObject(repoPath, constants.BlobObject, []byte("nobody refers to me"))
+	if err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	report, err := Check(repoPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	if !report.OK() {
+		t.Errorf("Expected a healthy repository, got problems: %v", report.Problems)
+	}
+
+	if len(report.Dangling) != 1 || report.Dangling[0] != "blob "+danglingHash {
+		t.Errorf("Expected the unreferenced blob to be dangling, got %v", report.Dangling)
+	}
+
+	// Replace the committed blob with content that hashes to something else
+	objectPath := filepath.Join(repoPath, ".quill", "objects", blobHash[:2], blobHash[2:])
+	stored, err := os.ReadFile(filepath.Join(repoPath, ".quill", "objects", danglingHash[:2], danglingHash[2:]))
+	if err != nil {
+		t.Fatalf("Failed to read object: %v", err)
+	}
+
+	err = os.WriteFile(objectPath, stored, 0600)
+	if err != nil {
+		t.Fatalf("Failed to corrupt object: %v", err)
+	}
+
+	report, err = Check(repoPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	if report.OK() || len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "corrupt object "+blobHash) {
+		t.Errorf("Expected the corrupt blob to be the only problem, got %v", report.Problems)
+	}
+
+	// A missing object breaks the link from its tree
+	err = os.Remove(objectPath)
+	if err != nil {
+		t.Fatalf("Failed to remove object: %v", err)
+	}
+
+	report, err = Check(repoPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	var broken bool
+	for _, problem := range report.Problems {
+		broken = broken || strings.HasPrefix(problem, "broken link from tree")
+	}
+	if !broken {
+		t.Errorf("Expected a broken link to be reported, got %v", report.Problems)
+	}
+}
+
+func TestRootsIncludeStash(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "a.txt", "a\n")
+	signature := objects.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+
+	var stashed []string
+	for _, content := range []string{"first\n", "second\n"} {
+		err := os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write a.txt: %v", err)
+		}
+
+		hash, err := stash.Push(repoPath, "", false, signature, signature)
+		if err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+		stashed = append(stashed, hash)
+	}
+
+	roots, err := Roots(repoPath)
+	if err != nil {
+		t.Fatalf("Roots failed: %v", err)
+	}
+
+	// The older entry has no ref of its own
+	if _, exists := roots[stashed[0]]; !exists {
+		t.Errorf("Expected stash@{1} to be a root, got %v", roots)
+	}
+
+	report, err := Check(repoPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	if !report.OK() || len(report.Dangling) != 0 {
+		t.Errorf("Expected stashed objects to be reachable, got problems %v and dangling %v", report.Problems, report.Dangling)
+	}
+}
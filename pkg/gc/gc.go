@@ -0,0 +1,78 @@
+package gc
+
+import (
+	"time"
+
+	"github.com/tejastn10/quill/pkg/fsck"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// PrunedObject describes an unreachable object that was, or would be, removed
+type PrunedObject struct {
+	Hash string
+	Type string // Empty when the object can't be read
+	Size int64  // Bytes used on disk
+}
+
+// Result summarizes a prune
+type Result struct {
+	Pruned []PrunedObject
+
+	// Kept counts unreachable objects spared because they are newer than the expiry time
+	Kept int
+}
+
+// Reclaimed returns the number of bytes freed by the pruned objects
+func (r *Result) Reclaimed() int64 {
+	var total int64
+	for _, object := range r.Pruned {
+		total += object.Size
+	}
+	return total
+}
+
+// Prune removes loose objects that are unreachable from refs, HEAD and the index and were written before expire.
+// The grace period protects objects that a running command has written but not yet referenced.
+// With dryRun nothing is deleted and the result lists what would have been.
+func Prune(repoPath string, expire time.Time, dryRun bool) (*Result, error) {
+	reachable, err := fsck.Reachable(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := storage.ListObjects(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, hash := range hashes {
+		if reachable[hash] {
+			continue
+		}
+
+		info, err := storage.StatObject(repoPath, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.ModTime().Before(expire) {
+			result.Kept++
+			continue
+		}
+
+		// The type is only informational, so corrupt objects are pruned all the same
+		objType, _, _ := storage.ReadObject(repoPath, hash)
+
+		if !dryRun {
+			err = storage.RemoveObject(repoPath, hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result.Pruned = append(result.Pruned, PrunedObject{Hash: hash, Type: objType, Size: info.Size()})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package gc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/pack"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+func TestPrune(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	// The first staged version is replaced before committing and becomes unreachable
+	testutil.Stage(t, repoPath, "a.txt", "draft\n")
+	testutil.Stage(t, repoPath, "a.txt", "final\n")
+
+	_, err := objects.CreateCommit(repoPath, "test", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	hasher, err := storage.ObjectHasher(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to get hasher: %v", err)
+	}
+	draft := storage.HashObject(hasher, constants.BlobObject, []byte("draft\n"))
+
+	// Nothing is old enough yet
+	result, err := Prune(repoPath, time.Now().Add(-time.Hour), false)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	if len(result.Pruned) != 0 || result.Kept != 1 {
+		t.Errorf("Expected the draft to be kept by the grace period, got %+v", result)
+	}
+
+	result, err = Prune(repoPath, time.Now().Add(time.Minute), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+
+	if len(result.Pruned) != 1 || result.Pruned[0].Hash != draft || result.Pruned[0].Type != constants.BlobObject || result.Reclaimed() == 0 {
+		t.Fatalf("Expected the draft to be listed, got %+v", result)
+	}
+
+	if !storage.ObjectExists(repoPath, draft) {
+		t.Errorf("Expected a dry run to keep the draft")
+	}
+
+	_, err = Prune(repoPath, time.Now().Add(time.Minute), false)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	if storage.ObjectExists(repoPath, draft) {
+		t.Errorf("Expected the draft to be removed")
+	}
+
+	// Everything that is still referenced survives
+	hashes, err := storage.ListObjects(repoPath)
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+
+	if len(hashes) != 3 {
+		t.Errorf("Expected the commit, its tree and blob to remain, got %v", hashes)
+	}
+}
+
+func TestRepack(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, "a.txt", "draft\n")
+	testutil.Stage(t, repoPath, "a.txt", "first\n")
+	_, err := objects.CreateCommit(repoPath, "first", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	testutil.Stage(t, repoPath, "a.txt", "second\n")
+	head, err := objects.CreateCommit(repoPath, "second", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	result, err := Repack(repoPath, pack.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+
+	// Two commits, two trees and two blobs; the draft is unreachable
+	if result.Pack == "" || result.Objects != 6 || result.Removed != 6 {
+		t.Errorf("Expected 6 objects to be packed and removed, got %+v", result)
+	}
+
+	loose, err := storage.ListObjects(repoPath)
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+
+	if len(loose) != 1 {
+		t.Errorf("Expected only the unreachable draft to stay loose, got %v", loose)
+	}
+
+	// Packed objects read the same as loose ones
+	commit, err := objects.ReadCommit(repoPath, head)
+	if err != nil {
+		t.Fatalf("Failed to read packed commit: %v", err)
+	}
+
+	if commit.Message != "second" {
+		t.Errorf("Expected the packed commit message to be %q, got %q", "second", commit.Message)
+	}
+
+	// Repacking again without changes keeps the same pack
+	again, err := Repack(repoPath, pack.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Second repack failed: %v", err)
+	}
+
+	if again.Pack != result.Pack || again.Removed != 0 {
+		t.Errorf("Expected an unchanged repack to reuse %s, got %+v", result.Pack, again)
+	}
+
+	packs, err := storage.Packs(repoPath)
+	if err != nil {
+		t.Fatalf("Packs failed: %v", err)
+	}
+
+	if len(packs) != 1 {
+		t.Errorf("Expected a single pack, got %d", len(packs))
+	}
+}
@@ -0,0 +1,185 @@
+package gc
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/fsck"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/pack"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// RepackResult summarizes a repack
+type RepackResult struct {
+	Pack    string // Name of the new pack, empty when there was nothing to pack
+	Objects int
+	Deltas  int
+
+	// Loosened counts unreachable objects moved out of the old packs so prune can expire them
+	Loosened int
+
+	// Removed counts the loose objects deleted because the new pack holds them
+	Removed int
+}
+
+// Repack writes every reachable object into a single new pack with delta compression and then
+// deletes the old packs and the loose copies of packed objects. Unreachable objects stay loose.
+func Repack(repoPath string, options pack.Options) (*RepackResult, error) {
+	reachable, err := fsck.Reachable(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPacks, err := storage.Packs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RepackResult{}
+
+	// Unreachable objects would vanish with their pack, keep them loose until they expire
+	for _, old := range oldPacks {
+		for _, hash := range old.Hashes() {
+			if reachable[hash] {
+				continue
+			}
+
+			objType, data, err := old.Read(hash)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = storage.WriteLooseObject(repoPath, objType, data)
+			if err != nil {
+				return nil, err
+			}
+			result.Loosened++
+		}
+	}
+
+	packObjects := make([]pack.Object, 0, len(reachable))
+	for hash := range reachable {
+		objType, data, err := storage.ReadObject(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+
+		packObjects = append(packObjects, pack.Object{Hash: hash, Type: objType, Data: data})
+	}
+
+	err = nameObjects(packObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packObjects) > 0 {
+		hasher, err := storage.ObjectHasher(repoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Pack, result.Deltas, err = pack.Write(storage.PackDir(repoPath), hasher, packObjects, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write pack: %w", err)
+		}
+		result.Objects = len(packObjects)
+	}
+
+	for _, old := range oldPacks {
+		if old.Name == result.Pack {
+			continue // Nothing changed since the last repack
+		}
+
+		err = storage.RemovePack(repoPath, old.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loose, err := storage.ListObjects(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hash := range loose {
+		if !reachable[hash] {
+			continue
+		}
+
+		err = storage.RemoveObject(repoPath, hash)
+		if err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	return result, nil
+}
+
+// nameObjects sets the name of every blob and tree to a path it has in one of the commits, so revisions
+// of the same file end up next to each other in the pack and are tried as delta bases for one another
+func nameObjects(packObjects []pack.Object) error {
+	byHash := make(map[string]*pack.Object, len(packObjects))
+	for i := range packObjects {
+		byHash[packObjects[i].Hash] = &packObjects[i]
+	}
+
+	named := make(map[string]bool)
+
+	var walk func(treeHash, prefix string) error
+	walk = func(treeHash, prefix string) error {
+		object, exists := byHash[treeHash]
+		if !exists {
+			return fmt.Errorf("tree %s is missing", treeHash)
+		}
+
+		tree, err := objects.ParseTree(object.Data)
+		if err != nil {
+			return fmt.Errorf("invalid tree %s: %w", treeHash, err)
+		}
+
+		for _, entry := range tree.Entries {
+			if named[entry.Hash] {
+				continue
+			}
+			named[entry.Hash] = true
+
+			entryPath := path.Join(prefix, entry.Path)
+			if target, exists := byHash[entry.Hash]; exists {
+				target.Name = entryPath
+			}
+
+			if entry.Type == objects.TreeType {
+				err = walk(entry.Hash, entryPath)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	for _, object := range packObjects {
+		if object.Type != constants.CommitObject {
+			continue
+		}
+
+		commit, err := objects.ParseCommit(object.Hash, object.Data)
+		if err != nil {
+			return fmt.Errorf("invalid commit %s: %w", object.Hash, err)
+		}
+
+		if !named[commit.Tree] {
+			named[commit.Tree] = true
+			err = walk(commit.Tree, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
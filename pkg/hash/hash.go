@@ -3,10 +3,78 @@ package hash
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/blake2b"
 )
 
+const (
+	// SHA256 names the SHA-256 object format
+	SHA256 = "sha256"
+
+	// BLAKE2b names the BLAKE2b-256 object format
+	BLAKE2b = "blake2b"
+
+	// DefaultAlgorithm is the object format of repositories that don't record one
+	DefaultAlgorithm = SHA256
+
+	// HexSize is the length of a hex encoded object hash, which is the same in every object format
+	HexSize = 64
+)
+
+// Hasher computes object hashes for one of the supported object formats
+type Hasher interface {
+	// Name returns the object format name recorded in the repository config
+	Name() string
+
+	// Sum returns the hex encoded hash of data
+	Sum(data []byte) string
+}
+
+// NewHasher returns the hasher for the named object format
+func NewHasher(name string) (Hasher, error) {
+	switch name {
+	case SHA256:
+		return sha256Hasher{}, nil
+	case BLAKE2b:
+		return blake2bHasher{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported object format %q (supported: %s, %s)", name, SHA256, BLAKE2b)
+	}
+}
+
+// IsHash reports whether s is a full, lowercase hex encoded object hash
+func IsHash(s string) bool {
+	if len(s) != HexSize {
+		return false
+	}
+
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Name() string { return SHA256 }
+
+func (sha256Hasher) Sum(data []byte) string { return ComputeSHA256(data) }
+
+type blake2bHasher struct{}
+
+func (blake2bHasher) Name() string { return BLAKE2b }
+
+func (blake2bHasher) Sum(data []byte) string {
+	// ComputeBLAKE2 can only fail for an oversized key and doesn't use one
+	sum, _ := ComputeBLAKE2(data)
+	return sum
+}
+
 // Compute a SHA-256 hash for the given data.
 func ComputeSHA256(data []byte) string {
 	hasher := sha256.New()
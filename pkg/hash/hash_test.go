@@ -19,3 +19,35 @@ func TestHashFunctions(t *testing.T) {
 		t.Errorf("hash functions should produce unique results")
 	}
 }
+
+func TestNewHasher(t *testing.T) {
+	data := []byte("test content")
+
+	sha, err := hash.NewHasher(hash.SHA256)
+	if err != nil {
+		t.Fatalf("NewHasher(%q) failed: %v", hash.SHA256, err)
+	}
+
+	if sha.Name() != hash.SHA256 || sha.Sum(data) != hash.ComputeSHA256(data) {
+		t.Errorf("sha256 hasher does not match ComputeSHA256")
+	}
+
+	blake, err := hash.NewHasher(hash.BLAKE2b)
+	if err != nil {
+		t.Fatalf("NewHasher(%q) failed: %v", hash.BLAKE2b, err)
+	}
+
+	expected, err := hash.ComputeBLAKE2(data)
+	if err != nil {
+		t.Fatalf("error computing BLAKE2 hash: %v", err)
+	}
+
+	if blake.Name() != hash.BLAKE2b || blake.Sum(data) != expected {
+		t.Errorf("blake2b hasher does not match ComputeBLAKE2")
+	}
+
+	_, err = hash.NewHasher("md5")
+	if err == nil {
+		t.Errorf("expected an error for an unsupported object format")
+	}
+}
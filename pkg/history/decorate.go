@@ -0,0 +1,120 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+)
+
+// refTip is a ref together with the commit it leads to
+type refTip struct {
+	name   string
+	commit string
+}
+
+// refTips returns every ref that leads to a commit, following annotated tags, with branches before tags
+func refTips(repoPath string) ([]refTip, error) {
+	names, err := refs.ListRefs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches, others []refTip
+	for _, name := range names {
+		hash, err := refs.ReadRef(repoPath, name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Tags may point at trees or blobs, which have no place in history
+		commit, objType, err := objects.PeelTag(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+
+		if objType != constants.CommitObject {
+			continue
+		}
+
+		if strings.HasPrefix(name, refs.HeadsPrefix) {
+			branches = append(branches, refTip{name: name, commit: commit})
+		} else {
+			others = append(others, refTip{name: name, commit: commit})
+		}
+	}
+
+	return append(branches, others...), nil
+}
+
+// Tips returns the commits HEAD and every ref lead to, for walking all of history
+func Tips(repoPath string) ([]string, error) {
+	tips, err := refTips(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+
+	head, err := refs.ReadRef(repoPath, "HEAD")
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return nil, err
+	}
+
+	// HEAD on a branch without commits has nothing to add
+	if head != "" {
+		hashes = append(hashes, head)
+	}
+
+	for _, tip := range tips {
+		hashes = append(hashes, tip.commit)
+	}
+
+	return hashes, nil
+}
+
+// Decorations returns the names pointing at each commit, such as "HEAD -> main", "feature" and "tag: v1.0".
+// HEAD comes first, then branches and then tags and other refs, each sorted by name.
+func Decorations(repoPath string) (map[string][]string, error) {
+	tips, err := refTips(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := refs.ReadHEAD(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := refs.ReadRef(repoPath, "HEAD")
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return nil, err
+	}
+
+	decorations := make(map[string][]string)
+
+	// A detached HEAD is shown on its own, otherwise with the branch it is on
+	if head != "" && target == "" {
+		decorations[head] = append(decorations[head], "HEAD")
+	}
+
+	for _, tip := range tips {
+		switch {
+		case tip.name == target:
+			// The branch HEAD is on comes before other names at the same commit
+			label := "HEAD -> " + strings.TrimPrefix(tip.name, refs.HeadsPrefix)
+			decorations[tip.commit] = append([]string{label}, decorations[tip.commit]...)
+		case strings.HasPrefix(tip.name, refs.HeadsPrefix):
+			decorations[tip.commit] = append(decorations[tip.commit], strings.TrimPrefix(tip.name, refs.HeadsPrefix))
+		case strings.HasPrefix(tip.name, refs.TagsPrefix):
+			decorations[tip.commit] = append(decorations[tip.commit], "tag: "+strings.TrimPrefix(tip.name, refs.TagsPrefix))
+		default:
+			decorations[tip.commit] = append(decorations[tip.commit], tip.name)
+		}
+	}
+
+	return decorations, nil
+}
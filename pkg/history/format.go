@@ -0,0 +1,92 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/date"
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// Placeholders lists what Format replaces, for help texts
+const Placeholders = "%H hash, %h short hash, %P parent hashes, %p short parent hashes, %an author name, %ae author email, " +
+	"%ad author date, %ar relative author date, %cn committer name, %ce committer email, %cd committer date, " +
+	"%cr relative committer date, %s subject, %b body, %n newline, %% a literal %"
+
+// Format expands the placeholders of a template for a commit, showing dates in the given style.
+// Unknown placeholders are left as they are.
+func Format(template string, commit *objects.Commit, style date.Style, now time.Time) (string, error) {
+	author, err := commit.AuthorSignature()
+	if err != nil {
+		return "", fmt.Errorf("failed to read author of %s: %w", commit.Hash, err)
+	}
+
+	committer, err := commit.CommitterSignature()
+	if err != nil {
+		return "", fmt.Errorf("failed to read committer of %s: %w", commit.Hash, err)
+	}
+
+	subject, body := SplitMessage(commit.Message)
+
+	shortParents := make([]string, len(commit.Parents))
+	for i, parent := range commit.Parents {
+		shortParents[i] = objects.ShortHash(parent)
+	}
+
+	values := map[string]string{
+		"H":  commit.Hash,
+		"h":  objects.ShortHash(commit.Hash),
+		"P":  strings.Join(commit.Parents, " "),
+		"p":  strings.Join(shortParents, " "),
+		"an": author.Name,
+		"ae": author.Email,
+		"ad": date.Format(author.When, style, now),
+		"ar": date.Ago(author.When, now),
+		"cn": committer.Name,
+		"ce": committer.Email,
+		"cd": date.Format(committer.When, style, now),
+		"cr": date.Ago(committer.When, now),
+		"s":  subject,
+		"b":  body,
+		"n":  "\n",
+		"%":  "%",
+	}
+
+	var out strings.Builder
+	for len(template) > 0 {
+		before, after, found := strings.Cut(template, "%")
+		out.WriteString(before)
+		if !found {
+			break
+		}
+
+		// Placeholders are one or two letters, try the longer one first
+		matched := false
+		for _, length := range []int{2, 1} {
+			if len(after) < length {
+				continue
+			}
+
+			if value, known := values[after[:length]]; known {
+				out.WriteString(value)
+				after = after[length:]
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			out.WriteString("%")
+		}
+		template = after
+	}
+
+	return out.String(), nil
+}
+
+// SplitMessage returns the first line of a commit message and the rest, without the blank line between them
+func SplitMessage(message string) (string, string) {
+	subject, body, _ := strings.Cut(message, "\n")
+	return subject, strings.Trim(body, "\n")
+}
@@ -0,0 +1,170 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// Graph draws the lines of history next to a list of commits with one column per line, marking each commit
+// with * and forks, joins and shifts between columns with |, / and \. Commits must be given children first,
+// as Walk returns them in date or topological order.
+type Graph struct {
+	parents map[string][]string // Parents of each shown commit, skipping over commits that are not shown
+	lanes   []string            // Commit each column leads to
+}
+
+// NewGraph prepares a graph of the given commits. Where a parent is not among them, such as when history was
+// filtered, the commit is joined to the nearest ancestors that are, so lines of history stay connected.
+func NewGraph(repoPath string, commits []*objects.Commit) (*Graph, error) {
+	shown := make(map[string]bool)
+	for _, commit := range commits {
+		shown[commit.Hash] = true
+	}
+
+	// Nearest shown ancestors of each hidden commit, a hidden commit can be reached from many shown ones
+	nearest := make(map[string][]string)
+
+	var visible func(hash string) ([]string, error)
+	visible = func(hash string) ([]string, error) {
+		if shown[hash] {
+			return []string{hash}, nil
+		}
+
+		if ancestors, known := nearest[hash]; known {
+			return ancestors, nil
+		}
+
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+
+		ancestors, err := visibleParents(commit, visible)
+		if err != nil {
+			return nil, err
+		}
+
+		nearest[hash] = ancestors
+		return ancestors, nil
+	}
+
+	g := &Graph{parents: make(map[string][]string)}
+	for _, commit := range commits {
+		parents, err := visibleParents(commit, visible)
+		if err != nil {
+			return nil, err
+		}
+		g.parents[commit.Hash] = parents
+	}
+
+	return g, nil
+}
+
+// visibleParents replaces each parent of a commit with its nearest shown ancestors, leaving out repeats
+func visibleParents(commit *objects.Commit, visible func(string) ([]string, error)) ([]string, error) {
+	var parents []string
+	for _, parent := range commit.Parents {
+		ancestors, err := visible(parent)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ancestor := range ancestors {
+			if !slices.Contains(parents, ancestor) {
+				parents = append(parents, ancestor)
+			}
+		}
+	}
+
+	return parents, nil
+}
+
+// edge is a line of history on its way from one column to another
+type edge struct {
+	column int
+	target int
+}
+
+// Next returns the rows to draw for the next commit: first the row marking the commit with *, then any rows
+// where lines of history fork off it, join each other or shift to fill a column that ended
+func (g *Graph) Next(commit *objects.Commit) []string {
+	column := slices.Index(g.lanes, commit.Hash)
+	if column < 0 {
+		// A commit no shown child leads to starts a new column
+		g.lanes = append(g.lanes, commit.Hash)
+		column = len(g.lanes) - 1
+	}
+
+	rows := []string{g.row(column)}
+	before := len(g.lanes)
+
+	// The commit's column continues to each of its parents, every other column to the commit it already leads to.
+	// Columns leading to the same commit join the leftmost one.
+	var lanes []string
+	var edges []edge
+	for i, hash := range g.lanes {
+		targets := []string{hash}
+		if i == column {
+			targets = g.parents[commit.Hash]
+		}
+
+		for _, target := range targets {
+			position := slices.Index(lanes, target)
+			if position < 0 {
+				lanes = append(lanes, target)
+				position = len(lanes) - 1
+			}
+			edges = append(edges, edge{column: i, target: position})
+		}
+	}
+	g.lanes = lanes
+
+	// Lines move one column per row until each reaches its place
+	width := 2*max(before, len(lanes)) - 1
+	for {
+		row := []byte(strings.Repeat(" ", width))
+		moved := false
+
+		for i := range edges {
+			e := &edges[i]
+			switch {
+			case e.target < e.column:
+				row[2*e.column-1] = '/'
+				e.column--
+				moved = true
+			case e.target > e.column:
+				row[2*e.column+1] = '\\'
+				e.column++
+				moved = true
+			default:
+				row[2*e.column] = '|'
+			}
+		}
+
+		if !moved {
+			break
+		}
+		rows = append(rows, strings.TrimRight(string(row), " "))
+	}
+
+	return rows
+}
+
+// Padding returns the columns to draw beside lines that belong to the last commit but are not part of the graph
+func (g *Graph) Padding() string {
+	return strings.TrimRight(strings.Repeat("| ", len(g.lanes)), " ")
+}
+
+// row draws one row of columns with the commit in the given one
+func (g *Graph) row(column int) string {
+	marks := make([]string, len(g.lanes))
+	for i := range marks {
+		marks[i] = "|"
+	}
+	marks[column] = "*"
+
+	return strings.Join(marks, " ")
+}
@@ -0,0 +1,318 @@
+package history
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/date"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// commitFile writes and stages a file, then commits it by the given author at the given time
+func commitFile(t *testing.T, repoPath, name, content, message, author string, when time.Time) string {
+	t.Helper()
+
+	testutil.Stage(t, repoPath, filepath.FromSlash(name), content)
+
+	name, email, err := objects.ParseIdentity(author)
+	if err != nil {
+		t.Fatalf("Invalid author %q: %v", author, err)
+	}
+	signature := objects.Signature{Name: name, Email: email, When: when}
+
+	hash, err := objects.CreateMergeCommit(repoPath, message, signature, signature, nil)
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return hash
+}
+
+// commitAt stores a commit without files on January 1st 2024, the given number of hours in
+func commitAt(t *testing.T, repoPath, message string, hour int, parents ...string) string {
+	t.Helper()
+
+	when := time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)
+	signature := objects.Signature{Name: "Ada", Email: "ada@example.com", When: when}
+
+	hash, err := objects.CommitTree(repoPath, "", parents, message, signature, signature)
+	if err != nil {
+		t.Fatalf("Failed to commit %q: %v", message, err)
+	}
+
+	return hash
+}
+
+// mergedHistory stores a feature branch merged into main and returns the commit after the merge:
+//
+//	first - main b - main a - merge - tip
+//	     \                  /
+//	      feature one - feature two
+func mergedHistory(t *testing.T, repoPath string) string {
+	t.Helper()
+
+	first := commitAt(t, repoPath, "first", 1)
+	mainB := commitAt(t, repoPath, "main b", 2, first)
+	featureOne := commitAt(t, repoPath, "feature one", 3, first)
+	featureTwo := commitAt(t, repoPath, "feature two", 4, featureOne)
+	mainA := commitAt(t, repoPath, "main a", 5, mainB)
+	merge := commitAt(t, repoPath, "merge", 6, mainA, featureTwo)
+
+	return commitAt(t, repoPath, "tip", 7, merge)
+}
+
+// subjects returns the first lines of the commit messages
+func subjects(commits []*objects.Commit) string {
+	var lines []string
+	for _, commit := range commits {
+		subject, _ := SplitMessage(commit.Message)
+		lines = append(lines, subject)
+	}
+	return strings.Join(lines, ", ")
+}
+
+func TestWalk(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 12, 0, 0, 0, time.UTC) }
+	ada, bob := "Ada Lovelace <ada@example.com>", "Bob <bob@example.com>"
+
+	commitFile(t, repoPath, "a.txt", "a\n", "first", ada, day(1))
+	commitFile(t, repoPath, "pkg/index/index.go", "1\n", "add index", bob, day(2))
+	commitFile(t, repoPath, "a.txt", "a\nb\n", "touch a\n\nFixes the index docs.", ada, day(3))
+	head := commitFile(t, repoPath, "pkg/index/index.go", "2\n", "fix index", bob, day(4))
+
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"everything", Options{MaxCount: -1}, "fix index, touch a, add index, first"},
+		{"max count", Options{MaxCount: 2}, "fix index, touch a"},
+		{"skip", Options{MaxCount: 2, Skip: 1}, "touch a, add index"},
+		{"since", Options{MaxCount: -1, Since: day(3)}, "fix index, touch a"},
+		{"until", Options{MaxCount: -1, Until: day(2)}, "add index, first"},
+		{"author", Options{MaxCount: -1, Author: regexp.MustCompile("ada@")}, "touch a, first"},
+		{"grep body", Options{MaxCount: -1, Grep: regexp.MustCompile("index")}, "fix index, touch a, add index"},
+		{"path", Options{MaxCount: -1, Paths: []string{filepath.Join("pkg", "index")}}, "fix index, add index"},
+		{"file", Options{MaxCount: -1, Paths: []string{"a.txt"}}, "touch a, first"},
+		{"prefix is not a directory", Options{MaxCount: -1, Paths: []string{"pkg/ind"}}, ""},
+		{"combined", Options{MaxCount: 1, Author: regexp.MustCompile("Bob"), Paths: []string{"pkg"}, Until: day(3)}, "add index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := Walk(repoPath, []string{head}, tt.options)
+			if err != nil {
+				t.Fatalf("Walk failed: %v", err)
+			}
+
+			if got := subjects(commits); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := commitFile(t, repoPath, "a.txt", "a\n", "first", "Ada <ada@example.com>", when)
+	second := commitFile(t, repoPath, "a.txt", "b\n", "subject line\n\nbody line one\nbody line two\n", "Ada <ada@example.com>", when)
+
+	commit, err := objects.ReadCommit(repoPath, second)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	tests := []struct {
+		template string
+		style    date.Style
+		want     string
+	}{
+		{"%H", date.Default, second},
+		{"%h %s", date.Default, second[:8] + " subject line"},
+		{"%p|%P", date.Default, first[:8] + "|" + first},
+		{"%an <%ae> %cn", date.Default, "Ada <ada@example.com> Ada"},
+		{"%ad", date.ISO, "2024-01-02 03:04:05 +0000"},
+		{"%cd", date.Unix, "1704164645"},
+		{"%ar", date.Default, "2 days ago"},
+		{"%b", date.Default, "body line one\nbody line two"},
+		{"%s%n%%done %z", date.Default, "subject line\n%done %z"},
+	}
+
+	for _, tt := range tests {
+		got, err := Format(tt.template, commit, tt.style, when.Add(48*time.Hour))
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+	tip := mergedHistory(t, repoPath)
+
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"date", Options{MaxCount: -1, Order: DateOrder}, "tip, merge, main a, feature two, feature one, main b, first"},
+		{"topological", Options{MaxCount: -1, Order: TopoOrder}, "tip, merge, feature two, feature one, main a, main b, first"},
+		{"reverse", Options{MaxCount: -1, Order: TopoOrder, Reverse: true}, "first, main b, main a, feature one, feature two, merge, tip"},
+		{"reverse after limiting", Options{MaxCount: 3, Skip: 1, Reverse: true}, "feature two, main a, merge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := Walk(repoPath, []string{tip}, tt.options)
+			if err != nil {
+				t.Fatalf("Walk failed: %v", err)
+			}
+
+			if got := subjects(commits); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGraph(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+	tip := mergedHistory(t, repoPath)
+
+	tests := []struct {
+		name string
+		grep string
+		want []string
+	}{
+		{"everything", ".", []string{
+			"* tip",
+			"* merge",
+			"|\\",
+			"| * feature two",
+			"| * feature one",
+			"* | main a",
+			"* | main b",
+			"|/",
+			"* first",
+		}},
+		{"hidden commits are skipped over", "merge|main a|first", []string{
+			"* merge",
+			"|\\",
+			"* | main a",
+			"|/",
+			"* first",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := Walk(repoPath, []string{tip}, Options{MaxCount: -1, Order: TopoOrder, Grep: regexp.MustCompile(tt.grep)})
+			if err != nil {
+				t.Fatalf("Walk failed: %v", err)
+			}
+
+			graph, err := NewGraph(repoPath, commits)
+			if err != nil {
+				t.Fatalf("NewGraph failed: %v", err)
+			}
+
+			var lines []string
+			for _, commit := range commits {
+				rows := graph.Next(commit)
+				lines = append(lines, rows[0]+" "+commit.Message)
+				lines = append(lines, rows[1:]...)
+			}
+
+			if got, want := strings.Join(lines, "\n"), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("Expected graph\n%s\ngot\n%s", want, got)
+			}
+
+			if padding := graph.Padding(); padding != "" {
+				t.Errorf("Expected no columns left after the root commit, got %q", padding)
+			}
+		})
+	}
+}
+
+func TestDecorations(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+	tip := mergedHistory(t, repoPath)
+
+	commits, err := Walk(repoPath, []string{tip}, Options{MaxCount: -1, Order: TopoOrder})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	hashes := make(map[string]string)
+	for _, commit := range commits {
+		hashes[commit.Message] = commit.Hash
+	}
+
+	for name, hash := range map[string]string{
+		refs.HeadsPrefix + "main":    tip,
+		refs.HeadsPrefix + "feature": hashes["feature two"],
+		refs.HeadsPrefix + "topic":   tip,
+		refs.TagsPrefix + "v1.0":     hashes["first"],
+	} {
+		err := refs.UpdateRef(repoPath, name, hash)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	decorations, err := Decorations(repoPath)
+	if err != nil {
+		t.Fatalf("Decorations failed: %v", err)
+	}
+
+	for message, want := range map[string]string{
+		"tip":         "HEAD -> main, topic",
+		"feature two": "feature",
+		"first":       "tag: v1.0",
+		"merge":       "",
+	} {
+		if got := strings.Join(decorations[hashes[message]], ", "); got != want {
+			t.Errorf("Expected %q at %q, got %q", want, message, got)
+		}
+	}
+
+	tips, err := Tips(repoPath)
+	if err != nil {
+		t.Fatalf("Tips failed: %v", err)
+	}
+
+	if got, want := len(tips), 5; got != want {
+		t.Errorf("Expected %d tips for HEAD and four refs, got %d", want, got)
+	}
+
+	// A detached HEAD is decorated on its own
+	err = refs.DetachHEAD(repoPath, hashes["merge"])
+	if err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+
+	decorations, err = Decorations(repoPath)
+	if err != nil {
+		t.Fatalf("Decorations failed: %v", err)
+	}
+
+	if got := strings.Join(decorations[hashes["merge"]], ", "); got != "HEAD" {
+		t.Errorf("Expected a detached HEAD at the merge, got %q", got)
+	}
+
+	if got := strings.Join(decorations[tip], ", "); got != "main, topic" {
+		t.Errorf("Expected %q at the tip, got %q", "main, topic", got)
+	}
+}
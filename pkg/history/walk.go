@@ -0,0 +1,364 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// Order is the order a walk returns commits in
+type Order int
+
+const (
+	// ChronologicalOrder follows committer dates alone. It reads no more history than it shows.
+	ChronologicalOrder Order = iota
+
+	// DateOrder follows committer dates too, but never shows a commit before all of its children
+	DateOrder
+
+	// TopoOrder never shows a commit before its children, and shows each line of history in one piece
+	// instead of interleaving branches by date
+	TopoOrder
+)
+
+// Options select which commits a walk returns
+type Options struct {
+	MaxCount int // Negative for no limit
+	Skip     int
+
+	Order   Order
+	Reverse bool // Oldest first, applied after MaxCount and Skip
+
+	// Since and Until bound the committer dates, a zero time leaves that side open
+	Since time.Time
+	Until time.Time
+
+	Author *regexp.Regexp // Matched against "Name <email>" of the author
+	Grep   *regexp.Regexp // Matched against the whole message
+
+	// Paths limit the walk to commits that change one of these paths or something below them
+	Paths []string
+}
+
+// walker carries the state of a single walk
+type walker struct {
+	repoPath string
+	options  Options
+	trees    map[string]map[string]objects.TreeEntry // Tree entries by tree hash, since each is compared twice
+}
+
+// Walk returns the commits reachable from the starting commits that match the options, newest first.
+// Merged branches are interleaved with the one they were merged into unless the order is TopoOrder.
+func Walk(repoPath string, starts []string, options Options) ([]*objects.Commit, error) {
+	w := &walker{repoPath: repoPath, options: options, trees: make(map[string]map[string]objects.TreeEntry)}
+
+	order := w.chronological
+	if options.Order != ChronologicalOrder {
+		order = w.sorted
+	}
+
+	next, err := order(starts)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*objects.Commit
+	skip := options.Skip
+
+	for options.MaxCount < 0 || len(commits) < options.MaxCount {
+		commit, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		if commit == nil {
+			break
+		}
+
+		matches, err := w.matches(commit)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matches {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		commits = append(commits, commit)
+	}
+
+	if options.Reverse {
+		slices.Reverse(commits)
+	}
+
+	return commits, nil
+}
+
+// chronological returns a function yielding the commits reachable from the starting commits by committer date,
+// reading parents only as their children are yielded. The function returns nil once every commit was yielded.
+func (w *walker) chronological(starts []string) (func() (*objects.Commit, error), error) {
+	var pending []*objects.Commit
+	seen := make(map[string]bool)
+
+	queue := func(hash string) error {
+		if seen[hash] {
+			return nil
+		}
+		seen[hash] = true
+
+		commit, err := objects.ReadCommit(w.repoPath, hash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+
+		pending = append(pending, commit)
+		return nil
+	}
+
+	for _, start := range starts {
+		err := queue(start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	next := func() (*objects.Commit, error) {
+		if len(pending) == 0 {
+			return nil, nil
+		}
+
+		newest := newestCommit(pending)
+		commit := pending[newest]
+		pending = append(pending[:newest], pending[newest+1:]...)
+
+		for _, parent := range commit.Parents {
+			err := queue(parent)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return commit, nil
+	}
+
+	return next, nil
+}
+
+// sorted reads every commit reachable from the starting commits and returns a function yielding them
+// children first, in date or topological order. The function returns nil once every commit was yielded.
+func (w *walker) sorted(starts []string) (func() (*objects.Commit, error), error) {
+	commits := make(map[string]*objects.Commit)
+	children := make(map[string]int) // Children not yet yielded
+	var tips []*objects.Commit
+
+	// Read the whole history first, counting the children of each commit
+	queue := slices.Clone(starts)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+
+		if _, seen := commits[hash]; seen {
+			continue
+		}
+
+		commit, err := objects.ReadCommit(w.repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+		commits[hash] = commit
+
+		for _, parent := range commit.Parents {
+			children[parent]++
+			queue = append(queue, parent)
+		}
+	}
+
+	for _, start := range starts {
+		if commit := commits[start]; children[start] == 0 && !slices.Contains(tips, commit) {
+			tips = append(tips, commit)
+		}
+	}
+
+	// Commits whose children were all yielded, the newest tip is taken first either way
+	ready := tips
+	if w.options.Order == TopoOrder {
+		slices.Reverse(ready)
+		slices.SortStableFunc(ready, func(a, b *objects.Commit) int {
+			return committerTime(a).Compare(committerTime(b))
+		})
+	}
+
+	next := func() (*objects.Commit, error) {
+		if len(ready) == 0 {
+			return nil, nil
+		}
+
+		// Topological order keeps following the commits it just made ready, so a branch is shown to its fork point
+		// before anything else. The last parent is followed first, so merged branches come before the mainline.
+		position := len(ready) - 1
+		if w.options.Order == DateOrder {
+			position = newestCommit(ready)
+		}
+
+		commit := ready[position]
+		ready = append(ready[:position], ready[position+1:]...)
+
+		for _, parent := range commit.Parents {
+			children[parent]--
+			if children[parent] == 0 {
+				ready = append(ready, commits[parent])
+			}
+		}
+
+		return commit, nil
+	}
+
+	return next, nil
+}
+
+// newestCommit returns the position of the most recently committed commit, preferring the earliest queued on ties
+func newestCommit(commits []*objects.Commit) int {
+	newest := 0
+	for i, commit := range commits {
+		if committerTime(commit).After(committerTime(commits[newest])) {
+			newest = i
+		}
+	}
+
+	return newest
+}
+
+// committerTime returns when a commit was committed, or the zero time when its committer can't be read
+func committerTime(commit *objects.Commit) time.Time {
+	committer, err := commit.CommitterSignature()
+	if err != nil {
+		return time.Time{}
+	}
+
+	return committer.When
+}
+
+// matches reports whether a commit passes every filter of the walk
+func (w *walker) matches(commit *objects.Commit) (bool, error) {
+	if !w.options.Since.IsZero() || !w.options.Until.IsZero() {
+		committer, err := commit.CommitterSignature()
+		if err != nil {
+			return false, fmt.Errorf("failed to read committer of %s: %w", commit.Hash, err)
+		}
+
+		if !w.options.Since.IsZero() && committer.When.Before(w.options.Since) {
+			return false, nil
+		}
+
+		if !w.options.Until.IsZero() && committer.When.After(w.options.Until) {
+			return false, nil
+		}
+	}
+
+	if w.options.Author != nil && !w.options.Author.MatchString(commit.Author) {
+		return false, nil
+	}
+
+	if w.options.Grep != nil && !w.options.Grep.MatchString(commit.Message) {
+		return false, nil
+	}
+
+	if len(w.options.Paths) == 0 {
+		return true, nil
+	}
+
+	return w.changesPaths(commit)
+}
+
+// changesPaths reports whether a commit changes any of the walk's paths. A merge only counts when it differs
+// from every parent there, otherwise the change it brings in is shown with the commits that made it.
+func (w *walker) changesPaths(commit *objects.Commit) (bool, error) {
+	entries, err := w.treeEntries(commit.Tree)
+	if err != nil {
+		return false, err
+	}
+
+	if len(commit.Parents) == 0 {
+		for path := range entries {
+			if w.limited(path) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	for _, parentHash := range commit.Parents {
+		parent, err := objects.ReadCommit(w.repoPath, parentHash)
+		if err != nil {
+			return false, fmt.Errorf("failed to read commit %s: %w", parentHash, err)
+		}
+
+		parentEntries, err := w.treeEntries(parent.Tree)
+		if err != nil {
+			return false, err
+		}
+
+		if !w.differ(entries, parentEntries) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// differ reports whether two trees have different contents at the walk's paths
+func (w *walker) differ(a, b map[string]objects.TreeEntry) bool {
+	for path, entry := range a {
+		if !w.limited(path) {
+			continue
+		}
+
+		other, exists := b[path]
+		if !exists || other.Hash != entry.Hash || other.Mode != entry.Mode {
+			return true
+		}
+	}
+
+	for path := range b {
+		if _, exists := a[path]; !exists && w.limited(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// limited reports whether a path is one of the walk's paths or lies below one
+func (w *walker) limited(path string) bool {
+	for _, spec := range w.options.Paths {
+		if spec == "." || path == spec || strings.HasPrefix(path, spec+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// treeEntries returns the entries of a tree, reading each tree once per walk
+func (w *walker) treeEntries(treeHash string) (map[string]objects.TreeEntry, error) {
+	if entries, cached := w.trees[treeHash]; cached {
+		return entries, nil
+	}
+
+	entries, err := objects.GetTreeEntries(w.repoPath, treeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree %s: %w", treeHash, err)
+	}
+
+	w.trees[treeHash] = entries
+	return entries, nil
+}
@@ -0,0 +1,215 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+// FileName is the name of the per-directory ignore file
+const FileName = ".quillignore"
+
+// pattern is a single parsed line of an ignore file
+type pattern struct {
+	// segments is the slash separated glob, matched against the path relative to base
+	segments []string
+
+	// base is the directory holding the ignore file, relative to the repository root
+	base string
+
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher decides whether working tree paths are ignored, loading ignore files lazily per directory
+type Matcher struct {
+	repoPath string
+	patterns map[string][]pattern
+}
+
+// NewMatcher creates a matcher for the working tree rooted at repoPath
+func NewMatcher(repoPath string) *Matcher {
+	return &Matcher{
+		repoPath: repoPath,
+		patterns: make(map[string][]pattern),
+	}
+}
+
+// parsePatterns parses the content of an ignore file located in the directory base
+func parsePatterns(content, base string) []pattern {
+	var patterns []pattern
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// Trailing spaces are ignored unless escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+
+		// Blank lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pattern{base: base}
+
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		// A slash anywhere but the end anchors the pattern to its directory,
+		// otherwise it matches a name at any depth below it
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		p.segments = strings.Split(line, "/")
+		if !anchored {
+			p.segments = append([]string{"**"}, p.segments...)
+		}
+
+		patterns = append(patterns, p)
+	}
+
+	return patterns
+}
+
+// matches reports whether the pattern matches a slash separated path relative to the repository root
+func (p pattern) matches(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		rest, found := strings.CutPrefix(relPath, p.base+"/")
+		if !found {
+			return false
+		}
+		relPath = rest
+	}
+
+	return matchSegments(p.segments, strings.Split(relPath, "/"))
+}
+
+// matchSegments matches glob segments against path segments, with "**" spanning any number of directories
+func matchSegments(patternParts, pathParts []string) bool {
+	for len(patternParts) > 0 {
+		if patternParts[0] == "**" {
+			rest := patternParts[1:]
+
+			// A trailing "**" matches everything inside, but not the directory itself
+			if len(rest) == 0 {
+				return len(pathParts) > 0
+			}
+
+			for i := 0; i <= len(pathParts); i++ {
+				if matchSegments(rest, pathParts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(pathParts) == 0 {
+			return false
+		}
+
+		matched, err := path.Match(patternParts[0], pathParts[0])
+		if err != nil || !matched {
+			return false
+		}
+
+		patternParts, pathParts = patternParts[1:], pathParts[1:]
+	}
+
+	return len(pathParts) == 0
+}
+
+// loadPatterns returns the patterns of the ignore file in dir, reading it on first use
+func (m *Matcher) loadPatterns(dir string) ([]pattern, error) {
+	if patterns, loaded := m.patterns[dir]; loaded {
+		return patterns, nil
+	}
+
+	ignorePath := filepath.Clean(filepath.Join(m.repoPath, filepath.FromSlash(dir), FileName))
+
+	if !repo.IsPathSafe(ignorePath) {
+		return nil, fmt.Errorf("invalid file path: potential directory traversal attempt")
+	}
+
+	data, err := os.ReadFile(ignorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", ignorePath, err)
+	}
+
+	patterns := parsePatterns(string(data), dir)
+	m.patterns[dir] = patterns
+
+	return patterns, nil
+}
+
+// matchPath applies every ignore file from the root down to the path's directory; the last matching pattern wins
+func (m *Matcher) matchPath(relPath string, isDir bool) (bool, error) {
+	ignored := false
+
+	parts := strings.Split(relPath, "/")
+	for depth := 0; depth < len(parts); depth++ {
+		dir := strings.Join(parts[:depth], "/")
+
+		patterns, err := m.loadPatterns(dir)
+		if err != nil {
+			return false, err
+		}
+
+		for _, p := range patterns {
+			if p.matches(relPath, isDir) {
+				ignored = !p.negate
+			}
+		}
+	}
+
+	return ignored, nil
+}
+
+// IsIgnored reports whether a path relative to the repository root is ignored.
+// A path inside an ignored directory is always ignored, as with git.
+func (m *Matcher) IsIgnored(relPath string, isDir bool) (bool, error) {
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	if relPath == "." {
+		return false, nil
+	}
+
+	parts := strings.Split(relPath, "/")
+
+	// The repository metadata is never part of the working tree
+	if parts[0] == ".quill" {
+		return true, nil
+	}
+
+	for depth := 1; depth < len(parts); depth++ {
+		ignored, err := m.matchPath(strings.Join(parts[:depth], "/"), true)
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+
+	return m.matchPath(relPath, isDir)
+}
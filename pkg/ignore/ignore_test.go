@@ -0,0 +1,136 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPatternMatching(t *testing.T) {
+	content := `
+# Build output
+/build
+*.log
+!keep.log
+node_modules/
+docs/**/*.pdf
+vendor/**
+\#notes
+`
+	patterns := parsePatterns(content, "")
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"build", true, true},
+		{"src/build", true, false}, // Anchored to the root
+		{"debug.log", false, true},
+		{"logs/server/debug.log", false, true},
+		{"keep.log", false, false}, // Negated
+		{"node_modules", true, true},
+		{"node_modules", false, false}, // Directory only
+		{"web/node_modules", true, true},
+		{"docs/guide.pdf", false, true},
+		{"docs/a/b/guide.pdf", false, true},
+		{"other/guide.pdf", false, false},
+		{"vendor/lib/x.go", false, true},
+		{"vendor", true, false}, // A trailing /** only matches the contents
+		{"#notes", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ignored := false
+			for _, p := range patterns {
+				if p.matches(tt.path, tt.isDir) {
+					ignored = !p.negate
+				}
+			}
+
+			if ignored != tt.ignored {
+				t.Errorf("path %q (dir %v): ignored = %v, want %v", tt.path, tt.isDir, ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestMatcherPerDirectoryFiles(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	files := map[string]string{
+		".quill/HEAD":         "ref: refs/heads/main\n",
+		FileName:              "*.tmp\ncache/\n",
+		"src/" + FileName:     "generated.go\n!important.tmp\n",
+		"src/lib/" + FileName: "/local.txt\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+
+		err = os.MkdirAll(filepath.Dir(path), 0750)
+		if err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// Reading ignore files requires running inside the repository
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+
+	defer func() {
+		if err := os.Chdir(originalDir); err != nil {
+			t.Errorf("Failed to restore original directory: %v", err)
+		}
+	}()
+
+	matcher := NewMatcher(tempDir)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{".quill/HEAD", false, true},
+		{"a.tmp", false, true},
+		{"src/important.tmp", false, false},  // Re-included by a deeper ignore file
+		{"other/important.tmp", false, true}, // The deeper file doesn't apply here
+		{"src/generated.go", false, true},
+		{"src/lib/generated.go", false, true},
+		{"generated.go", false, false},
+		{"src/lib/local.txt", false, true},
+		{"src/lib/sub/local.txt", false, false}, // Anchored to src/lib
+		{"cache/data.bin", false, true},         // Inside an ignored directory
+		{"src/main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ignored, err := matcher.IsIgnored(filepath.FromSlash(tt.path), tt.isDir)
+			if err != nil {
+				t.Fatalf("IsIgnored failed: %v", err)
+			}
+
+			if ignored != tt.ignored {
+				t.Errorf("IsIgnored(%q) = %v, want %v", tt.path, ignored, tt.ignored)
+			}
+		})
+	}
+}
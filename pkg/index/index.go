@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/tejastn10/quill/pkg/constants"
-	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/lockfile"
 	"github.com/tejastn10/quill/pkg/repo"
 	"github.com/tejastn10/quill/pkg/storage"
 )
@@ -19,12 +21,82 @@ type IndexEntry struct {
 	Hash   string `json:"hash"`
 	Mode   string `json:"mode"`
 	Staged bool   `json:"staged,omitempty"`
+
+	// Conflict is set while the path has an unresolved merge conflict
+	Conflict *Conflict `json:"conflict,omitempty"`
+}
+
+// Conflict records the blob hashes of the three versions of a conflicted path; a missing version is empty
+type Conflict struct {
+	Base   string `json:"base,omitempty"`
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
 }
 
 // Index represents the staging area.
 type Index struct {
 	Entries        map[string]IndexEntry `json:"entries"`
 	LastCommitTree string                `json:"lastCommitTree,omitempty"`
+
+	// ObjectFormat is the hash algorithm the entry hashes were computed with
+	ObjectFormat string `json:"objectFormat,omitempty"`
+}
+
+// heldLock is an index lock this process holds, with how many callers are nested inside it
+type heldLock struct {
+	lock  *lockfile.Lock
+	depth int
+}
+
+var (
+	heldMutex sync.Mutex
+	held      = make(map[string]*heldLock) // By index path
+)
+
+// Lock takes index.lock for the rest of an operation, so no other process can change the index between
+// loading and saving it. Saves made while the lock is held replace the index through it. Taking the lock
+// again in the same process nests: the returned function releases one level, and the outermost releases the lock.
+func Lock(repoPath string) (func(), error) {
+	indexPath := filepath.Clean(filepath.Join(repoPath, ".quill", "index"))
+
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+
+	current, exists := held[indexPath]
+	if !exists {
+		err := os.MkdirAll(filepath.Dir(indexPath), constants.DirectoryPerms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create directory for index: %w", err)
+		}
+
+		lock, err := lockfile.Acquire(indexPath)
+		if err != nil {
+			return nil, err
+		}
+
+		current = &heldLock{lock: lock}
+		held[indexPath] = current
+	}
+	current.depth++
+
+	released := false
+	unlock := func() {
+		heldMutex.Lock()
+		defer heldMutex.Unlock()
+
+		if released {
+			return
+		}
+		released = true
+
+		current.depth--
+		if current.depth == 0 {
+			current.lock.Rollback()
+			delete(held, indexPath)
+		}
+	}
+
+	return unlock, nil
 }
 
 // LoadIndex loads the index from the .quill/index file.
@@ -52,6 +124,19 @@ func LoadIndex(repoPath string) (*Index, error) {
 	if err := decoder.Decode(&idx); err != nil {
 		return nil, fmt.Errorf("failed to decode index: %w", err)
 	}
+
+	// Hashes from another object format would never match the object store
+	if idx.ObjectFormat != "" {
+		format, err := repo.ReadObjectFormat(repoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if idx.ObjectFormat != format {
+			return nil, fmt.Errorf("index uses the %s object format but the repository uses %s", idx.ObjectFormat, format)
+		}
+	}
+
 	return &idx, nil
 }
 
@@ -71,18 +156,29 @@ func (idx *Index) SaveIndex(repoPath string) error {
 		return fmt.Errorf("index path %q is outside the repository", indexPath)
 	}
 
-	// Write the index to the file safely
-	file, err := os.OpenFile(indexPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.ConfigFilePerms)
+	idx.ObjectFormat, err = repo.ReadObjectFormat(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to create index file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // Pretty print the JSON for debugging.
-	if err := encoder.Encode(idx); err != nil {
+	data, err := json.MarshalIndent(idx, "", "  ") // Pretty print the JSON for debugging.
+	if err != nil {
 		return fmt.Errorf("failed to encode index: %w", err)
 	}
+
+	// Write the index through index.lock so readers never see a partial index
+	heldMutex.Lock()
+	current, locked := held[indexPath]
+	heldMutex.Unlock()
+
+	if locked {
+		err = current.lock.Replace(append(data, '\n'))
+	} else {
+		err = lockfile.WriteFile(indexPath, append(data, '\n'))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
 	return nil
 }
 
@@ -111,8 +207,12 @@ func (idx *Index) AddFile(repoPath, filePath string) error {
 		return fmt.Errorf("failed to read file %q: %w", filePath, err)
 	}
 
-	// Compute hash
-	fileHash := hash.ComputeSHA256(data)
+	// Compute hash with the repository's object format
+	hasher, err := storage.ObjectHasher(repoPath)
+	if err != nil {
+		return err
+	}
+	fileHash := storage.HashObject(hasher, constants.BlobObject, data)
 
 	// Get relative path for storage
 	relPath, err := filepath.Rel(repoPath, filePath)
@@ -122,14 +222,14 @@ func (idx *Index) AddFile(repoPath, filePath string) error {
 
 	// Check if file has changed since last commit
 	currentEntry, exists := idx.Entries[relPath]
-	if exists && currentEntry.Hash == fileHash && !currentEntry.Staged {
+	if exists && currentEntry.Hash == fileHash && !currentEntry.Staged && currentEntry.Conflict == nil {
 		// File hasn't changed, no need to add it again
 		fmt.Printf("File %q unchanged, not adding to staging area\n", relPath)
 		return nil
 	}
 
 	// Store the object
-	err = storage.CreateObject(repoPath, fileHash, data)
+	_, err = storage.CreateObject(repoPath, constants.BlobObject, data)
 	if err != nil {
 		return fmt.Errorf("failed to store object for %q: %w", filePath, err)
 	}
@@ -149,6 +249,12 @@ func (idx *Index) AddFile(repoPath, filePath string) error {
 
 // CreateCleanIndex creates a clean index after commit, marking all files as committed
 func CreateCleanIndex(repoPath, treeHash string) error {
+	unlock, err := Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Load the current index
 	idx, err := LoadIndex(repoPath)
 	if err != nil {
@@ -162,9 +268,75 @@ func CreateCleanIndex(repoPath, treeHash string) error {
 	// Keep entries but mark them as committed (useful for status command)
 	for path, entry := range idx.Entries {
 		entry.Staged = false // Add this field to your IndexEntry struct
+		entry.Conflict = nil
 		idx.Entries[path] = entry
 	}
 
 	// Save the updated index
 	return idx.SaveIndex(repoPath)
 }
+
+// Conflicts returns the sorted paths that still have unresolved merge conflicts
+func (idx *Index) Conflicts() []string {
+	var paths []string
+	for path, entry := range idx.Entries {
+		if entry.Conflict != nil {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// PathsUnder returns the sorted entry paths that are relPath itself or lie below it
+func (idx *Index) PathsUnder(relPath string) []string {
+	relPath = filepath.Clean(relPath)
+
+	var paths []string
+	for path := range idx.Entries {
+		if relPath == "." || path == relPath || strings.HasPrefix(path, relPath+string(os.PathSeparator)) {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// RemoveMissing drops the entries at or below relPath whose files no longer exist in the working tree,
+// staging their removal. It returns the removed paths.
+func (idx *Index) RemoveMissing(repoPath, relPath string) []string {
+	var removed []string
+	for _, path := range idx.PathsUnder(relPath) {
+		_, err := os.Lstat(filepath.Join(repoPath, path))
+		if os.IsNotExist(err) {
+			delete(idx.Entries, path)
+			removed = append(removed, path)
+		}
+	}
+
+	return removed
+}
+
+// MovePath moves the entry of src, or every entry below it, to dst and stages the moved entries.
+// It returns the new paths.
+func (idx *Index) MovePath(src, dst string) []string {
+	src = filepath.Clean(src)
+	dst = filepath.Clean(dst)
+
+	var moved []string
+	for _, path := range idx.PathsUnder(src) {
+		entry := idx.Entries[path]
+		delete(idx.Entries, path)
+
+		newPath := dst + strings.TrimPrefix(path, src)
+		entry.Path = newPath
+		entry.Staged = true
+		idx.Entries[newPath] = entry
+
+		moved = append(moved, newPath)
+	}
+
+	return moved
+}
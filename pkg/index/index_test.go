@@ -1,9 +1,14 @@
 package index
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/repo"
 )
 
 // TestLoadIndex verifies loading the index from a file.
@@ -65,3 +70,125 @@ func TestSaveIndex(t *testing.T) {
 		t.Errorf("Expected hash to be 'hash123', got %s", loadedIdx.Entries["test.txt"].Hash)
 	}
 }
+
+// TestLock verifies the index lock nests within a process, keeps other writers out and lets saves through.
+func TestLock(t *testing.T) {
+	tempDir := t.TempDir()
+	indexPath := filepath.Join(tempDir, ".quill", "index")
+
+	unlock, err := Lock(tempDir)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	unlockNested, err := Lock(tempDir)
+	if err != nil {
+		t.Fatalf("Nested Lock failed: %v", err)
+	}
+
+	// Saves while the lock is held go through it, more than once
+	idx := &Index{Entries: map[string]IndexEntry{}}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		idx.Entries[name] = IndexEntry{Path: name, Hash: "hash123", Mode: "100644"}
+
+		err = idx.SaveIndex(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to save index under the lock: %v", err)
+		}
+	}
+
+	// Another writer is kept out until the outermost lock is released
+	err = lockfile.WriteFile(indexPath, []byte("{}\n"))
+	if !errors.Is(err, lockfile.ErrLocked) {
+		t.Errorf("Expected ErrLocked while the index is locked, got %v", err)
+	}
+
+	unlockNested()
+	unlockNested() // Releasing twice is harmless
+
+	err = lockfile.WriteFile(indexPath, []byte("{}\n"))
+	if !errors.Is(err, lockfile.ErrLocked) {
+		t.Errorf("Expected the index to stay locked after a nested release, got %v", err)
+	}
+
+	loaded, err := LoadIndex(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if len(loaded.Entries) != 2 {
+		t.Errorf("Expected both saved entries, got %v", loaded.Entries)
+	}
+
+	unlock()
+
+	if _, err := os.Stat(indexPath + lockfile.Suffix); !os.IsNotExist(err) {
+		t.Errorf("Expected index.lock to be gone after the outermost release")
+	}
+}
+
+// TestLoadIndexObjectFormatMismatch verifies an index from another object format is refused.
+func TestLoadIndexObjectFormatMismatch(t *testing.T) {
+	tempDir := t.TempDir()
+
+	idx := &Index{
+		Entries: map[string]IndexEntry{
+			"test.txt": {Path: "test.txt", Hash: "hash123", Mode: "100644"},
+		},
+	}
+
+	err := idx.SaveIndex(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	if idx.ObjectFormat != hash.SHA256 {
+		t.Errorf("Expected the index to record %q, got %q", hash.SHA256, idx.ObjectFormat)
+	}
+
+	// Switching the repository format leaves the index hashes unusable
+	err = repo.CreateCoreConfig(tempDir, hash.BLAKE2b)
+	if err != nil {
+		t.Fatalf("Failed to write core config: %v", err)
+	}
+
+	_, err = LoadIndex(tempDir)
+	if err == nil {
+		t.Errorf("Expected LoadIndex to refuse an index written with another object format")
+	}
+}
+
+// TestMovePath verifies moving single entries and whole directories.
+func TestMovePath(t *testing.T) {
+	idx := &Index{
+		Entries: map[string]IndexEntry{
+			"a.txt":                              {Path: "a.txt", Hash: "a"},
+			filepath.Join("dir", "b.txt"):        {Path: filepath.Join("dir", "b.txt"), Hash: "b"},
+			filepath.Join("dir", "sub", "c.txt"): {Path: filepath.Join("dir", "sub", "c.txt"), Hash: "c"},
+			"dirty.txt":                          {Path: "dirty.txt", Hash: "d"},
+		},
+	}
+
+	if got := idx.PathsUnder("dir"); len(got) != 2 {
+		t.Errorf("Expected two paths under dir, got %v", got)
+	}
+
+	moved := idx.MovePath("dir", "other")
+	if len(moved) != 2 {
+		t.Fatalf("Expected two moved entries, got %v", moved)
+	}
+
+	entry, exists := idx.Entries[filepath.Join("other", "sub", "c.txt")]
+	if !exists || entry.Hash != "c" || !entry.Staged || entry.Path != filepath.Join("other", "sub", "c.txt") {
+		t.Errorf("Moved entry is wrong: %+v", entry)
+	}
+
+	// A sibling sharing the prefix is not part of the directory
+	if _, exists := idx.Entries["dirty.txt"]; !exists {
+		t.Errorf("Expected dirty.txt to be left alone")
+	}
+
+	idx.MovePath("a.txt", "renamed.txt")
+	if _, exists := idx.Entries["a.txt"]; exists || len(idx.Entries) != 4 {
+		t.Errorf("Unexpected entries after rename: %v", idx.Entries)
+	}
+}
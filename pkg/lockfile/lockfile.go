@@ -0,0 +1,280 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/tejastn10/quill/pkg/constants"
+)
+
+// Suffix is appended to a file's path to name its lock file
+const Suffix = ".lock"
+
+// ErrLocked is returned when another process holds the lock on a file
+var ErrLocked = errors.New("another quill process is running")
+
+// Lock is an exclusive lock on a file. New content is written to the lock file
+// and only replaces the file when the lock is committed, so readers never see a partial write.
+type Lock struct {
+	path     string
+	lockPath string
+	file     *os.File
+}
+
+// Acquire takes the lock on path by creating path.lock. A lock is only taken over when its owner line names
+// a process on this machine that has exited; a lock of unknown age or owner may belong to a process that is
+// still running, such as one waiting for an editor, so it is left for the user to remove.
+func Acquire(path string) (*Lock, error) {
+	lockPath := path + Suffix
+
+	file, err := create(lockPath)
+	if errors.Is(err, os.ErrExist) && removeStale(lockPath) {
+		file, err = create(lockPath)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: unable to create %s, remove it if no other quill process is running", ErrLocked, lockPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock %s: %w", lockPath, err)
+	}
+
+	return &Lock{path: path, lockPath: lockPath, file: file}, nil
+}
+
+// create makes the lock file exclusively and records the owning process in it
+func create(lockPath string) (*os.File, error) {
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, constants.ConfigFilePerms)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	// The owner line is replaced by the new content on the first write
+	_, err = fmt.Fprintf(file, "%d %s\n", os.Getpid(), hostname)
+	if err != nil {
+		file.Close()
+		os.Remove(lockPath)
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// removeStale removes a lock file left behind by a process that no longer runs and reports whether it did.
+// A live owner may release its lock and another process take it between reading the owner and removing the
+// file, so the owner is read again after it was found gone: only the dead owner could have removed the file
+// since. Takeovers go through a second lock so two of them can't remove each other's new locks.
+func removeStale(lockPath string) bool {
+	// Named like a lock itself so listings that skip lock files skip it too
+	guardPath := lockPath + Suffix
+
+	guard, err := os.OpenFile(guardPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, constants.ConfigFilePerms)
+	if err != nil {
+		return false
+	}
+	guard.Close()
+	defer os.Remove(guardPath)
+
+	owner, err := os.ReadFile(lockPath)
+	if err != nil || !ownerGone(owner) {
+		return false
+	}
+
+	again, err := os.ReadFile(lockPath)
+	if err != nil || string(again) != string(owner) {
+		return false
+	}
+
+	err = os.Remove(lockPath)
+	return err == nil || os.IsNotExist(err)
+}
+
+// ownerGone reports whether the owner line of a lock file names a process on this machine that no longer runs
+func ownerGone(data []byte) bool {
+	// Only the owner line of a lock on this machine says anything about a live process
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return false
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false
+	}
+
+	hostname, _ := os.Hostname()
+	if fields[1] != hostname {
+		return false
+	}
+
+	return !processAlive(pid)
+}
+
+// processAlive reports whether a process with the given id exists
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Signals can't probe processes on Windows, FindProcess already failed for missing ones
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// Write replaces the pending content of the locked file
+func (l *Lock) Write(data []byte) error {
+	if l.file == nil {
+		return fmt.Errorf("lock %s is no longer held", l.lockPath)
+	}
+
+	err := l.file.Truncate(0)
+	if err == nil {
+		_, err = l.file.WriteAt(data, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.lockPath, err)
+	}
+
+	return nil
+}
+
+// Replace atomically replaces the locked file with data straight away and keeps holding the lock,
+// for holders that save the file more than once before they are done with it. The owner line stays in the lock file.
+func (l *Lock) Replace(data []byte) error {
+	if l.file == nil {
+		return fmt.Errorf("lock %s is no longer held", l.lockPath)
+	}
+
+	return WriteAtomic(l.path, data, constants.ConfigFilePerms)
+}
+
+// Commit flushes the pending content to disk and renames it over the locked file, releasing the lock
+func (l *Lock) Commit() error {
+	if l.file == nil {
+		return fmt.Errorf("lock %s is no longer held", l.lockPath)
+	}
+
+	err := l.file.Sync()
+	closeErr := l.file.Close()
+	l.file = nil
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(l.lockPath)
+		return fmt.Errorf("failed to flush %s: %w", l.lockPath, err)
+	}
+
+	err = os.Rename(l.lockPath, l.path)
+	if err != nil {
+		os.Remove(l.lockPath)
+		return fmt.Errorf("failed to replace %s: %w", l.path, err)
+	}
+
+	syncDir(filepath.Dir(l.path))
+	return nil
+}
+
+// Rollback releases the lock without touching the locked file. It does nothing after Commit.
+func (l *Lock) Rollback() error {
+	if l.file == nil {
+		return nil
+	}
+
+	l.file.Close()
+	l.file = nil
+
+	err := os.Remove(l.lockPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", l.lockPath, err)
+	}
+
+	return nil
+}
+
+// WriteFile atomically replaces the content of path while holding its lock
+func WriteFile(path string, data []byte) error {
+	lock, err := Acquire(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	err = lock.Write(data)
+	if err != nil {
+		return err
+	}
+
+	return lock.Commit()
+}
+
+// Remove deletes path while holding its lock, so it can't race with a writer
+func Remove(path string) error {
+	lock, err := Acquire(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	return os.Remove(path)
+}
+
+// WriteAtomic writes data to a temporary file next to path and renames it into place.
+// It takes no lock, so it suits files whose content is determined by their name, like objects.
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	file, err := os.CreateTemp(dir, "tmp_"+filepath.Base(path)+"_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tempPath := file.Name()
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes a rename inside dir durable. Not every platform can sync directories, so failures are ignored.
+func syncDir(dir string) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	file.Sync()
+}
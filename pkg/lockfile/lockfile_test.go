@@ -0,0 +1,178 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+
+	err := os.WriteFile(path, []byte("old\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err = WriteFile(path, []byte("new\n"))
+	if err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new\n" {
+		t.Errorf("Expected the new content, got %q (%v)", data, err)
+	}
+
+	if _, err := os.Stat(path + Suffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be gone after committing")
+	}
+}
+
+func TestAcquireHeldLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "HEAD")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	err = lock.Write([]byte("pending\n"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// A second writer is refused while the lock is held
+	err = WriteFile(path, []byte("other\n"))
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+
+	// Rolling back leaves the file untouched and frees the lock
+	err = lock.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created by a rolled back lock", path)
+	}
+
+	err = WriteFile(path, []byte("other\n"))
+	if err != nil {
+		t.Errorf("Expected the lock to be free after rollback, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer lock.Rollback()
+
+	for _, content := range []string{"first\n", "second\n"} {
+		err = lock.Replace([]byte(content))
+		if err != nil {
+			t.Fatalf("Replace failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("Expected %q after Replace, got %q (%v)", content, data, err)
+		}
+	}
+
+	// The lock is still held between and after replacements
+	err = WriteFile(path, []byte("other\n"))
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while the lock is held, got %v", err)
+	}
+
+	err = lock.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second\n" {
+		t.Errorf("Expected a rollback to keep replaced content, got %q (%v)", data, err)
+	}
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	// A process that has exited leaves its id behind
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	err := exited.Run()
+	if err != nil {
+		t.Fatalf("Failed to run a short-lived process: %v", err)
+	}
+	deadPid := exited.Process.Pid
+
+	tests := []struct {
+		name    string
+		content string
+		age     time.Duration
+		stale   bool
+	}{
+		{"live process", fmt.Sprintf("%d %s\n", os.Getpid(), hostname), 0, false},
+		{"old lock of a live process", fmt.Sprintf("%d %s\n", os.Getpid(), hostname), 24 * time.Hour, false},
+		{"exited process", fmt.Sprintf("%d %s\n", deadPid, hostname), 0, true},
+		{"other machine", fmt.Sprintf("%d %s\n", deadPid, hostname+"-elsewhere"), 0, false},
+		{"old lock of an unknown owner", "partial content", 24 * time.Hour, false},
+		{"unknown owner", "partial content", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+
+			err := os.WriteFile(path+Suffix, []byte(tt.content), 0600)
+			if err != nil {
+				t.Fatalf("Failed to write lock file: %v", err)
+			}
+
+			modTime := time.Now().Add(-tt.age)
+			err = os.Chtimes(path+Suffix, modTime, modTime)
+			if err != nil {
+				t.Fatalf("Failed to age lock file: %v", err)
+			}
+
+			err = WriteFile(path, []byte("data\n"))
+			if tt.stale && err != nil {
+				t.Errorf("Expected the stale lock to be taken over, got %v", err)
+			}
+			if !tt.stale && !errors.Is(err, ErrLocked) {
+				t.Errorf("Expected ErrLocked, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "object")
+
+	err := WriteAtomic(path, []byte("content"), 0600)
+	if err != nil {
+		t.Fatalf("WriteAtomic failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "object" {
+		t.Errorf("Expected only the object to remain, got %v", entries)
+	}
+}
@@ -0,0 +1,105 @@
+package merge
+
+import (
+	"fmt"
+
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// Ancestors returns every commit reachable from start, including start itself
+func Ancestors(repoPath, start string) (map[string]bool, error) {
+	reachable := map[string]bool{start: true}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+
+		for _, parent := range commit.Parents {
+			if !reachable[parent] {
+				reachable[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	return reachable, nil
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant; a commit is its own ancestor
+func IsAncestor(repoPath, ancestor, descendant string) (bool, error) {
+	reachable, err := Ancestors(repoPath, descendant)
+	if err != nil {
+		return false, err
+	}
+
+	return reachable[ancestor], nil
+}
+
+// MergeBase returns the best common ancestor of two commits, or an empty string when their histories are unrelated.
+// When several best common ancestors exist the one closest to b is returned.
+func MergeBase(repoPath, a, b string) (string, error) {
+	reachableFromA, err := Ancestors(repoPath, a)
+	if err != nil {
+		return "", err
+	}
+
+	// Walk back from b, stopping at the first common commit on each path
+	var candidates []string
+	seen := map[string]bool{b: true}
+	queue := []string{b}
+
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+
+		if reachableFromA[hash] {
+			candidates = append(candidates, hash)
+			continue
+		}
+
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+
+		for _, parent := range commit.Parents {
+			if !seen[parent] {
+				seen[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	// A candidate that is an ancestor of another candidate is never the best one
+	for _, candidate := range candidates {
+		redundant := false
+
+		for _, other := range candidates {
+			if other == candidate {
+				continue
+			}
+
+			isAncestor, err := IsAncestor(repoPath, candidate, other)
+			if err != nil {
+				return "", err
+			}
+
+			if isAncestor {
+				redundant = true
+				break
+			}
+		}
+
+		if !redundant {
+			return candidate, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,179 @@
+package merge
+
+import (
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/diff"
+)
+
+// Labels names the two sides of a merge in conflict markers
+type Labels struct {
+	Ours   string
+	Theirs string
+}
+
+// change is a run of lines in one side's version replacing the base lines [start, end)
+type change struct {
+	start int
+	end   int
+	lines []string
+}
+
+// changes lists the regions of base that side replaced, in order
+func changes(base, side []string) []change {
+	var result []change
+	var current *change
+
+	baseIndex := 0
+	for _, edit := range diff.Diff(base, side) {
+		if edit.Op == diff.Equal {
+			if current != nil {
+				result = append(result, *current)
+				current = nil
+			}
+			baseIndex++
+			continue
+		}
+
+		if current == nil {
+			current = &change{start: baseIndex, end: baseIndex}
+		}
+
+		if edit.Op == diff.Delete {
+			current.end++
+			baseIndex++
+		} else {
+			current.lines = append(current.lines, edit.Line)
+		}
+	}
+
+	if current != nil {
+		result = append(result, *current)
+	}
+
+	return result
+}
+
+// apply returns the base lines [start, end) with the given changes applied
+func apply(base []string, start, end int, edits []change) []string {
+	var lines []string
+
+	pos := start
+	for _, c := range edits {
+		lines = append(lines, base[pos:c.start]...)
+		lines = append(lines, c.lines...)
+		pos = c.end
+	}
+
+	return append(lines, base[pos:end]...)
+}
+
+// equalLines reports whether two line slices are identical
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeLines appends lines to the output, terminating the last one so a marker can follow
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
+
+// MergeLines performs a line-level three-way merge of text content.
+// Changes from both sides are combined when they don't overlap or are identical; otherwise
+// the region is written with conflict markers and the second return value is false.
+func MergeLines(base, ours, theirs []byte, labels Labels) ([]byte, bool) {
+	baseLines := diff.SplitLines(base)
+	oursChanges := changes(baseLines, diff.SplitLines(ours))
+	theirsChanges := changes(baseLines, diff.SplitLines(theirs))
+
+	var out strings.Builder
+	clean := true
+
+	pos := 0
+	i, j := 0, 0
+	for i < len(oursChanges) || j < len(theirsChanges) {
+		// Start a region at the earliest pending change
+		var start int
+		if j >= len(theirsChanges) || (i < len(oursChanges) && oursChanges[i].start <= theirsChanges[j].start) {
+			start = oursChanges[i].start
+		} else {
+			start = theirsChanges[j].start
+		}
+
+		// Grow the region while changes from either side overlap or touch it
+		end := start
+		nextOurs, nextTheirs := i, j
+		for {
+			extended := false
+
+			if nextOurs < len(oursChanges) && oursChanges[nextOurs].start <= end {
+				end = max(end, oursChanges[nextOurs].end)
+				nextOurs++
+				extended = true
+			}
+
+			if nextTheirs < len(theirsChanges) && theirsChanges[nextTheirs].start <= end {
+				end = max(end, theirsChanges[nextTheirs].end)
+				nextTheirs++
+				extended = true
+			}
+
+			if !extended {
+				break
+			}
+		}
+
+		// Unchanged lines before the region
+		for _, line := range baseLines[pos:start] {
+			out.WriteString(line)
+		}
+
+		oursRegion := apply(baseLines, start, end, oursChanges[i:nextOurs])
+		theirsRegion := apply(baseLines, start, end, theirsChanges[j:nextTheirs])
+
+		switch {
+		case nextTheirs == j, equalLines(oursRegion, theirsRegion):
+			// Only ours changed the region, or both made the same change
+			for _, line := range oursRegion {
+				out.WriteString(line)
+			}
+		case nextOurs == i:
+			for _, line := range theirsRegion {
+				out.WriteString(line)
+			}
+		default:
+			clean = false
+
+			out.WriteString("<<<<<<< " + labels.Ours + "\n")
+			writeLines(&out, oursRegion)
+			out.WriteString("=======\n")
+			writeLines(&out, theirsRegion)
+			out.WriteString(">>>>>>> " + labels.Theirs + "\n")
+		}
+
+		pos = end
+		i, j = nextOurs, nextTheirs
+	}
+
+	for _, line := range baseLines[pos:] {
+		out.WriteString(line)
+	}
+
+	return []byte(out.String()), clean
+}
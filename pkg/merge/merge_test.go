@@ -0,0 +1,253 @@
+package merge
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// storeCommit writes a commit object with the given parents and files, returning its hash
+func storeCommit(t *testing.T, repoPath string, files map[string]string, parents ...string) string {
+	t.Helper()
+
+	tree := &objects.Tree{}
+	for name, content := range files {
+		blobHash, err := storage.CreateObject(repoPath, constants.BlobObject, []byte(content))
+		if err != nil {
+			t.Fatalf("Failed to store blob: %v", err)
+		}
+		tree.Entries = append(tree.Entries, objects.TreeEntry{Mode: "644", Type: objects.BlobType, Hash: blobHash, Path: name})
+	}
+
+	treeHash, err := objects.WriteTree(repoPath, tree)
+	if err != nil {
+		t.Fatalf("Failed to write tree: %v", err)
+	}
+
+	data, err := json.Marshal(objects.Commit{Parents: parents, Tree: treeHash, Message: "test"})
+	if err != nil {
+		t.Fatalf("Failed to marshal commit: %v", err)
+	}
+
+	commitHash, err := storage.CreateObject(repoPath, constants.CommitObject, data)
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	return commitHash
+}
+
+func TestMergeLines(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	labels := Labels{Ours: "HEAD", Theirs: "feature"}
+
+	tests := []struct {
+		name   string
+		ours   string
+		theirs string
+		want   string
+		clean  bool
+	}{
+		{"separate changes", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", true},
+		{"one side only", "a\nb\nc\nd\ne\n", "a\nb\nX\nd\ne\n", "a\nb\nX\nd\ne\n", true},
+		{"identical changes", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", true},
+		{"insert and delete", "a\nb\nnew\nc\nd\ne\n", "a\nb\nc\nd\n", "a\nb\nnew\nc\nd\n", true},
+		{
+			"conflict",
+			"a\nb\nours\nd\ne\n",
+			"a\nb\ntheirs\nd\ne\n",
+			"a\nb\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\nd\ne\n",
+			false,
+		},
+		{
+			"conflict without trailing newline",
+			"a\nb\nc\nd\nours",
+			"a\nb\nc\nd\ntheirs",
+			"a\nb\nc\nd\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\n",
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, clean := MergeLines([]byte(base), []byte(tt.ours), []byte(tt.theirs), labels)
+
+			if string(merged) != tt.want {
+				t.Errorf("MergeLines merged content:\n%q\nwant:\n%q", merged, tt.want)
+			}
+
+			if clean != tt.clean {
+				t.Errorf("MergeLines clean = %v, want %v", clean, tt.clean)
+			}
+		})
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	// root - a1 - a2 (main)
+	//     \          \
+	//      b1 ------ merge - b2 (feature)
+	root := storeCommit(t, repoPath, map[string]string{"f": "root"})
+	a1 := storeCommit(t, repoPath, map[string]string{"f": "a1"}, root)
+	a2 := storeCommit(t, repoPath, map[string]string{"f": "a2"}, a1)
+	b1 := storeCommit(t, repoPath, map[string]string{"f": "b1"}, root)
+	merged := storeCommit(t, repoPath, map[string]string{"f": "merge"}, b1, a2)
+	b2 := storeCommit(t, repoPath, map[string]string{"f": "b2"}, merged)
+	unrelated := storeCommit(t, repoPath, map[string]string{"g": "other"})
+
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"diverged", a1, b1, root},
+		{"after merge", a2, b2, a2},
+		{"ancestor", root, a2, root},
+		{"same commit", a1, a1, a1},
+		{"unrelated", a2, unrelated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeBase(repoPath, tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("MergeBase failed: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("MergeBase = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	isAncestor, err := IsAncestor(repoPath, a1, b2)
+	if err != nil {
+		t.Fatalf("IsAncestor failed: %v", err)
+	}
+	if !isAncestor {
+		t.Errorf("Expected %s to be an ancestor of %s through the merge", a1, b2)
+	}
+}
+
+func TestMergeTreesAndApply(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	base := storeCommit(t, repoPath, map[string]string{
+		"same.txt":     "same\n",
+		"ours.txt":     "base\n",
+		"theirs.txt":   "base\n",
+		"conflict.txt": "base\n",
+		"deleted.txt":  "base\n",
+	})
+	ours := storeCommit(t, repoPath, map[string]string{
+		"same.txt":     "same\n",
+		"ours.txt":     "changed by us\n",
+		"theirs.txt":   "base\n",
+		"conflict.txt": "ours\n",
+	}, base)
+	theirs := storeCommit(t, repoPath, map[string]string{
+		"same.txt":     "same\n",
+		"ours.txt":     "base\n",
+		"theirs.txt":   "changed by them\n",
+		"conflict.txt": "theirs\n",
+		"deleted.txt":  "base\n",
+		"added.txt":    "new\n",
+	}, base)
+
+	trees := make(map[string]string)
+	for name, hash := range map[string]string{"base": base, "ours": ours, "theirs": theirs} {
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			t.Fatalf("Failed to read commit: %v", err)
+		}
+		trees[name] = commit.Tree
+	}
+
+	// Check out ours so the merge can be applied on top of it
+	oursEntries, err := objects.GetTreeEntries(repoPath, trees["ours"])
+	if err != nil {
+		t.Fatalf("Failed to read tree: %v", err)
+	}
+
+	idx := &index.Index{Entries: make(map[string]index.IndexEntry)}
+	for path, entry := range oursEntries {
+		data, err := objects.ReadBlob(repoPath, entry.Hash)
+		if err != nil {
+			t.Fatalf("Failed to read blob: %v", err)
+		}
+
+		err = os.WriteFile(filepath.Join(repoPath, path), data, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		idx.Entries[path] = index.IndexEntry{Path: path, Hash: entry.Hash, Mode: "644"}
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	result, err := MergeTrees(repoPath, trees["base"], trees["ours"], trees["theirs"], Labels{Ours: "HEAD", Theirs: "feature"})
+	if err != nil {
+		t.Fatalf("MergeTrees failed: %v", err)
+	}
+
+	if result.Clean() || len(result.Conflicts) != 1 {
+		t.Fatalf("Expected exactly one conflict, got %+v", result.Conflicts)
+	}
+
+	if _, kept := result.Entries["deleted.txt"]; kept {
+		t.Errorf("Expected deleted.txt to stay deleted")
+	}
+
+	err = Apply(repoPath, trees["ours"], result)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	want := map[string]string{
+		"same.txt":     "same\n",
+		"ours.txt":     "changed by us\n",
+		"theirs.txt":   "changed by them\n",
+		"added.txt":    "new\n",
+		"conflict.txt": "<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(filepath.Join(repoPath, path))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", path, data, content)
+		}
+	}
+
+	idx, err = index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	conflicts := idx.Conflicts()
+	if len(conflicts) != 1 || conflicts[0] != "conflict.txt" {
+		t.Errorf("Expected conflict.txt to be recorded as conflicted, got %v", conflicts)
+	}
+
+	// The conflict has to be resolved before committing
+	signature := objects.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+	_, err = objects.CreateMergeCommit(repoPath, "merge", signature, signature, []string{theirs})
+	if err == nil {
+		t.Errorf("Expected committing with unmerged paths to fail")
+	}
+}
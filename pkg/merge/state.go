@@ -0,0 +1,69 @@
+package merge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/lockfile"
+)
+
+const (
+	// headFile records the commit being merged while conflicts are resolved
+	headFile = "MERGE_HEAD"
+
+	// messageFile holds the message proposed for the merge commit
+	messageFile = "MERGE_MSG"
+)
+
+// WriteState records an interrupted merge of the given commit so a later commit can finish it
+func WriteState(repoPath, theirs, message string) error {
+	quillPath := filepath.Join(repoPath, ".quill")
+
+	err := lockfile.WriteFile(filepath.Join(quillPath, headFile), []byte(theirs+"\n"))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", headFile, err)
+	}
+
+	err = lockfile.WriteFile(filepath.Join(quillPath, messageFile), []byte(message+"\n"))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", messageFile, err)
+	}
+
+	return nil
+}
+
+// ReadState returns the commit being merged and the proposed message, or empty strings when no merge is in progress
+func ReadState(repoPath string) (string, string, error) {
+	quillPath := filepath.Join(repoPath, ".quill")
+
+	head, err := os.ReadFile(filepath.Join(quillPath, headFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("failed to read %s: %w", headFile, err)
+	}
+
+	message, err := os.ReadFile(filepath.Join(quillPath, messageFile))
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read %s: %w", messageFile, err)
+	}
+
+	return strings.TrimSpace(string(head)), strings.TrimSpace(string(message)), nil
+}
+
+// ClearState removes the record of an interrupted merge
+func ClearState(repoPath string) error {
+	quillPath := filepath.Join(repoPath, ".quill")
+
+	for _, name := range []string{headFile, messageFile} {
+		err := os.Remove(filepath.Join(quillPath, name))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
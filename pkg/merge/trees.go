@@ -0,0 +1,264 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/diff"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// Result is the outcome of merging three trees
+type Result struct {
+	// Entries holds the merged version of every path. Conflicted paths hold the
+	// version written to the working tree, with conflict markers where possible.
+	Entries map[string]objects.TreeEntry
+
+	// Conflicts records the three versions of every conflicted path
+	Conflicts map[string]index.Conflict
+
+	// Messages describes each conflict, in path order
+	Messages []string
+}
+
+// Clean reports whether the merge finished without conflicts
+func (r *Result) Clean() bool {
+	return len(r.Conflicts) == 0
+}
+
+// sameEntry reports whether two optional tree entries hold the same content and mode
+func sameEntry(a objects.TreeEntry, aExists bool, b objects.TreeEntry, bExists bool) bool {
+	if aExists != bExists {
+		return false
+	}
+
+	return !aExists || (a.Hash == b.Hash && a.Mode == b.Mode)
+}
+
+// readBlobOrEmpty returns a blob's content, or nothing for a missing version
+func readBlobOrEmpty(repoPath string, entry objects.TreeEntry, exists bool) ([]byte, error) {
+	if !exists {
+		return nil, nil
+	}
+
+	return objects.ReadBlob(repoPath, entry.Hash)
+}
+
+// MergeTrees merges the changes made from baseTree to oursTree and to theirsTree.
+// Paths changed on one side only take that side's version; paths changed on both
+// sides are merged line by line, and anything that can't be combined is reported
+// as a conflict.
+func MergeTrees(repoPath, baseTree, oursTree, theirsTree string, labels Labels) (*Result, error) {
+	baseEntries, err := objects.GetTreeEntries(repoPath, baseTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merge base tree: %w", err)
+	}
+
+	oursEntries, err := objects.GetTreeEntries(repoPath, oursTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", labels.Ours, err)
+	}
+
+	theirsEntries, err := objects.GetTreeEntries(repoPath, theirsTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", labels.Theirs, err)
+	}
+
+	paths := make(map[string]bool)
+	for _, entries := range []map[string]objects.TreeEntry{baseEntries, oursEntries, theirsEntries} {
+		for path := range entries {
+			paths[path] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	result := &Result{
+		Entries:   make(map[string]objects.TreeEntry),
+		Conflicts: make(map[string]index.Conflict),
+	}
+
+	for _, path := range sorted {
+		base, inBase := baseEntries[path]
+		ours, inOurs := oursEntries[path]
+		theirs, inTheirs := theirsEntries[path]
+
+		switch {
+		case sameEntry(ours, inOurs, theirs, inTheirs):
+			if inOurs {
+				result.Entries[path] = ours
+			}
+			continue
+		case sameEntry(base, inBase, ours, inOurs):
+			if inTheirs {
+				result.Entries[path] = theirs
+			}
+			continue
+		case sameEntry(base, inBase, theirs, inTheirs):
+			if inOurs {
+				result.Entries[path] = ours
+			}
+			continue
+		}
+
+		// Both sides changed the path in different ways
+		conflict := index.Conflict{Base: base.Hash, Ours: ours.Hash, Theirs: theirs.Hash}
+
+		if !inOurs || !inTheirs {
+			kept, deletedIn, modifiedIn := theirs, labels.Ours, labels.Theirs
+			if inOurs {
+				kept, deletedIn, modifiedIn = ours, labels.Theirs, labels.Ours
+			}
+
+			result.Entries[path] = kept
+			result.Conflicts[path] = conflict
+			result.Messages = append(result.Messages, fmt.Sprintf("CONFLICT (modify/delete): %s deleted in %s and modified in %s", path, deletedIn, modifiedIn))
+			continue
+		}
+
+		// Take whichever side changed the mode
+		mode := ours.Mode
+		if inBase && ours.Mode == base.Mode {
+			mode = theirs.Mode
+		}
+
+		if ours.Hash == theirs.Hash {
+			result.Entries[path] = objects.TreeEntry{Mode: mode, Type: objects.BlobType, Hash: ours.Hash, Path: path}
+			continue
+		}
+
+		baseData, err := readBlobOrEmpty(repoPath, base, inBase)
+		if err != nil {
+			return nil, err
+		}
+
+		oursData, err := objects.ReadBlob(repoPath, ours.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		theirsData, err := objects.ReadBlob(repoPath, theirs.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		// Binary content can't be merged by line, so ours is kept
+		if diff.IsBinary(baseData) || diff.IsBinary(oursData) || diff.IsBinary(theirsData) {
+			result.Entries[path] = ours
+			result.Conflicts[path] = conflict
+			result.Messages = append(result.Messages, fmt.Sprintf("CONFLICT (binary): Merge conflict in %s", path))
+			continue
+		}
+
+		merged, clean := MergeLines(baseData, oursData, theirsData, labels)
+
+		mergedHash, err := storage.CreateObject(repoPath, constants.BlobObject, merged)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store merged %s: %w", path, err)
+		}
+
+		result.Entries[path] = objects.TreeEntry{Mode: mode, Type: objects.BlobType, Hash: mergedHash, Path: path}
+
+		if !clean {
+			kind := "content"
+			if !inBase {
+				kind = "add/add"
+			}
+
+			result.Conflicts[path] = conflict
+			result.Messages = append(result.Messages, fmt.Sprintf("CONFLICT (%s): Merge conflict in %s", kind, path))
+		}
+	}
+
+	return result, nil
+}
+
+// Apply updates the working tree and index from the tree oursTree to a merge result.
+// Merged paths are staged and conflicted paths are recorded in the index. Untracked
+// files that the merge would overwrite make it fail before anything is changed.
+func Apply(repoPath, oursTree string, result *Result) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	oursEntries, err := objects.GetTreeEntries(repoPath, oursTree)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	var changed []string
+	for path, entry := range result.Entries {
+		ours, inOurs := oursEntries[path]
+		if _, conflicted := result.Conflicts[path]; !conflicted && sameEntry(ours, inOurs, entry, true) {
+			continue
+		}
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+
+	// Files the merge adds must not replace untracked files
+	var untracked []string
+	for _, path := range changed {
+		if _, inOurs := oursEntries[path]; inOurs {
+			continue
+		}
+
+		_, _, err := worktree.HashFile(repoPath, path)
+		if err == nil {
+			untracked = append(untracked, path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if len(untracked) > 0 {
+		return fmt.Errorf("the following untracked working tree files would be overwritten by merge:\n\t%s\nMove or remove them before you merge", strings.Join(untracked, "\n\t"))
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	for path := range oursEntries {
+		if _, kept := result.Entries[path]; kept {
+			continue
+		}
+
+		err = worktree.RemoveFile(repoPath, path)
+		if err != nil {
+			return err
+		}
+		delete(idx.Entries, path)
+	}
+
+	for _, path := range changed {
+		entry := result.Entries[path]
+
+		err = worktree.WriteFile(repoPath, path, entry.Hash, entry.Mode)
+		if err != nil {
+			return err
+		}
+
+		indexEntry := index.IndexEntry{Path: path, Hash: entry.Hash, Mode: entry.Mode, Staged: true}
+		if conflict, conflicted := result.Conflicts[path]; conflicted {
+			indexEntry.Conflict = &conflict
+		}
+		idx.Entries[path] = indexEntry
+	}
+
+	return idx.SaveIndex(repoPath)
+}
@@ -6,7 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/constants"
 	"github.com/tejastn10/quill/pkg/storage"
 )
 
@@ -40,14 +40,25 @@ func CreateBlob(repoPath string, filePath string) (string, error) {
 		return "", err
 	}
 
-	// Generating a hash for the file contents
-	blobHash := hash.ComputeSHA256(data)
-
 	// Storing the object in .quill/objects
-	err = storage.CreateObject(repoPath, blobHash, data)
+	blobHash, err := storage.CreateObject(repoPath, constants.BlobObject, data)
 	if err != nil {
 		return "", err
 	}
 
 	return blobHash, nil
 }
+
+// ReadBlob reads the content of a blob object from storage
+func ReadBlob(repoPath, hash string) ([]byte, error) {
+	objType, data, err := storage.ReadObject(repoPath, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if objType != constants.BlobObject {
+		return nil, fmt.Errorf("object %s is a %s, not a blob", hash, objType)
+	}
+
+	return data, nil
+}
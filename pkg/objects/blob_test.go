@@ -1,11 +1,15 @@
 package objects
 
 import (
+	"bytes"
+	"compress/zlib"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 
-	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/storage"
 )
 
 func TestCreateBlob(t *testing.T) {
@@ -33,21 +37,37 @@ func TestCreateBlob(t *testing.T) {
 			t.Fatalf("CreateBlob failed: %v", err)
 		}
 
+		hasher, err := storage.ObjectHasher(tempRepo)
+		if err != nil {
+			t.Fatalf("ObjectHasher failed: %v", err)
+		}
+
 		// Verify the hash matches the file content
-		expectedHash := hash.ComputeSHA256(testData)
+		expectedHash := storage.HashObject(hasher, constants.BlobObject, testData)
 		if blobHash != expectedHash {
 			t.Errorf("Blob hash mismatch: got %s, want %s", blobHash, expectedHash)
 		}
 
 		// Verify the blob was stored in the .quill/objects directory
 		objectPath := filepath.Join(tempRepo, ".quill", "objects", blobHash[:2], blobHash[2:])
-		content, err := os.ReadFile(objectPath)
+		compressed, err := os.ReadFile(objectPath)
 		if err != nil {
 			t.Fatalf("Failed to read blob object: %v", err)
 		}
 
-		if string(content) != string(testData) {
-			t.Errorf("Blob content mismatch: got %q, want %q", content, testData)
+		reader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("Blob object is not zlib compressed: %v", err)
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to decompress blob object: %v", err)
+		}
+
+		expectedContent := "blob 19\x00" + string(testData)
+		if string(content) != expectedContent {
+			t.Errorf("Blob content mismatch: got %q, want %q", content, expectedContent)
 		}
 	})
 
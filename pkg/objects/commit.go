@@ -3,29 +3,79 @@ package objects
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/tejastn10/quill/pkg/constants"
-	"github.com/tejastn10/quill/pkg/hash"
 	"github.com/tejastn10/quill/pkg/index"
-	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/refs"
 	"github.com/tejastn10/quill/pkg/storage"
 )
 
-// Commit represents a commit object
+// Commit represents a commit object. Timestamp is when the author made the change and CommitTimestamp when
+// it was recorded, which differ for cherry-picks, rebases and imports. Older commits have no committer.
 type Commit struct {
-	Hash      string `json:"hash"`
-	Parent    string `json:"parent"`
-	Timestamp string `json:"timestamp"`
-	Author    string `json:"author"`
-	Message   string `json:"message"`
-	Tree      string `json:"tree"`
+	Hash            string   `json:"-"`
+	Parents         []string `json:"parents"`
+	Timestamp       string   `json:"timestamp"`
+	Author          string   `json:"author"`
+	CommitTimestamp string   `json:"commit_timestamp,omitempty"`
+	Committer       string   `json:"committer,omitempty"`
+	Message         string   `json:"message"`
+	Tree            string   `json:"tree"`
 }
 
-// CreateCommit generates a new commit from staged changes
-func CreateCommit(repoPath, message, author string) (string, error) {
+// FirstParent returns the commit's first parent, or an empty string for a root commit
+func (c *Commit) FirstParent() string {
+	if len(c.Parents) == 0 {
+		return ""
+	}
+	return c.Parents[0]
+}
+
+// AuthorSignature returns who wrote the change and when
+func (c *Commit) AuthorSignature() (Signature, error) {
+	return parseSignature(c.Author, c.Timestamp)
+}
+
+// CommitterSignature returns who recorded the commit and when, which is the author for older commits
+func (c *Commit) CommitterSignature() (Signature, error) {
+	if c.Committer == "" {
+		return c.AuthorSignature()
+	}
+	return parseSignature(c.Committer, c.CommitTimestamp)
+}
+
+// CreateCommit generates a new commit from staged changes, authored and committed now by the given "Name <email>"
+func CreateCommit(repoPath, message, identity string) (string, error) {
+	name, email, err := ParseIdentity(identity)
+	if err != nil {
+		return "", err
+	}
+
+	signature := Signature{Name: name, Email: email, When: time.Now()}
+	return CreateMergeCommit(repoPath, message, signature, signature, nil)
+}
+
+// CreateMergeCommit generates a commit from the index whose parents are HEAD followed by the given commits
+func CreateMergeCommit(repoPath, message string, author, committer Signature, mergeParents []string) (string, error) {
+	// The index and HEAD read here must be the ones the commit replaces
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load index: %w", err)
+	}
+
+	// Conflicts have to be resolved before their paths can be recorded
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return "", fmt.Errorf("cannot commit with unmerged paths: %s", strings.Join(conflicts, ", "))
+	}
+
 	// Create tree object from index
 	treeHash, err := CreateTree(repoPath)
 	if err != nil {
@@ -33,125 +83,171 @@ func CreateCommit(repoPath, message, author string) (string, error) {
 	}
 
 	// Read HEAD to find last commit
-	headRefPath := filepath.Join(repoPath, ".quill", "HEAD")
-
-	cleanPath := filepath.Clean(headRefPath)
+	parentHash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
 
-	if !repo.IsPathSafe(cleanPath) {
-		return "", fmt.Errorf("invalid file path: potential directory traversal attempt")
+	parents := []string{}
+	if parentHash != "" {
+		parents = append(parents, parentHash)
 	}
-	headBytes, err := os.ReadFile(cleanPath)
+	parents = append(parents, mergeParents...)
 
-	var parentHash string
-	if err == nil {
-		parentHash = string(headBytes)
+	commitHash, err := CommitTree(repoPath, treeHash, parents, message, author, committer)
+	if err != nil {
+		return "", err
 	}
 
-	// Create commit object
-	commit := Commit{
-		Parent:    parentHash,
-		Timestamp: time.Now().Format(time.RFC3339),
-		Author:    author,
-		Message:   message,
-		Tree:      treeHash,
+	// Advance the current branch
+	err = refs.AdvanceHEAD(repoPath, parentHash, commitHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to update HEAD: %w", err)
 	}
 
-	// Marshal commit data
-	data, err := json.Marshal(commit)
+	// Mark all entries as unstaged and remember the committed tree
+	err = index.CreateCleanIndex(repoPath, treeHash)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal commit: %w", err)
+		return "", fmt.Errorf("failed to update index: %w", err)
 	}
 
-	// Compute commit hash
-	commit.Hash = hash.ComputeSHA256(data)
+	return commitHash, nil
+}
 
-	// Update commit with hash
-	data, err = json.Marshal(commit)
+// HasStagedChanges reports whether committing the index would record a different tree than HEAD's. The
+// staged flags alone can't tell, a removed path leaves no entry behind to carry one. Nothing is stored.
+func HasStagedChanges(repoPath string) (bool, error) {
+	idx, err := index.LoadIndex(repoPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal commit with hash: %w", err)
+		return false, fmt.Errorf("failed to load index: %w", err)
 	}
 
-	// Store commit object
-	err = storage.CreateObject(repoPath, commit.Hash, data)
+	headHash, err := refs.ResolveHEAD(repoPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to store commit: %w", err)
+		return false, fmt.Errorf("failed to read HEAD: %w", err)
 	}
 
-	// Update HEAD
-	err = os.WriteFile(headRefPath, []byte(commit.Hash), constants.ConfigFilePerms)
+	// Anything in the index is new on an unborn branch
+	if headHash == "" {
+		return len(idx.Entries) > 0, nil
+	}
+
+	head, err := ReadCommit(repoPath, headHash)
 	if err != nil {
-		return "", fmt.Errorf("failed to update HEAD: %w", err)
+		return false, err
 	}
 
-	// Mark all entries as unstaged
-	idx, err := index.LoadIndex(repoPath)
+	// Only the hash is needed, a refused commit mustn't leave trees behind
+	treeHash, err := HashIndexTree(repoPath, idx.Entries)
 	if err != nil {
-		return "", fmt.Errorf("failed to load index: %w", err)
+		return false, fmt.Errorf("failed to hash tree: %w", err)
+	}
+
+	return treeHash != head.Tree, nil
+}
+
+// CommitTree stores a commit of an existing tree with the given parents, leaving HEAD and the index alone
+func CommitTree(repoPath, treeHash string, parents []string, message string, author, committer Signature) (string, error) {
+	if parents == nil {
+		parents = []string{}
 	}
 
-	for path, entry := range idx.Entries {
-		entry.Staged = false
-		idx.Entries[path] = entry
+	commit := Commit{
+		Parents:         parents,
+		Timestamp:       author.When.Format(time.RFC3339),
+		Author:          author.String(),
+		CommitTimestamp: committer.When.Format(time.RFC3339),
+		Committer:       committer.String(),
+		Message:         message,
+		Tree:            treeHash,
 	}
 
-	// Save the updated index
-	err = idx.SaveIndex(repoPath)
+	// Marshal commit data
+	data, err := json.Marshal(commit)
 	if err != nil {
-		return "", fmt.Errorf("failed to update index: %w", err)
+		return "", fmt.Errorf("failed to marshal commit: %w", err)
+	}
+
+	// Store commit object, its hash is derived from the content
+	commitHash, err := storage.CreateObject(repoPath, constants.CommitObject, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to store commit: %w", err)
 	}
 
-	return commit.Hash, nil
+	return commitHash, nil
 }
 
 // ReadCommit reads a commit object from storage
 func ReadCommit(repoPath, hash string) (*Commit, error) {
 	// Read the commit object
-	data, err := storage.ReadObject(repoPath, hash)
+	objType, data, err := storage.ReadObject(repoPath, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read commit: %w", err)
 	}
 
-	// Unmarshal the commit
-	var commit Commit
-	err = json.Unmarshal(data, &commit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal commit: %w", err)
+	if objType != constants.CommitObject {
+		return nil, fmt.Errorf("object %s is a %s, not a commit", hash, objType)
 	}
 
-	return &commit, nil
+	return ParseCommit(hash, data)
 }
 
-// GetTreeFiles returns a list of files in a tree
-func GetTreeFiles(repoPath, treeHash string) ([]string, error) {
-	// Read the tree object
-	data, err := storage.ReadObject(repoPath, treeHash)
+// TreeOf returns the tree of a commit, or an empty tree hash for no commit
+func TreeOf(repoPath, hash string) (string, error) {
+	if hash == "" {
+		return "", nil
+	}
+
+	commit, err := ReadCommit(repoPath, hash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tree: %w", err)
+		return "", err
 	}
 
-	var tree struct {
-		Entries map[string]struct {
-			Path string `json:"path"`
-			Hash string `json:"hash"`
-			Mode string `json:"mode"`
-		} `json:"entries"`
+	return commit.Tree, nil
+}
+
+// HeadTree returns the tree of the HEAD commit, or an empty tree hash before the first commit
+func HeadTree(repoPath string) (string, error) {
+	headHash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
 	}
 
-	err = json.Unmarshal(data, &tree)
+	return TreeOf(repoPath, headHash)
+}
+
+// ParseCommit decodes the content of a commit object with the given hash
+func ParseCommit(hash string, data []byte) (*Commit, error) {
+	// Unmarshal the commit, accepting the single parent field of older commits
+	var stored struct {
+		Commit
+		Parent string `json:"parent"`
+	}
+	err := json.Unmarshal(data, &stored)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tree: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal commit: %w", err)
 	}
 
-	// Check if tree structure is valid
-	if tree.Entries == nil {
-		return nil, fmt.Errorf("invalid tree format: no entries field")
+	commit := stored.Commit
+	if len(commit.Parents) == 0 && stored.Parent != "" {
+		commit.Parents = []string{stored.Parent}
 	}
 
-	var files []string
+	commit.Hash = hash
 
-	for path, entry := range tree.Entries {
-		files = append(files, fmt.Sprintf("%s:%s", entry.Hash[:8], path))
-	}
+	return &commit, nil
+}
+
+// FirstLine returns the first line of a commit message, its subject
+func FirstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
 
-	return files, nil
+// ShortHash abbreviates a hash for display, leaving hashes already shorter than that alone
+func ShortHash(hash string) string {
+	if len(hash) <= 8 {
+		return hash
+	}
+	return hash[:8]
 }
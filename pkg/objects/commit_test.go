@@ -0,0 +1,314 @@
+package objects
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+func TestReadCommitLegacyParent(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	// Commits written before merges existed record a single parent
+	legacy := `{"parent":"abcd1234","timestamp":"2024-01-02T03:04:05Z","author":"Test User <test@example.com>","message":"old","tree":"ef567890"}`
+
+	commitHash, err := storage.CreateObject(repoPath, constants.CommitObject, []byte(legacy))
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	commit, err := ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	if len(commit.Parents) != 1 || commit.FirstParent() != "abcd1234" {
+		t.Errorf("Expected the legacy parent to be read, got %v", commit.Parents)
+	}
+
+	// Root commits have no parents at all
+	root := `{"parent":"","timestamp":"2024-01-02T03:04:05Z","author":"Test User <test@example.com>","message":"root","tree":"ef567890"}`
+
+	rootHash, err := storage.CreateObject(repoPath, constants.CommitObject, []byte(root))
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	commit, err = ReadCommit(repoPath, rootHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	if len(commit.Parents) != 0 || commit.FirstParent() != "" {
+		t.Errorf("Expected a root commit, got parents %v", commit.Parents)
+	}
+}
+
+func TestAuthorAndCommitter(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	saveIndex(t, repoPath, map[string]string{"README.md": "aa11"}, true)
+
+	author := Signature{Name: "Ada", Email: "ada@example.com", When: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5*3600+30*60))}
+	committer := Signature{Name: "Bob", Email: "bob@example.com", When: time.Date(2024, 2, 3, 4, 5, 6, 0, time.FixedZone("", -8*3600))}
+
+	commitHash, err := CreateMergeCommit(repoPath, "imported", author, committer, nil)
+	if err != nil {
+		t.Fatalf("CreateMergeCommit failed: %v", err)
+	}
+
+	commit, err := ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		role string
+		read func() (Signature, error)
+		want Signature
+	}{
+		{"author", commit.AuthorSignature, author},
+		{"committer", commit.CommitterSignature, committer},
+	} {
+		got, err := tt.read()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.role, err)
+		}
+
+		_, gotOffset := got.When.Zone()
+		_, wantOffset := tt.want.When.Zone()
+		if got.Name != tt.want.Name || got.Email != tt.want.Email || !got.When.Equal(tt.want.When) || gotOffset != wantOffset {
+			t.Errorf("Expected %s %v, got %v", tt.role, tt.want, got)
+		}
+	}
+
+	// Commits from before committers were recorded fall back to the author
+	legacy := `{"parents":[],"timestamp":"2024-01-02T03:04:05Z","author":"Test User <test@example.com>","message":"old","tree":"ef567890"}`
+
+	legacyHash, err := storage.CreateObject(repoPath, constants.CommitObject, []byte(legacy))
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	commit, err = ReadCommit(repoPath, legacyHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	got, err := commit.CommitterSignature()
+	if err != nil {
+		t.Fatalf("Failed to read committer: %v", err)
+	}
+
+	if got.String() != "Test User <test@example.com>" {
+		t.Errorf("Expected the author to stand in for the committer, got %v", got)
+	}
+}
+
+func TestSignatureFromEnv(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	user := Signature{Name: "Config User", Email: "config@example.com", When: now}
+
+	t.Setenv(AuthorNameEnv, "Env Author")
+	t.Setenv(AuthorDateEnv, "1700000000 +0200")
+	t.Setenv(CommitterEmailEnv, "env@example.com")
+
+	author, err := AuthorFromEnv(user)
+	if err != nil {
+		t.Fatalf("AuthorFromEnv failed: %v", err)
+	}
+
+	if author.Name != "Env Author" || author.Email != "config@example.com" || !author.When.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected author %v at %v", author, author.When)
+	}
+
+	committer, err := CommitterFromEnv(user)
+	if err != nil {
+		t.Fatalf("CommitterFromEnv failed: %v", err)
+	}
+
+	if committer.String() != "Config User <env@example.com>" || !committer.When.Equal(now) {
+		t.Errorf("Unexpected committer %v at %v", committer, committer.When)
+	}
+
+	t.Setenv(CommitterDateEnv, "someday")
+	if _, err := CommitterFromEnv(user); err == nil {
+		t.Errorf("Expected an invalid date to be rejected")
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	name, email, err := ParseIdentity("Ada Lovelace <ada@example.com>")
+	if err != nil || name != "Ada Lovelace" || email != "ada@example.com" {
+		t.Errorf("ParseIdentity = %q, %q, %v", name, email, err)
+	}
+
+	for _, identity := range []string{"Ada", "<ada@example.com>", "Ada <>", "Ada ada@example.com>"} {
+		if _, _, err := ParseIdentity(identity); err == nil {
+			t.Errorf("Expected ParseIdentity(%q) to fail", identity)
+		}
+	}
+}
+
+func TestHasStagedChangesAfterRemoval(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	assertChanges := func(want bool) {
+		t.Helper()
+
+		got, err := HasStagedChanges(repoPath)
+		if err != nil {
+			t.Fatalf("HasStagedChanges failed: %v", err)
+		}
+		if got != want {
+			t.Fatalf("Expected HasStagedChanges to be %v, got %v", want, got)
+		}
+	}
+
+	assertChanges(false)
+
+	saveIndex(t, repoPath, map[string]string{"a.txt": "aa11", "b.txt": "bb22"}, true)
+	assertChanges(true)
+
+	_, err := CreateCommit(repoPath, "first", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+	assertChanges(false)
+
+	// Removing a path, as rm does, leaves every remaining entry unstaged
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	delete(idx.Entries, "a.txt")
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	assertChanges(true)
+
+	commitHash, err := CreateCommit(repoPath, "remove a.txt", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+	assertChanges(false)
+
+	commit, err := ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	entries, err := GetTreeEntries(repoPath, commit.Tree)
+	if err != nil {
+		t.Fatalf("GetTreeEntries failed: %v", err)
+	}
+	if _, exists := entries["a.txt"]; exists || len(entries) != 1 {
+		t.Errorf("Expected only b.txt to be committed, got %v", entries)
+	}
+}
+
+func TestCommitStagedRemovals(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	for _, name := range []string{"keep.txt", filepath.Join("dir", "gone.txt")} {
+		path := filepath.Join(repoPath, name)
+
+		err = os.MkdirAll(filepath.Dir(path), 0750)
+		if err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(name+"\n"), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+
+		err = idx.AddFile(repoPath, path)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	_, err = CreateCommit(repoPath, "first", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+
+	// add -A after deleting a file stages nothing but the removal
+	err = os.Remove(filepath.Join(repoPath, "dir", "gone.txt"))
+	if err != nil {
+		t.Fatalf("Failed to remove gone.txt: %v", err)
+	}
+
+	idx, err = index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	removed := idx.RemoveMissing(repoPath, ".")
+	if len(removed) != 1 || removed[0] != filepath.Join("dir", "gone.txt") {
+		t.Fatalf("Expected only dir/gone.txt to be removed, got %v", removed)
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	changed, err := HasStagedChanges(repoPath)
+	if err != nil {
+		t.Fatalf("HasStagedChanges failed: %v", err)
+	}
+	if !changed {
+		t.Fatalf("Expected the removal to count as a staged change")
+	}
+
+	commitHash, err := CreateCommit(repoPath, "remove gone.txt", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+
+	commit, err := ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("ReadCommit failed: %v", err)
+	}
+
+	entries, err := GetTreeEntries(repoPath, commit.Tree)
+	if err != nil {
+		t.Fatalf("GetTreeEntries failed: %v", err)
+	}
+	if _, exists := entries["keep.txt"]; !exists || len(entries) != 1 {
+		t.Errorf("Expected only keep.txt to be committed, got %v", entries)
+	}
+}
+
+func TestShortHash(t *testing.T) {
+	for hash, want := range map[string]string{
+		"0123456789abcdef": "01234567",
+		"01234567":         "01234567",
+		"0123":             "0123",
+		"":                 "",
+	} {
+		if got := ShortHash(hash); got != want {
+			t.Errorf("ShortHash(%q) = %q, want %q", hash, got, want)
+		}
+	}
+}
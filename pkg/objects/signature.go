@@ -0,0 +1,95 @@
+package objects
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/date"
+)
+
+// Environment variables that override the identity and date recorded in new commits
+const (
+	AuthorNameEnv     = "QUILL_AUTHOR_NAME"
+	AuthorEmailEnv    = "QUILL_AUTHOR_EMAIL"
+	AuthorDateEnv     = "QUILL_AUTHOR_DATE"
+	CommitterNameEnv  = "QUILL_COMMITTER_NAME"
+	CommitterEmailEnv = "QUILL_COMMITTER_EMAIL"
+	CommitterDateEnv  = "QUILL_COMMITTER_DATE"
+)
+
+// Signature identifies who made a change and when, in the time zone they made it in
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// String formats the identity as "Name <email>"
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s>", s.Name, s.Email)
+}
+
+// ParseIdentity splits an identity written as "Name <email>" into the name and email
+func ParseIdentity(identity string) (string, string, error) {
+	open := strings.LastIndex(identity, "<")
+	if open < 0 || !strings.HasSuffix(identity, ">") {
+		return "", "", fmt.Errorf("identity %q is not in the form \"Name <email>\"", identity)
+	}
+
+	name := strings.TrimSpace(identity[:open])
+	email := strings.TrimSpace(identity[open+1 : len(identity)-1])
+	if name == "" || email == "" {
+		return "", "", fmt.Errorf("identity %q is missing a name or email", identity)
+	}
+
+	return name, email, nil
+}
+
+// AuthorFromEnv returns fallback with any QUILL_AUTHOR_NAME, QUILL_AUTHOR_EMAIL and QUILL_AUTHOR_DATE overrides applied
+func AuthorFromEnv(fallback Signature) (Signature, error) {
+	return signatureFromEnv(fallback, AuthorNameEnv, AuthorEmailEnv, AuthorDateEnv)
+}
+
+// CommitterFromEnv returns fallback with any QUILL_COMMITTER_NAME, QUILL_COMMITTER_EMAIL and QUILL_COMMITTER_DATE overrides applied
+func CommitterFromEnv(fallback Signature) (Signature, error) {
+	return signatureFromEnv(fallback, CommitterNameEnv, CommitterEmailEnv, CommitterDateEnv)
+}
+
+// signatureFromEnv overrides the parts of a signature whose environment variables are set
+func signatureFromEnv(signature Signature, nameEnv, emailEnv, dateEnv string) (Signature, error) {
+	if name := os.Getenv(nameEnv); name != "" {
+		signature.Name = name
+	}
+
+	if email := os.Getenv(emailEnv); email != "" {
+		signature.Email = email
+	}
+
+	if value := os.Getenv(dateEnv); value != "" {
+		when, err := date.Parse(value, signature.When)
+		if err != nil {
+			return Signature{}, fmt.Errorf("invalid %s: %w", dateEnv, err)
+		}
+		signature.When = when
+	}
+
+	return signature, nil
+}
+
+// parseSignature rebuilds a signature from the identity and RFC 3339 timestamp stored in an object
+func parseSignature(identity, timestamp string) (Signature, error) {
+	when, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return Signature{}, fmt.Errorf("invalid timestamp %q: %w", timestamp, err)
+	}
+
+	// Identities are stored as written, so one that doesn't parse is kept whole as the name
+	name, email, err := ParseIdentity(identity)
+	if err != nil {
+		name = identity
+	}
+
+	return Signature{Name: name, Email: email, When: when}, nil
+}
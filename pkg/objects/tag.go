@@ -0,0 +1,102 @@
+package objects
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// Tag represents an annotated tag object
+type Tag struct {
+	Hash      string `json:"-"`
+	Object    string `json:"object"`
+	Type      string `json:"type"`
+	Name      string `json:"tag"`
+	Tagger    string `json:"tagger"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// CreateTag stores an annotated tag object pointing at the given object and returns its hash
+func CreateTag(repoPath, name, objectHash, tagger, message string) (string, error) {
+	// Record the type of the tagged object so it can be peeled without reading it
+	objType, _, err := storage.ReadObject(repoPath, objectHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tagged object: %w", err)
+	}
+
+	tag := Tag{
+		Object:    objectHash,
+		Type:      objType,
+		Name:      name,
+		Tagger:    tagger,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Message:   message,
+	}
+
+	data, err := json.Marshal(tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tag: %w", err)
+	}
+
+	tag.Hash, err = storage.CreateObject(repoPath, constants.TagObject, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to store tag: %w", err)
+	}
+
+	return tag.Hash, nil
+}
+
+// ReadTag reads an annotated tag object from storage
+func ReadTag(repoPath, hash string) (*Tag, error) {
+	objType, data, err := storage.ReadObject(repoPath, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag: %w", err)
+	}
+
+	if objType != constants.TagObject {
+		return nil, fmt.Errorf("object %s is a %s, not a tag", hash, objType)
+	}
+
+	return ParseTag(hash, data)
+}
+
+// ParseTag decodes the content of an annotated tag object with the given hash
+func ParseTag(hash string, data []byte) (*Tag, error) {
+	var tag Tag
+	err := json.Unmarshal(data, &tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tag: %w", err)
+	}
+
+	tag.Hash = hash
+
+	return &tag, nil
+}
+
+// PeelTag follows annotated tags until it reaches an object that isn't a tag, returning its hash and type
+func PeelTag(repoPath, hash string) (string, string, error) {
+	// Guard against tags that point at each other
+	for depth := 0; depth < 10; depth++ {
+		objType, data, err := storage.ReadObject(repoPath, hash)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+
+		if objType != constants.TagObject {
+			return hash, objType, nil
+		}
+
+		var tag Tag
+		err = json.Unmarshal(data, &tag)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to unmarshal tag: %w", err)
+		}
+		hash = tag.Object
+	}
+
+	return "", "", fmt.Errorf("too many levels of nested tags at %s", hash)
+}
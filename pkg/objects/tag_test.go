@@ -0,0 +1,53 @@
+package objects
+
+import (
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+func TestAnnotatedTag(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	saveIndex(t, repoPath, map[string]string{"README.md": "aa11"}, true)
+
+	commitHash, err := CreateCommit(repoPath, "release", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+
+	tagHash, err := CreateTag(repoPath, "v1.0", commitHash, "Test User <test@example.com>", "First release")
+	if err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	tag, err := ReadTag(repoPath, tagHash)
+	if err != nil {
+		t.Fatalf("ReadTag failed: %v", err)
+	}
+
+	if tag.Object != commitHash || tag.Type != constants.CommitObject || tag.Name != "v1.0" || tag.Message != "First release" {
+		t.Errorf("Unexpected tag: %+v", tag)
+	}
+
+	// A tag of a tag peels all the way down to the commit
+	nestedHash, err := CreateTag(repoPath, "v1.0-signed", tagHash, "Test User <test@example.com>", "Nested")
+	if err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	peeled, objType, err := PeelTag(repoPath, nestedHash)
+	if err != nil {
+		t.Fatalf("PeelTag failed: %v", err)
+	}
+
+	if peeled != commitHash || objType != constants.CommitObject {
+		t.Errorf("PeelTag = %s (%s), want %s (commit)", peeled, objType, commitHash)
+	}
+
+	_, err = ReadTag(repoPath, commitHash)
+	if err == nil {
+		t.Errorf("Expected ReadTag to reject a commit")
+	}
+}
@@ -3,13 +3,29 @@ package objects
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 
-	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/constants"
 	"github.com/tejastn10/quill/pkg/index"
 	"github.com/tejastn10/quill/pkg/storage"
 )
 
-// TreeEntry represents an entry in a tree object
+const (
+	// BlobType marks a tree entry pointing at file contents
+	BlobType = constants.BlobObject
+
+	// TreeType marks a tree entry pointing at a subdirectory
+	TreeType = constants.TreeObject
+
+	// TreeMode is the mode recorded for subdirectory entries
+	TreeMode = "40000"
+)
+
+// TreeEntry represents an entry in a tree object.
+// Inside a stored tree Path is the name of the entry within its directory,
+// entries returned by GetTreeEntries carry the full path instead.
 type TreeEntry struct {
 	Mode string `json:"mode"`
 	Type string `json:"type"`
@@ -22,7 +38,20 @@ type Tree struct {
 	Entries []TreeEntry `json:"entries"`
 }
 
-// CreateTree creates a tree object from the current index
+// treeNode is a directory being assembled from index entries
+type treeNode struct {
+	blobs map[string]index.IndexEntry
+	dirs  map[string]*treeNode
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{
+		blobs: make(map[string]index.IndexEntry),
+		dirs:  make(map[string]*treeNode),
+	}
+}
+
+// CreateTree creates tree objects for every directory in the current index and returns the root tree hash
 func CreateTree(repoPath string) (string, error) {
 	// Load index
 	idx, err := index.LoadIndex(repoPath)
@@ -30,20 +59,220 @@ func CreateTree(repoPath string) (string, error) {
 		return "", fmt.Errorf("failed to load index: %w", err)
 	}
 
-	// Create tree object
-	treeData, err := json.Marshal(idx)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal tree: %w", err)
+	return WriteIndexTree(repoPath, idx.Entries)
+}
+
+// WriteIndexTree creates tree objects for a set of index entries that need not be the saved index
+func WriteIndexTree(repoPath string, entries map[string]index.IndexEntry) (string, error) {
+	return writeTreeNode(repoPath, indexTreeNode(entries), true)
+}
+
+// HashIndexTree returns the hash WriteIndexTree would give a set of index entries, without storing any trees
+func HashIndexTree(repoPath string, entries map[string]index.IndexEntry) (string, error) {
+	return writeTreeNode(repoPath, indexTreeNode(entries), false)
+}
+
+// indexTreeNode arranges the flat index into a directory hierarchy
+func indexTreeNode(entries map[string]index.IndexEntry) *treeNode {
+	root := newTreeNode()
+	for path, entry := range entries {
+		parts := strings.Split(filepath.ToSlash(path), "/")
+
+		node := root
+		for _, dir := range parts[:len(parts)-1] {
+			child, exists := node.dirs[dir]
+			if !exists {
+				child = newTreeNode()
+				node.dirs[dir] = child
+			}
+			node = child
+		}
+
+		node.blobs[parts[len(parts)-1]] = entry
+	}
+
+	return root
+}
+
+// writeTreeNode stores the subtrees of a directory followed by the directory itself, or only hashes them
+// when store is false
+func writeTreeNode(repoPath string, node *treeNode, store bool) (string, error) {
+	tree := Tree{Entries: []TreeEntry{}}
+
+	for name, entry := range node.blobs {
+		tree.Entries = append(tree.Entries, TreeEntry{
+			Mode: entry.Mode,
+			Type: BlobType,
+			Hash: entry.Hash,
+			Path: name,
+		})
 	}
 
-	// Compute tree hash
-	treeHash := hash.ComputeSHA256(treeData)
+	for name, child := range node.dirs {
+		childHash, err := writeTreeNode(repoPath, child, store)
+		if err != nil {
+			return "", err
+		}
+
+		tree.Entries = append(tree.Entries, TreeEntry{
+			Mode: TreeMode,
+			Type: TreeType,
+			Hash: childHash,
+			Path: name,
+		})
+	}
+
+	if !store {
+		return hashTree(repoPath, &tree)
+	}
+
+	return WriteTree(repoPath, &tree)
+}
+
+// WriteTree stores a single tree object, sorting its entries so identical directories hash identically
+func WriteTree(repoPath string, tree *Tree) (string, error) {
+	treeData, err := encodeTree(tree)
+	if err != nil {
+		return "", err
+	}
 
 	// Store tree object
-	err = storage.CreateObject(repoPath, treeHash, treeData)
+	treeHash, err := storage.CreateObject(repoPath, constants.TreeObject, treeData)
 	if err != nil {
 		return "", fmt.Errorf("failed to store tree object: %w", err)
 	}
 
 	return treeHash, nil
 }
+
+// hashTree returns the hash WriteTree would store a tree under
+func hashTree(repoPath string, tree *Tree) (string, error) {
+	treeData, err := encodeTree(tree)
+	if err != nil {
+		return "", err
+	}
+
+	hasher, err := storage.ObjectHasher(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.HashObject(hasher, constants.TreeObject, treeData), nil
+}
+
+// encodeTree sorts the entries of a tree and returns the content of its object
+func encodeTree(tree *Tree) ([]byte, error) {
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return tree.Entries[i].Path < tree.Entries[j].Path
+	})
+
+	treeData, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree: %w", err)
+	}
+
+	return treeData, nil
+}
+
+// ReadTree reads a single tree object from storage
+func ReadTree(repoPath, treeHash string) (*Tree, error) {
+	objType, data, err := storage.ReadObject(repoPath, treeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	if objType != constants.TreeObject {
+		return nil, fmt.Errorf("object %s is a %s, not a tree", treeHash, objType)
+	}
+
+	return ParseTree(data)
+}
+
+// ParseTree decodes and validates the content of a tree object
+func ParseTree(data []byte) (*Tree, error) {
+	var tree Tree
+	err := json.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree: %w", err)
+	}
+
+	// Check if tree structure is valid
+	if tree.Entries == nil {
+		return nil, fmt.Errorf("invalid tree format: no entries field")
+	}
+
+	for _, entry := range tree.Entries {
+		if entry.Path == "" || entry.Path == "." || entry.Path == ".." || strings.ContainsAny(entry.Path, "/\\") {
+			return nil, fmt.Errorf("invalid tree entry name %q", entry.Path)
+		}
+
+		if entry.Type != BlobType && entry.Type != TreeType {
+			return nil, fmt.Errorf("invalid tree entry type %q for %q", entry.Type, entry.Path)
+		}
+	}
+
+	return &tree, nil
+}
+
+// GetTreeEntries walks a tree recursively and returns its blob entries keyed by their full path.
+// An empty tree hash, the tree of no commit, has no entries.
+func GetTreeEntries(repoPath, treeHash string) (map[string]TreeEntry, error) {
+	entries := make(map[string]TreeEntry)
+	if treeHash == "" {
+		return entries, nil
+	}
+
+	err := collectTreeEntries(repoPath, treeHash, "", entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// collectTreeEntries adds the blobs below a tree to entries, prefixing their names with prefix
+func collectTreeEntries(repoPath, treeHash, prefix string, entries map[string]TreeEntry) error {
+	tree, err := ReadTree(repoPath, treeHash)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range tree.Entries {
+		path := filepath.Join(prefix, entry.Path)
+
+		if entry.Type == TreeType {
+			err = collectTreeEntries(repoPath, entry.Hash, path, entries)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		entry.Path = path
+		entries[path] = entry
+	}
+
+	return nil
+}
+
+// GetTreeFiles returns a list of files in a tree
+func GetTreeFiles(repoPath, treeHash string) ([]string, error) {
+	entries, err := GetTreeEntries(repoPath, treeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var files []string
+
+	for _, path := range paths {
+		files = append(files, fmt.Sprintf("%s:%s", ShortHash(entries[path].Hash), path))
+	}
+
+	return files, nil
+}
@@ -0,0 +1,142 @@
+package objects
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// saveIndex writes an index holding the given path -> hash entries
+func saveIndex(t *testing.T, repoPath string, entries map[string]string, staged bool) {
+	t.Helper()
+
+	idx := &index.Index{Entries: make(map[string]index.IndexEntry)}
+	for path, hash := range entries {
+		idx.Entries[path] = index.IndexEntry{Path: path, Hash: hash, Mode: "644", Staged: staged}
+	}
+
+	err := idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+}
+
+func TestCreateTreeIsHierarchical(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	saveIndex(t, repoPath, map[string]string{
+		"README.md":      "aa11",
+		"src/main.go":    "bb22",
+		"src/lib/lib.go": "cc33",
+	}, true)
+
+	treeHash, err := CreateTree(repoPath)
+	if err != nil {
+		t.Fatalf("CreateTree failed: %v", err)
+	}
+
+	root, err := ReadTree(repoPath, treeHash)
+	if err != nil {
+		t.Fatalf("ReadTree failed: %v", err)
+	}
+
+	if len(root.Entries) != 2 {
+		t.Fatalf("Expected 2 root entries, got %+v", root.Entries)
+	}
+
+	// Entries are sorted by name
+	if root.Entries[0].Path != "README.md" || root.Entries[0].Type != BlobType {
+		t.Errorf("Unexpected first entry: %+v", root.Entries[0])
+	}
+	if root.Entries[1].Path != "src" || root.Entries[1].Type != TreeType {
+		t.Errorf("Unexpected second entry: %+v", root.Entries[1])
+	}
+
+	entries, err := GetTreeEntries(repoPath, treeHash)
+	if err != nil {
+		t.Fatalf("GetTreeEntries failed: %v", err)
+	}
+
+	want := map[string]string{
+		"README.md":                           "aa11",
+		filepath.Join("src", "main.go"):       "bb22",
+		filepath.Join("src", "lib", "lib.go"): "cc33",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), entries)
+	}
+	for path, hash := range want {
+		if entries[path].Hash != hash {
+			t.Errorf("Expected %s to have hash %s, got %+v", path, hash, entries[path])
+		}
+	}
+}
+
+func TestCreateTreeIsDeterministic(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	files := map[string]string{
+		"a/x.txt": "1111",
+		"b/x.txt": "1111",
+	}
+
+	// Staging state must not affect the tree hash
+	saveIndex(t, repoPath, files, true)
+	stagedHash, err := CreateTree(repoPath)
+	if err != nil {
+		t.Fatalf("CreateTree failed: %v", err)
+	}
+
+	saveIndex(t, repoPath, files, false)
+	committedHash, err := CreateTree(repoPath)
+	if err != nil {
+		t.Fatalf("CreateTree failed: %v", err)
+	}
+
+	if stagedHash != committedHash {
+		t.Errorf("Tree hash changed with staging state: %s != %s", stagedHash, committedHash)
+	}
+
+	// Identical directories share a tree object
+	root, err := ReadTree(repoPath, stagedHash)
+	if err != nil {
+		t.Fatalf("ReadTree failed: %v", err)
+	}
+
+	if root.Entries[0].Hash != root.Entries[1].Hash {
+		t.Errorf("Expected identical directories to share a tree, got %+v", root.Entries)
+	}
+}
+
+func TestHashIndexTreeStoresNothing(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	entries := map[string]index.IndexEntry{
+		"README.md":                           {Path: "README.md", Hash: "aa11", Mode: "644"},
+		filepath.Join("src", "lib", "lib.go"): {Path: filepath.Join("src", "lib", "lib.go"), Hash: "cc33", Mode: "644"},
+	}
+
+	hashed, err := HashIndexTree(repoPath, entries)
+	if err != nil {
+		t.Fatalf("HashIndexTree failed: %v", err)
+	}
+
+	stored, err := storage.ListObjects(repoPath)
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("Expected hashing a tree to store nothing, got %v", stored)
+	}
+
+	written, err := WriteIndexTree(repoPath, entries)
+	if err != nil {
+		t.Fatalf("WriteIndexTree failed: %v", err)
+	}
+	if hashed != written {
+		t.Errorf("Expected HashIndexTree to match WriteIndexTree, got %s and %s", hashed, written)
+	}
+}
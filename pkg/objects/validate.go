@@ -0,0 +1,46 @@
+package objects
+
+import (
+	"fmt"
+
+	"github.com/tejastn10/quill/pkg/constants"
+)
+
+// Validate checks that data is well formed content for an object of the given type, so that storing it
+// can't leave an object behind that fsck reports as invalid. Whether the objects it refers to exist is
+// not checked.
+func Validate(objType string, data []byte) error {
+	switch objType {
+	case constants.BlobObject:
+		return nil
+
+	case constants.TreeObject:
+		_, err := ParseTree(data)
+		return err
+
+	case constants.CommitObject:
+		commit, err := ParseCommit("", data)
+		if err != nil {
+			return err
+		}
+
+		if commit.Tree == "" {
+			return fmt.Errorf("commit has no tree")
+		}
+		return nil
+
+	case constants.TagObject:
+		tag, err := ParseTag("", data)
+		if err != nil {
+			return err
+		}
+
+		switch tag.Type {
+		case constants.BlobObject, constants.TreeObject, constants.CommitObject, constants.TagObject:
+			return nil
+		}
+		return fmt.Errorf("tag points at an object of unknown type %q", tag.Type)
+	}
+
+	return fmt.Errorf("unknown object type %q", objType)
+}
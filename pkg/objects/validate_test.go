@@ -0,0 +1,36 @@
+package objects
+
+import (
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/constants"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		objType string
+		data    string
+		valid   bool
+	}{
+		{"any blob", constants.BlobObject, "not json", true},
+		{"tree", constants.TreeObject, `{"entries":[]}`, true},
+		{"malformed tree", constants.TreeObject, "not json", false},
+		{"commit", constants.CommitObject, `{"tree":"aa11"}`, true},
+		{"commit without tree", constants.CommitObject, `{"message":"lost"}`, false},
+		{"malformed commit", constants.CommitObject, "not json", false},
+		{"tag", constants.TagObject, `{"object":"aa11","type":"commit"}`, true},
+		{"tag of unknown type", constants.TagObject, `{"object":"aa11","type":"branch"}`, false},
+		{"unknown type", "branch", "{}", false},
+	}
+
+	for _, test := range tests {
+		err := Validate(test.objType, []byte(test.data))
+		if test.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
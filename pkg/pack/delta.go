@@ -0,0 +1,148 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A delta rebuilds a target from a base object. It starts with the uvarint sizes of the base and the
+// target, followed by instructions:
+//
+//	0x80 <uvarint offset> <uvarint length>  copy length bytes starting at offset in the base
+//	0x01 - 0x7f <literal bytes>              insert the next 1 to 127 bytes as they are
+const (
+	copyOp        = 0x80
+	maxInsertSize = 0x7f
+
+	// blockSize is the length of the base windows matches are looked up by
+	blockSize = 16
+)
+
+// ErrInvalidDelta is returned when a delta is malformed or doesn't fit its base
+var ErrInvalidDelta = errors.New("invalid delta")
+
+// Delta returns the instructions that turn base into target
+func Delta(base, target []byte) []byte {
+	var out bytes.Buffer
+	out.Write(binary.AppendUvarint(nil, uint64(len(base))))
+	out.Write(binary.AppendUvarint(nil, uint64(len(target))))
+
+	// Index the base by aligned blocks, keeping the first occurrence of each
+	blocks := make(map[string]int)
+	for offset := 0; offset+blockSize <= len(base); offset += blockSize {
+		key := string(base[offset : offset+blockSize])
+		if _, exists := blocks[key]; !exists {
+			blocks[key] = offset
+		}
+	}
+
+	var pending []byte
+	flush := func() {
+		for len(pending) > 0 {
+			n := min(len(pending), maxInsertSize)
+			out.WriteByte(byte(n))
+			out.Write(pending[:n])
+			pending = pending[n:]
+		}
+	}
+
+	for i := 0; i < len(target); {
+		offset, found := -1, false
+		if i+blockSize <= len(target) {
+			offset, found = blocks[string(target[i:i+blockSize])]
+		}
+
+		if !found {
+			pending = append(pending, target[i])
+			i++
+			continue
+		}
+
+		// Grow the match forwards, then backwards into bytes that were about to be inserted
+		length := blockSize
+		for offset+length < len(base) && i+length < len(target) && base[offset+length] == target[i+length] {
+			length++
+		}
+		i += length
+
+		for offset > 0 && len(pending) > 0 && base[offset-1] == pending[len(pending)-1] {
+			offset--
+			length++
+			pending = pending[:len(pending)-1]
+		}
+
+		flush()
+		out.WriteByte(copyOp)
+		out.Write(binary.AppendUvarint(nil, uint64(offset)))
+		out.Write(binary.AppendUvarint(nil, uint64(length)))
+	}
+	flush()
+
+	return out.Bytes()
+}
+
+// ApplyDelta rebuilds the target a delta was computed for from its base
+func ApplyDelta(base, delta []byte) ([]byte, error) {
+	reader := bytes.NewReader(delta)
+
+	baseSize, err := binary.ReadUvarint(reader)
+	if err != nil || baseSize != uint64(len(base)) {
+		return nil, fmt.Errorf("%w: base size mismatch", ErrInvalidDelta)
+	}
+
+	targetSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing target size", ErrInvalidDelta)
+	}
+
+	// A copy takes at least three bytes and yields at most the whole base, an insert one byte more than it
+	// yields, so a larger size can only come from a corrupt delta
+	remaining := uint64(reader.Len())
+	if targetSize > remaining/3*uint64(len(base))+remaining {
+		return nil, fmt.Errorf("%w: target size %d is more than the delta can produce", ErrInvalidDelta, targetSize)
+	}
+
+	// The size is only checked against the result at the end, don't allocate more than the inputs up front
+	target := make([]byte, 0, min(targetSize, uint64(len(base)+len(delta))))
+	for reader.Len() > 0 {
+		op, _ := reader.ReadByte()
+
+		switch {
+		case op == copyOp:
+			offset, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, fmt.Errorf("%w: truncated copy", ErrInvalidDelta)
+			}
+
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, fmt.Errorf("%w: truncated copy", ErrInvalidDelta)
+			}
+
+			if offset > uint64(len(base)) || length > uint64(len(base))-offset {
+				return nil, fmt.Errorf("%w: copy outside the base", ErrInvalidDelta)
+			}
+			target = append(target, base[offset:offset+length]...)
+
+		case op > 0 && op <= maxInsertSize:
+			if int(op) > reader.Len() {
+				return nil, fmt.Errorf("%w: truncated insert", ErrInvalidDelta)
+			}
+
+			literal := make([]byte, op)
+			_, _ = reader.Read(literal)
+			target = append(target, literal...)
+
+		default:
+			return nil, fmt.Errorf("%w: unknown instruction %#x", ErrInvalidDelta, op)
+		}
+	}
+
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("%w: target size mismatch", ErrInvalidDelta)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,470 @@
+package pack
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/lockfile"
+)
+
+// A pack file stores many objects in one file:
+//
+//	"QPCK" <uint32 version> <uint32 object count>
+//	entries: <kind byte> <uvarint content size> [<uvarint distance back to the base entry>] <zlib content>
+//	<checksum of everything above>
+//
+// Delta entries hold the instructions that rebuild the object from an earlier entry of the same pack.
+// The matching index file lists every hash with the offset of its entry, sorted for binary search:
+//
+//	"QIDX" <uint32 version> <uint32 object count> <uint32 hash length>
+//	<256 uint32 fan-out counts> <count x (hash, uint64 offset)> <pack checksum>
+const (
+	packMagic  = "QPCK"
+	indexMagic = "QIDX"
+	version    = 1
+
+	// Extensions of the two files making up a pack named pack-<checksum>
+	PackExt  = ".pack"
+	IndexExt = ".idx"
+
+	deltaKind byte = 5
+)
+
+// kinds maps object types to the entry kind byte stored in packs
+var kinds = map[string]byte{
+	constants.BlobObject:   1,
+	constants.TreeObject:   2,
+	constants.CommitObject: 3,
+	constants.TagObject:    4,
+}
+
+// ErrCorruptPack is returned when a pack or its index doesn't match the format
+var ErrCorruptPack = errors.New("corrupt pack")
+
+// Object is an object to be written to a pack
+type Object struct {
+	Hash string
+	Type string
+	Data []byte
+
+	// Name is a path the object was seen at. Objects with the same name are tried as delta bases for each other.
+	Name string
+}
+
+// Options control how hard Write looks for deltas
+type Options struct {
+	// Window is how many preceding objects are tried as the base of each object
+	Window int
+
+	// Depth limits how long chains of deltas on deltas may get
+	Depth int
+}
+
+// DefaultOptions are the window and depth git uses
+var DefaultOptions = Options{Window: 10, Depth: 50}
+
+// entry is an object placed in the pack being written
+type entry struct {
+	object *Object
+	offset uint64
+	depth  int
+}
+
+// Write stores the objects as a new pack in dir. Similar objects are stored as deltas against each other.
+// It returns the name of the pack, pack-<checksum>, and how many objects became deltas.
+func Write(dir string, hasher hash.Hasher, objects []Object, options Options) (string, int, error) {
+	// Put similar objects next to each other: same type, same name, larger (usually newer) first.
+	// The hash settles the rest so the same objects always make the same pack.
+	sorted := make([]*Object, len(objects))
+	for i := range objects {
+		sorted[i] = &objects[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if len(a.Data) != len(b.Data) {
+			return len(a.Data) > len(b.Data)
+		}
+		return a.Hash < b.Hash
+	})
+
+	var buffer bytes.Buffer
+	buffer.WriteString(packMagic)
+	buffer.Write(binary.BigEndian.AppendUint32(nil, version))
+	buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(len(sorted))))
+
+	entries := make([]entry, 0, len(sorted))
+	deltas := 0
+
+	for i, object := range sorted {
+		kind, known := kinds[object.Type]
+		if !known {
+			return "", 0, fmt.Errorf("cannot pack object %s of unknown type %q", object.Hash, object.Type)
+		}
+
+		current := entry{object: object, offset: uint64(buffer.Len())}
+		content := object.Data
+
+		// Try the objects in the window before this one as bases, keeping the smallest delta
+		var base *entry
+		for j := max(0, i-options.Window); j < i; j++ {
+			candidate := &entries[j]
+			if candidate.object.Type != object.Type || candidate.depth >= options.Depth {
+				continue
+			}
+
+			delta := Delta(candidate.object.Data, object.Data)
+			if len(delta) < len(content) && len(delta) < len(object.Data)/2 {
+				base, content = candidate, delta
+			}
+		}
+
+		if base != nil {
+			current.depth = base.depth + 1
+			buffer.WriteByte(deltaKind)
+			buffer.Write(binary.AppendUvarint(nil, uint64(len(content))))
+			buffer.Write(binary.AppendUvarint(nil, current.offset-base.offset))
+			deltas++
+		} else {
+			buffer.WriteByte(kind)
+			buffer.Write(binary.AppendUvarint(nil, uint64(len(content))))
+		}
+
+		writer := zlib.NewWriter(&buffer)
+		_, err := writer.Write(content)
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to compress object %s: %w", object.Hash, err)
+		}
+
+		entries = append(entries, current)
+	}
+
+	checksum, err := hex.DecodeString(hasher.Sum(buffer.Bytes()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to compute pack checksum: %w", err)
+	}
+	buffer.Write(checksum)
+
+	index, err := encodeIndex(entries, checksum)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = os.MkdirAll(dir, constants.DirectoryPerms)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create pack directory: %w", err)
+	}
+
+	// The index makes the pack visible, so it is written last
+	name := "pack-" + hex.EncodeToString(checksum)
+
+	err = lockfile.WriteAtomic(filepath.Join(dir, name+PackExt), buffer.Bytes(), constants.ConfigFilePerms)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = lockfile.WriteAtomic(filepath.Join(dir, name+IndexExt), index, constants.ConfigFilePerms)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return name, deltas, nil
+}
+
+// encodeIndex builds the index file for the entries of a pack
+func encodeIndex(entries []entry, checksum []byte) ([]byte, error) {
+	type record struct {
+		hash   []byte
+		offset uint64
+	}
+
+	records := make([]record, len(entries))
+	for i, e := range entries {
+		raw, err := hex.DecodeString(e.object.Hash)
+		if err != nil || len(raw) != len(checksum) {
+			return nil, fmt.Errorf("invalid object hash %q", e.object.Hash)
+		}
+		records[i] = record{raw, e.offset}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i].hash, records[j].hash) < 0
+	})
+
+	var buffer bytes.Buffer
+	buffer.WriteString(indexMagic)
+	buffer.Write(binary.BigEndian.AppendUint32(nil, version))
+	buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(len(records))))
+	buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(len(checksum))))
+
+	// fanout[b] counts the hashes whose first byte is at most b
+	var fanout [256]uint32
+	for _, r := range records {
+		fanout[r.hash[0]]++
+	}
+	for b := 1; b < 256; b++ {
+		fanout[b] += fanout[b-1]
+	}
+	for _, count := range fanout {
+		buffer.Write(binary.BigEndian.AppendUint32(nil, count))
+	}
+
+	for _, r := range records {
+		buffer.Write(r.hash)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, r.offset))
+	}
+
+	buffer.Write(checksum)
+	return buffer.Bytes(), nil
+}
+
+// Pack is an opened pack whose index has been loaded
+type Pack struct {
+	Name     string
+	path     string
+	hashes   [][]byte
+	offsets  []uint64
+	fanout   [256]uint32
+	checksum []byte
+}
+
+// Open loads the index of the pack stored as dir/name.idx and dir/name.pack
+func Open(dir, name string) (*Pack, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name+IndexExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack index %s: %w", name, err)
+	}
+
+	const headerSize = 16
+	if len(data) < headerSize+256*4 || string(data[:4]) != indexMagic || binary.BigEndian.Uint32(data[4:8]) != version {
+		return nil, fmt.Errorf("%w: bad index header in %s", ErrCorruptPack, name)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	hashSize := int(binary.BigEndian.Uint32(data[12:16]))
+	recordSize := hashSize + 8
+
+	if hashSize == 0 || len(data) != headerSize+256*4+count*recordSize+hashSize {
+		return nil, fmt.Errorf("%w: truncated index %s", ErrCorruptPack, name)
+	}
+
+	p := &Pack{
+		Name:    name,
+		path:    filepath.Join(dir, name+PackExt),
+		hashes:  make([][]byte, count),
+		offsets: make([]uint64, count),
+	}
+
+	position := headerSize
+	for b := range p.fanout {
+		p.fanout[b] = binary.BigEndian.Uint32(data[position:])
+		position += 4
+	}
+
+	for i := 0; i < count; i++ {
+		p.hashes[i] = data[position : position+hashSize]
+		p.offsets[i] = binary.BigEndian.Uint64(data[position+hashSize:])
+		position += recordSize
+	}
+	p.checksum = data[position:]
+
+	// find trusts the fan-out bounds and binary searches between them, so both must hold for every entry
+	for b := range p.fanout {
+		if int(p.fanout[b]) > count || (b > 0 && p.fanout[b] < p.fanout[b-1]) {
+			return nil, fmt.Errorf("%w: bad fan-out table in %s", ErrCorruptPack, name)
+		}
+	}
+	if int(p.fanout[255]) != count {
+		return nil, fmt.Errorf("%w: bad fan-out table in %s", ErrCorruptPack, name)
+	}
+
+	for i, raw := range p.hashes {
+		if i > 0 && bytes.Compare(p.hashes[i-1], raw) >= 0 {
+			return nil, fmt.Errorf("%w: unsorted index %s", ErrCorruptPack, name)
+		}
+
+		first := raw[0]
+		if i >= int(p.fanout[first]) || (first > 0 && i < int(p.fanout[first-1])) {
+			return nil, fmt.Errorf("%w: bad fan-out table in %s", ErrCorruptPack, name)
+		}
+	}
+
+	return p, nil
+}
+
+// find returns the offset of an object's entry using the fan-out table and a binary search
+func (p *Pack) find(objectHash string) (uint64, bool) {
+	raw, err := hex.DecodeString(objectHash)
+	if err != nil || len(raw) == 0 {
+		return 0, false
+	}
+
+	low := 0
+	if raw[0] > 0 {
+		low = int(p.fanout[raw[0]-1])
+	}
+	high := int(p.fanout[raw[0]])
+
+	i := low + sort.Search(high-low, func(i int) bool {
+		return bytes.Compare(p.hashes[low+i], raw) >= 0
+	})
+	if i < high && bytes.Equal(p.hashes[i], raw) {
+		return p.offsets[i], true
+	}
+
+	return 0, false
+}
+
+// Contains reports whether the pack holds an object
+func (p *Pack) Contains(objectHash string) bool {
+	_, found := p.find(objectHash)
+	return found
+}
+
+// Hashes returns the hashes of all objects in the pack, sorted
+func (p *Pack) Hashes() []string {
+	hashes := make([]string, len(p.hashes))
+	for i, raw := range p.hashes {
+		hashes[i] = hex.EncodeToString(raw)
+	}
+	return hashes
+}
+
+// HashesWithPrefix returns the hashes in the pack starting with a hex prefix
+func (p *Pack) HashesWithPrefix(prefix string) []string {
+	var matches []string
+	for _, raw := range p.hashes {
+		if objectHash := hex.EncodeToString(raw); strings.HasPrefix(objectHash, prefix) {
+			matches = append(matches, objectHash)
+		}
+	}
+	return matches
+}
+
+// Read returns the type and content of an object stored in the pack
+func (p *Pack) Read(objectHash string) (string, []byte, error) {
+	offset, found := p.find(objectHash)
+	if !found {
+		return "", nil, fmt.Errorf("object %s is not in %s", objectHash, p.Name)
+	}
+
+	file, err := os.Open(p.path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open pack %s: %w", p.Name, err)
+	}
+	defer file.Close()
+
+	return readEntry(file, p.Name, offset, 0)
+}
+
+// readEntry decodes the entry at offset, resolving delta chains through their bases
+func readEntry(file *os.File, name string, offset uint64, depth int) (string, []byte, error) {
+	if depth > 1000 {
+		return "", nil, fmt.Errorf("%w: delta chain too long in %s", ErrCorruptPack, name)
+	}
+
+	reader := &byteReader{reader: io.NewSectionReader(file, int64(offset), 1<<62)}
+
+	kind, err := reader.ReadByte()
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: entry at %d in %s: %v", ErrCorruptPack, offset, name, err)
+	}
+
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: entry at %d in %s: %v", ErrCorruptPack, offset, name, err)
+	}
+
+	var distance uint64
+	if kind == deltaKind {
+		distance, err = binary.ReadUvarint(reader)
+		if err != nil || distance == 0 || distance > offset {
+			return "", nil, fmt.Errorf("%w: bad delta base at %d in %s", ErrCorruptPack, offset, name)
+		}
+	}
+
+	decompressor, err := zlib.NewReader(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: entry at %d in %s: %v", ErrCorruptPack, offset, name, err)
+	}
+	defer decompressor.Close()
+
+	content, err := io.ReadAll(io.LimitReader(decompressor, int64(size)+1))
+	if err != nil || uint64(len(content)) != size {
+		return "", nil, fmt.Errorf("%w: entry at %d in %s has the wrong size", ErrCorruptPack, offset, name)
+	}
+
+	if kind != deltaKind {
+		for objType, k := range kinds {
+			if k == kind {
+				return objType, content, nil
+			}
+		}
+		return "", nil, fmt.Errorf("%w: unknown entry kind %d at %d in %s", ErrCorruptPack, kind, offset, name)
+	}
+
+	objType, base, err := readEntry(file, name, offset-distance, depth+1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := ApplyDelta(base, content)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: entry at %d in %s: %v", ErrCorruptPack, offset, name, err)
+	}
+
+	return objType, data, nil
+}
+
+// byteReader adds ReadByte to a reader for decoding uvarints, without reading ahead
+type byteReader struct {
+	reader io.Reader
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.reader, b[:])
+	return b[0], err
+}
+
+// Verify checks the pack's checksum against its content and its index
+func (p *Pack) Verify(hasher hash.Hasher) error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read pack %s: %w", p.Name, err)
+	}
+
+	if len(data) < len(p.checksum) || !bytes.Equal(data[len(data)-len(p.checksum):], p.checksum) {
+		return fmt.Errorf("%w: %s doesn't match its index", ErrCorruptPack, p.Name)
+	}
+
+	content := data[:len(data)-len(p.checksum)]
+	if hasher.Sum(content) != hex.EncodeToString(p.checksum) {
+		return fmt.Errorf("%w: checksum mismatch in %s", ErrCorruptPack, p.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package pack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
+)
+
+func TestDelta(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("line %d of a file that changes a little between revisions", i))
+	}
+	base := []byte(strings.Join(lines, "\n"))
+
+	lines[10] = "an edited line"
+	lines = append(lines[:50], lines[60:]...)
+	lines = append(lines, "an appended line")
+	target := []byte(strings.Join(lines, "\n"))
+
+	tests := []struct {
+		name         string
+		base, target []byte
+	}{
+		{"similar", base, target},
+		{"identical", base, base},
+		{"unrelated", base, []byte("nothing in common")},
+		{"empty target", base, nil},
+		{"empty base", nil, target},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := Delta(tt.base, tt.target)
+
+			got, err := ApplyDelta(tt.base, delta)
+			if err != nil {
+				t.Fatalf("ApplyDelta failed: %v", err)
+			}
+
+			if !bytes.Equal(got, tt.target) {
+				t.Errorf("ApplyDelta rebuilt %q, want %q", got, tt.target)
+			}
+		})
+	}
+
+	if delta := Delta(base, target); len(delta) > len(target)/10 {
+		t.Errorf("Expected a small delta for similar content, got %d bytes for %d", len(delta), len(target))
+	}
+
+	_, err := ApplyDelta([]byte("other base"), Delta(base, target))
+	if !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Expected applying a delta to the wrong base to fail, got %v", err)
+	}
+
+	// A target size the instructions can't reach is refused before anything is allocated for it
+	huge := binary.AppendUvarint(nil, uint64(len(base)))
+	huge = binary.AppendUvarint(huge, 1<<60)
+	huge = append(huge, copyOp, 0, 1)
+
+	_, err = ApplyDelta(base, huge)
+	if !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Expected a delta claiming an impossible target size to fail, got %v", err)
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	hasher, err := hash.NewHasher(hash.DefaultAlgorithm)
+	if err != nil {
+		t.Fatalf("Failed to create hasher: %v", err)
+	}
+
+	// Revisions of the same file, each a small edit of the previous one
+	content := strings.Repeat("some repeated text for a large file\n", 100)
+	var objects []Object
+	for i := 0; i < 5; i++ {
+		content = strings.Replace(content, "some", fmt.Sprintf("edit %d", i), 1)
+		data := []byte(content)
+		objects = append(objects, Object{
+			Hash: hasher.Sum(append([]byte(fmt.Sprintf("blob %d\x00", len(data))), data...)),
+			Type: constants.BlobObject,
+			Data: data,
+			Name: "file.txt",
+		})
+	}
+
+	commit := []byte(`{"tree":"abc"}`)
+	objects = append(objects, Object{Hash: hasher.Sum(append([]byte("commit 14\x00"), commit...)), Type: constants.CommitObject, Data: commit})
+
+	name, deltas, err := Write(dir, hasher, objects, DefaultOptions)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if deltas != 4 {
+		t.Errorf("Expected every revision but one to be a delta, got %d deltas", deltas)
+	}
+
+	p, err := Open(dir, name)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if len(p.Hashes()) != len(objects) {
+		t.Errorf("Expected %d objects in the index, got %d", len(objects), len(p.Hashes()))
+	}
+
+	for _, object := range objects {
+		objType, data, err := p.Read(object.Hash)
+		if err != nil {
+			t.Fatalf("Read %s failed: %v", object.Hash, err)
+		}
+
+		if objType != object.Type || !bytes.Equal(data, object.Data) {
+			t.Errorf("Read %s returned a %s with different content", object.Hash, objType)
+		}
+	}
+
+	if p.Contains(strings.Repeat("0", 64)) {
+		t.Errorf("Expected an unknown hash not to be found")
+	}
+
+	err = p.Verify(hasher)
+	if err != nil {
+		t.Errorf("Verify failed on an intact pack: %v", err)
+	}
+
+	// Flip a byte in the middle of the pack
+	packPath := filepath.Join(dir, name+PackExt)
+	data, err := os.ReadFile(packPath)
+	if err != nil {
+		t.Fatalf("Failed to read pack: %v", err)
+	}
+	data[len(data)/2] ^= 0xff
+
+	err = os.WriteFile(packPath, data, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write pack: %v", err)
+	}
+
+	err = p.Verify(hasher)
+	if !errors.Is(err, ErrCorruptPack) {
+		t.Errorf("Expected Verify to detect the corruption, got %v", err)
+	}
+}
+
+func TestOpenCorruptIndex(t *testing.T) {
+	dir := t.TempDir()
+	hasher, err := hash.NewHasher(hash.DefaultAlgorithm)
+	if err != nil {
+		t.Fatalf("Failed to create hasher: %v", err)
+	}
+
+	var objects []Object
+	for i := 0; i < 20; i++ {
+		data := []byte(fmt.Sprintf("object %d\n", i))
+		objects = append(objects, Object{
+			Hash: hasher.Sum(append([]byte(fmt.Sprintf("blob %d\x00", len(data))), data...)),
+			Type: constants.BlobObject,
+			Data: data,
+		})
+	}
+
+	name, _, err := Write(dir, hasher, objects, DefaultOptions)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	indexPath := filepath.Join(dir, name+IndexExt)
+	original, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	const fanoutStart = 16
+	recordsStart := fanoutStart + 256*4
+	recordSize := len(objects[0].Hash)/2 + 8
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte)
+	}{
+		{"fan-out past the count", func(data []byte) {
+			binary.BigEndian.PutUint32(data[fanoutStart+100*4:], uint32(len(objects)+5))
+		}},
+		{"decreasing fan-out", func(data []byte) {
+			binary.BigEndian.PutUint32(data[fanoutStart+200*4:], 0)
+		}},
+		{"fan-out not matching the hashes", func(data []byte) {
+			for b := 0; b < 255; b++ {
+				binary.BigEndian.PutUint32(data[fanoutStart+b*4:], 0)
+			}
+		}},
+		{"unsorted hashes", func(data []byte) {
+			first := data[recordsStart : recordsStart+recordSize]
+			second := data[recordsStart+recordSize : recordsStart+2*recordSize]
+			swapped := append(append([]byte{}, second...), first...)
+			copy(data[recordsStart:], swapped)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{}, original...)
+			tt.corrupt(data)
+
+			err := os.WriteFile(indexPath, data, 0600)
+			if err != nil {
+				t.Fatalf("Failed to write index: %v", err)
+			}
+
+			_, err = Open(dir, name)
+			if !errors.Is(err, ErrCorruptPack) {
+				t.Errorf("Expected Open to refuse the corrupt index, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,742 @@
+package rebase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/sequencer"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+const (
+	// stateDir holds a rebase in progress, so it can be continued, skipped or aborted by later commands
+	stateDir = "rebase-merge"
+
+	// headNameFile names the branch being rebased, or detachedHead when HEAD wasn't on a branch
+	headNameFile = "head-name"
+
+	// origHeadFile records the commit HEAD was at before the rebase, for --abort
+	origHeadFile = "orig-head"
+
+	// ontoFile records the commit the todo list is replayed onto
+	ontoFile = "onto"
+
+	// todoFile lists the instructions left to carry out
+	todoFile = "todo"
+
+	// doneFile lists the instructions carried out, the current one last
+	doneFile = "done"
+
+	// stoppedFile exists while the current instruction waits for its conflicts to be resolved
+	stoppedFile = "stopped"
+
+	// amendFile holds the commit an edit stopped at, which --continue amends with any staged changes
+	amendFile = "amend"
+
+	// squashFile exists while a run of squashes and fixups includes a squash whose message is still to be edited
+	squashFile = "squash"
+
+	detachedHead = "detached HEAD"
+)
+
+var (
+	// ErrNoRebase is returned when there is no rebase to continue, skip or abort
+	ErrNoRebase = errors.New("no rebase in progress")
+
+	// ErrEmptyMessage is returned when the user leaves a commit message empty
+	ErrEmptyMessage = errors.New("aborting due to empty commit message")
+)
+
+// EditMessage lets the user change a commit message and returns the message to use
+type EditMessage func(message string) (string, error)
+
+// State is a rebase in progress
+type State struct {
+	HeadName string // Ref of the branch being rebased, empty when HEAD was detached
+	OrigHead string
+	Onto     string
+	Todo     []Instruction
+	Done     []Instruction // The instructions carried out, the current one last
+}
+
+// Stop describes why a rebase paused before reaching the end of the todo list
+type Stop struct {
+	Instruction Instruction
+
+	// Result is the merge that left conflicts, nil when an edit instruction stopped to let the commit be amended
+	Result *merge.Result
+}
+
+// Conflicted reports whether the rebase stopped on conflicts rather than for an edit
+func (s *Stop) Conflicted() bool {
+	return s.Result != nil && !s.Result.Clean()
+}
+
+// statePath returns the location of a file in the rebase state directory
+func statePath(repoPath, name string) string {
+	return filepath.Join(repoPath, ".quill", stateDir, name)
+}
+
+// TodoPath returns the location of the todo list, which can be edited before Start
+func TodoPath(repoPath string) string {
+	return statePath(repoPath, todoFile)
+}
+
+// InProgress reports whether a rebase has been started and not yet finished or aborted
+func InProgress(repoPath string) bool {
+	_, err := os.Stat(statePath(repoPath, origHeadFile))
+	return err == nil
+}
+
+// ReadState returns the rebase in progress
+func ReadState(repoPath string) (*State, error) {
+	if !InProgress(repoPath) {
+		return nil, ErrNoRebase
+	}
+
+	values := make(map[string]string)
+	for _, name := range []string{headNameFile, origHeadFile, ontoFile, todoFile, doneFile} {
+		data, err := os.ReadFile(statePath(repoPath, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read rebase state: %w", err)
+		}
+		values[name] = string(data)
+	}
+
+	state := &State{
+		HeadName: strings.TrimSpace(values[headNameFile]),
+		OrigHead: strings.TrimSpace(values[origHeadFile]),
+		Onto:     strings.TrimSpace(values[ontoFile]),
+	}
+
+	if state.HeadName == detachedHead {
+		state.HeadName = ""
+	}
+
+	var err error
+	state.Todo, err = ParseTodo(repoPath, values[todoFile])
+	if err != nil {
+		return nil, fmt.Errorf("invalid rebase todo list: %w", err)
+	}
+
+	state.Done, err = ParseTodo(repoPath, values[doneFile])
+	if err != nil {
+		return nil, fmt.Errorf("invalid rebase done list: %w", err)
+	}
+
+	return state, nil
+}
+
+// writeState saves the whole state of a rebase
+func writeState(repoPath string, state *State) error {
+	err := os.MkdirAll(filepath.Join(repoPath, ".quill", stateDir), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create rebase directory: %w", err)
+	}
+
+	headName := state.HeadName
+	if headName == "" {
+		headName = detachedHead
+	}
+
+	for name, value := range map[string]string{headNameFile: headName, origHeadFile: state.OrigHead, ontoFile: state.Onto} {
+		err = lockfile.WriteFile(statePath(repoPath, name), []byte(value+"\n"))
+		if err != nil {
+			return fmt.Errorf("failed to write rebase state: %w", err)
+		}
+	}
+
+	// This is the list the user gets to edit, later writes store full hashes
+	err = lockfile.WriteFile(statePath(repoPath, todoFile), []byte(FormatTodo(state.Todo)))
+	if err != nil {
+		return fmt.Errorf("failed to write rebase todo list: %w", err)
+	}
+
+	return nil
+}
+
+// writeProgress saves the todo and done lists
+func writeProgress(repoPath string, state *State) error {
+	err := lockfile.WriteFile(statePath(repoPath, todoFile), []byte(formatList(state.Todo)))
+	if err != nil {
+		return fmt.Errorf("failed to write rebase todo list: %w", err)
+	}
+
+	err = lockfile.WriteFile(statePath(repoPath, doneFile), []byte(formatList(state.Done)))
+	if err != nil {
+		return fmt.Errorf("failed to write rebase done list: %w", err)
+	}
+
+	return nil
+}
+
+// clearState removes the record of a rebase
+func clearState(repoPath string) error {
+	err := os.RemoveAll(filepath.Join(repoPath, ".quill", stateDir))
+	if err != nil {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+	return nil
+}
+
+// hasMarker reports whether one of the marker files of the state directory exists
+func hasMarker(repoPath, name string) bool {
+	_, err := os.Stat(statePath(repoPath, name))
+	return err == nil
+}
+
+// setMarker creates a marker file of the state directory with the given content
+func setMarker(repoPath, name, content string) error {
+	err := lockfile.WriteFile(statePath(repoPath, name), []byte(content))
+	if err != nil {
+		return fmt.Errorf("failed to write rebase state: %w", err)
+	}
+	return nil
+}
+
+// removeMarker deletes a marker file of the state directory
+func removeMarker(repoPath, name string) error {
+	err := os.Remove(statePath(repoPath, name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to update rebase state: %w", err)
+	}
+	return nil
+}
+
+// Plan lists the commits reachable from HEAD but not from upstream as a todo list picking them oldest first,
+// parents before their children. Merge commits are left out, since their changes come in with their parents.
+func Plan(repoPath, upstream string) ([]Instruction, error) {
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	if head == "" {
+		return nil, fmt.Errorf("cannot rebase before the initial commit")
+	}
+
+	inUpstream, err := merge.Ancestors(repoPath, upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	var todo []Instruction
+	visited := make(map[string]bool)
+
+	var visit func(hash string) error
+	visit = func(hash string) error {
+		if visited[hash] || inUpstream[hash] {
+			return nil
+		}
+		visited[hash] = true
+
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			return err
+		}
+
+		for _, parent := range commit.Parents {
+			err = visit(parent)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(commit.Parents) <= 1 {
+			todo = append(todo, Instruction{Command: Pick, Hash: hash, Subject: objects.FirstLine(commit.Message)})
+		}
+		return nil
+	}
+
+	err = visit(head)
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// Begin records a rebase of HEAD onto a commit with the given todo list. Nothing is checked out until Start,
+// so the todo list at TodoPath can be edited in between.
+func Begin(repoPath, onto string, todo []Instruction) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if InProgress(repoPath) {
+		return fmt.Errorf("a rebase is already in progress, use --continue, --skip or --abort")
+	}
+
+	headName, err := refs.ReadHEAD(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	origHead, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	return writeState(repoPath, &State{HeadName: headName, OrigHead: origHead, Onto: onto, Todo: todo})
+}
+
+// Start checks out the commit the rebase is onto, detaching HEAD, and carries out the todo list. It returns a
+// Stop when an instruction conflicts or asks to edit a commit; otherwise the rebased branch is updated and
+// checked out again. A todo list that melds its first commit into one that isn't being rebased is refused.
+func Start(repoPath string, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instruction := range state.Todo {
+		if instruction.Command == Drop {
+			continue
+		}
+
+		if instruction.melds() {
+			return nil, errors.Join(fmt.Errorf("cannot %s %s without a previous commit", instruction.Command, objects.ShortHash(instruction.Hash)), Abort(repoPath))
+		}
+		break
+	}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ontoTree, err := objects.TreeOf(repoPath, state.Onto)
+	if err != nil {
+		return nil, err
+	}
+
+	err = worktree.Checkout(repoPath, currentTree, ontoTree, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = refs.DetachHEAD(repoPath, state.Onto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	return run(repoPath, state, committer, edit)
+}
+
+// Continue finishes the instruction the rebase stopped at and carries on with the rest. Conflicts have to
+// be resolved and added first. After an edit, staged changes are amended into the commit that was stopped at.
+func Continue(repoPath string, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasMarker(repoPath, stoppedFile) && len(state.Done) > 0 {
+		err = finish(repoPath, state, state.Done[len(state.Done)-1], committer, edit)
+		if err != nil {
+			return nil, err
+		}
+
+		err = removeMarker(repoPath, stoppedFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hasMarker(repoPath, amendFile) {
+		err = amendStaged(repoPath, committer)
+		if err != nil {
+			return nil, err
+		}
+
+		err = removeMarker(repoPath, amendFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return run(repoPath, state, committer, edit)
+}
+
+// Skip drops the instruction the rebase stopped at, discarding its changes, and carries on with the rest
+func Skip(repoPath string, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = worktree.ResetHard(repoPath, currentTree, currentTree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{stoppedFile, amendFile} {
+		err = removeMarker(repoPath, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return run(repoPath, state, committer, edit)
+}
+
+// Abort puts HEAD, the index and the working tree back to where they were before the rebase started
+func Abort(repoPath string) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := ReadState(repoPath)
+	if err != nil {
+		return err
+	}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return err
+	}
+
+	origTree, err := objects.TreeOf(repoPath, state.OrigHead)
+	if err != nil {
+		return err
+	}
+
+	err = worktree.ResetHard(repoPath, currentTree, origTree)
+	if err != nil {
+		return err
+	}
+
+	// The branch itself only moves once the rebase is done
+	if state.HeadName != "" {
+		err = refs.SetHEADToBranch(repoPath, strings.TrimPrefix(state.HeadName, refs.HeadsPrefix))
+	} else {
+		err = refs.DetachHEAD(repoPath, state.OrigHead)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	return clearState(repoPath)
+}
+
+// run carries out the todo list until an instruction stops it, then moves the branch to the result
+func run(repoPath string, state *State, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	for len(state.Todo) > 0 {
+		instruction := state.Todo[0]
+		state.Todo = state.Todo[1:]
+		state.Done = append(state.Done, instruction)
+
+		err := writeProgress(repoPath, state)
+		if err != nil {
+			return nil, err
+		}
+
+		stop, err := replay(repoPath, state, instruction, committer, edit)
+		if err != nil {
+			// Leave the instruction to be tried again by --continue
+			state.Todo = append([]Instruction{instruction}, state.Todo...)
+			state.Done = state.Done[:len(state.Done)-1]
+			return nil, errors.Join(err, writeProgress(repoPath, state))
+		}
+
+		if stop != nil {
+			return stop, nil
+		}
+	}
+
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	if state.HeadName != "" {
+		// The branch must still be where the rebase started, or whatever moved it would be lost
+		err = refs.UpdateRefFrom(repoPath, state.HeadName, state.OrigHead, head)
+		if err != nil {
+			return nil, err
+		}
+
+		err = refs.SetHEADToBranch(repoPath, strings.TrimPrefix(state.HeadName, refs.HeadsPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update HEAD: %w", err)
+		}
+	}
+
+	return nil, clearState(repoPath)
+}
+
+// replay carries out a single instruction on top of HEAD
+func replay(repoPath string, state *State, instruction Instruction, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	if instruction.Command == Drop {
+		return nil, nil
+	}
+
+	commit, err := objects.ReadCommit(repoPath, instruction.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	// A commit whose parent is HEAD already is reused as it is instead of being recreated
+	if !instruction.melds() && len(commit.Parents) == 1 && commit.FirstParent() == head {
+		currentTree, err := objects.TreeOf(repoPath, head)
+		if err != nil {
+			return nil, err
+		}
+
+		err = worktree.Checkout(repoPath, currentTree, commit.Tree, false)
+		if err != nil {
+			return nil, err
+		}
+
+		err = refs.DetachHEAD(repoPath, commit.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update HEAD: %w", err)
+		}
+
+		return afterCommit(repoPath, instruction, committer, edit)
+	}
+
+	result, err := sequencer.MergeStep(repoPath, sequencer.Step{Action: sequencer.Pick, Hash: instruction.Hash})
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Clean() {
+		return &Stop{Instruction: instruction, Result: result}, setMarker(repoPath, stoppedFile, instruction.Hash+"\n")
+	}
+
+	if instruction.melds() {
+		return nil, meld(repoPath, state, instruction, committer, edit)
+	}
+
+	outcome, err := sequencer.CommitStep(repoPath, sequencer.Step{Action: sequencer.Pick, Hash: instruction.Hash}, committer)
+	if err != nil {
+		return nil, err
+	}
+
+	// A commit whose change is already upstream becomes empty and is left out
+	if outcome.Commit == "" {
+		return nil, nil
+	}
+
+	return afterCommit(repoPath, instruction, committer, edit)
+}
+
+// finish commits the resolved conflicts of the instruction the rebase stopped at
+func finish(repoPath string, state *State, instruction Instruction, committer objects.Signature, edit EditMessage) error {
+	if instruction.melds() {
+		return meld(repoPath, state, instruction, committer, edit)
+	}
+
+	outcome, err := sequencer.CommitStep(repoPath, sequencer.Step{Action: sequencer.Pick, Hash: instruction.Hash}, committer)
+	if err != nil {
+		return err
+	}
+
+	if outcome.Commit == "" || instruction.Command != Reword {
+		return nil
+	}
+
+	_, err = afterCommit(repoPath, instruction, committer, edit)
+	return err
+}
+
+// afterCommit does what a reword or edit asks for once its commit is HEAD
+func afterCommit(repoPath string, instruction Instruction, committer objects.Signature, edit EditMessage) (*Stop, error) {
+	switch instruction.Command {
+	case Reword:
+		head, err := headCommit(repoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := edit(head.Message)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, amendHead(repoPath, head.Tree, message, committer)
+
+	case Edit:
+		head, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HEAD: %w", err)
+		}
+
+		return &Stop{Instruction: instruction}, setMarker(repoPath, amendFile, head+"\n")
+	}
+
+	return nil, nil
+}
+
+// meld folds the merged index into HEAD. A squash adds its message to HEAD's, and the combined message is
+// handed to the user once the last squash or fixup in a row has been melded.
+func meld(repoPath string, state *State, instruction Instruction, committer objects.Signature, edit EditMessage) error {
+	head, err := headCommit(repoPath)
+	if err != nil {
+		return err
+	}
+
+	commit, err := objects.ReadCommit(repoPath, instruction.Hash)
+	if err != nil {
+		return err
+	}
+
+	tree, err := indexTree(repoPath)
+	if err != nil {
+		return err
+	}
+
+	message := head.Message
+	if instruction.Command == Squash {
+		message = strings.TrimRight(head.Message, "\n") + "\n\n" + commit.Message
+
+		err = setMarker(repoPath, squashFile, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	lastInRow := len(state.Todo) == 0 || !state.Todo[0].melds()
+	if lastInRow && hasMarker(repoPath, squashFile) {
+		message, err = edit(message)
+		if err != nil {
+			return err
+		}
+
+		err = removeMarker(repoPath, squashFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return amendHead(repoPath, tree, message, committer)
+}
+
+// amendStaged amends the commit an edit stopped at with the staged changes, if HEAD is still that commit
+func amendStaged(repoPath string, committer objects.Signature) error {
+	data, err := os.ReadFile(statePath(repoPath, amendFile))
+	if err != nil {
+		return fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	head, err := headCommit(repoPath)
+	if err != nil {
+		return err
+	}
+
+	// New commits were made on top of the stopped one, they are kept as they are
+	if head.Hash != strings.TrimSpace(string(data)) {
+		return nil
+	}
+
+	tree, err := indexTree(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if tree == head.Tree {
+		return nil
+	}
+
+	return amendHead(repoPath, tree, head.Message, committer)
+}
+
+// amendHead replaces the HEAD commit with one that has the given tree and message but the same parents and author
+func amendHead(repoPath, tree, message string, committer objects.Signature) error {
+	if strings.TrimSpace(message) == "" {
+		return ErrEmptyMessage
+	}
+
+	head, err := headCommit(repoPath)
+	if err != nil {
+		return err
+	}
+
+	author, err := head.AuthorSignature()
+	if err != nil {
+		return fmt.Errorf("failed to read author of %s: %w", head.Hash, err)
+	}
+
+	amended, err := objects.CommitTree(repoPath, tree, head.Parents, message, author, committer)
+	if err != nil {
+		return err
+	}
+
+	err = refs.DetachHEAD(repoPath, amended)
+	if err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	err = index.CreateCleanIndex(repoPath, tree)
+	if err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
+	return nil
+}
+
+// indexTree writes the index as a tree, refusing while it has unmerged paths
+func indexTree(repoPath string) (string, error) {
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load index: %w", err)
+	}
+
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return "", fmt.Errorf("cannot continue with unmerged paths: %s", strings.Join(conflicts, ", "))
+	}
+
+	return objects.WriteIndexTree(repoPath, idx.Entries)
+}
+
+// headCommit reads the commit HEAD points at
+func headCommit(repoPath string) (*objects.Commit, error) {
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	return objects.ReadCommit(repoPath, head)
+}
@@ -0,0 +1,408 @@
+package rebase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var signature = objects.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+
+// commitFile writes and stages a file, then commits it and returns the new commit
+func commitFile(t *testing.T, repoPath, name, content, message string) string {
+	t.Helper()
+
+	testutil.Stage(t, repoPath, name, content)
+
+	hash, err := objects.CreateCommit(repoPath, message, signature.String())
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return hash
+}
+
+// switchBranch checks out a branch, creating it at HEAD first when it doesn't exist
+func switchBranch(t *testing.T, repoPath, branch string) {
+	t.Helper()
+
+	head := currentCommit(t, repoPath)
+
+	if !refs.BranchExists(repoPath, branch) {
+		err := refs.CreateBranch(repoPath, branch, head.Hash, false)
+		if err != nil {
+			t.Fatalf("Failed to create branch %s: %v", branch, err)
+		}
+	}
+
+	target, err := refs.ReadBranch(repoPath, branch)
+	if err != nil {
+		t.Fatalf("Failed to read branch %s: %v", branch, err)
+	}
+
+	targetTree, err := objects.TreeOf(repoPath, target)
+	if err != nil {
+		t.Fatalf("Failed to read branch %s: %v", branch, err)
+	}
+
+	err = worktree.Checkout(repoPath, head.Tree, targetTree, false)
+	if err != nil {
+		t.Fatalf("Failed to check out %s: %v", branch, err)
+	}
+
+	err = refs.SetHEADToBranch(repoPath, branch)
+	if err != nil {
+		t.Fatalf("Failed to update HEAD: %v", err)
+	}
+}
+
+// currentCommit reads the commit HEAD points at
+func currentCommit(t *testing.T, repoPath string) *objects.Commit {
+	t.Helper()
+
+	commit, err := headCommit(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD commit: %v", err)
+	}
+
+	return commit
+}
+
+// history returns the first lines of the messages along the first parents of HEAD, newest first
+func history(t *testing.T, repoPath string) []string {
+	t.Helper()
+
+	var subjects []string
+	for hash := currentCommit(t, repoPath).Hash; hash != ""; {
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			t.Fatalf("Failed to read commit %s: %v", hash, err)
+		}
+
+		subjects = append(subjects, objects.FirstLine(commit.Message))
+		hash = commit.FirstParent()
+	}
+
+	return subjects
+}
+
+// assertHistory checks the subjects along the first parents of HEAD, newest first
+func assertHistory(t *testing.T, repoPath string, want ...string) {
+	t.Helper()
+
+	got := history(t, repoPath)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected history %q, got %q", want, got)
+	}
+}
+
+// noEditor fails the test if a rebase asks for a message to be edited
+func noEditor(t *testing.T) EditMessage {
+	return func(message string) (string, error) {
+		t.Errorf("Unexpected request to edit %q", message)
+		return message, nil
+	}
+}
+
+// rebaseOnto plans and starts a rebase of HEAD onto a branch, applying the edit to the todo list first
+func rebaseOnto(t *testing.T, repoPath, branch string, editTodo func([]Instruction) []Instruction, edit EditMessage) *Stop {
+	t.Helper()
+
+	onto, err := refs.ReadBranch(repoPath, branch)
+	if err != nil {
+		t.Fatalf("Failed to read branch %s: %v", branch, err)
+	}
+
+	todo, err := Plan(repoPath, onto)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if editTodo != nil {
+		todo = editTodo(todo)
+	}
+
+	err = Begin(repoPath, onto, todo)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	stop, err := Start(repoPath, signature, edit)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	return stop
+}
+
+func TestParseTodo(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	first := commitFile(t, repoPath, "a.txt", "a\n", "first")
+	second := commitFile(t, repoPath, "b.txt", "b\n", "second\n\nWith a body.")
+
+	todo := []Instruction{{Command: Pick, Hash: first, Subject: "first"}, {Command: Squash, Hash: second, Subject: "second"}}
+	text := FormatTodo(todo)
+
+	if !strings.HasPrefix(text, "pick "+first[:8]+" first\nsquash "+second[:8]+" second\n") {
+		t.Errorf("Unexpected todo list:\n%s", text)
+	}
+
+	parsed, err := ParseTodo(repoPath, text)
+	if err != nil {
+		t.Fatalf("ParseTodo failed: %v", err)
+	}
+
+	if len(parsed) != 2 || parsed[0] != todo[0] || parsed[1] != todo[1] {
+		t.Errorf("Expected %v back, got %v", todo, parsed)
+	}
+
+	// Abbreviated commands, full hashes and any subject are accepted
+	parsed, err = ParseTodo(repoPath, "# comment\n\nr "+second+" whatever\nf "+first[:6]+"\nd "+second[:8])
+	if err != nil {
+		t.Fatalf("ParseTodo failed: %v", err)
+	}
+
+	want := []Instruction{{Reword, second, "second"}, {Fixup, first, "first"}, {Drop, second, "second"}}
+	if len(parsed) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, parsed)
+	}
+	for i := range want {
+		if parsed[i] != want[i] {
+			t.Errorf("Expected instruction %d to be %v, got %v", i, want[i], parsed[i])
+		}
+	}
+
+	for _, invalid := range []string{"jump " + first, "pick", "pick nosuchcommit"} {
+		_, err = ParseTodo(repoPath, invalid)
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestRebase(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "a.txt", "1\n2\n3\n", "base")
+	switchBranch(t, repoPath, "topic")
+	commitFile(t, repoPath, "b.txt", "b\n", "add b")
+	commitFile(t, repoPath, "a.txt", "1\n2\nthree\n", "edit three")
+
+	switchBranch(t, repoPath, "main")
+	commitFile(t, repoPath, "a.txt", "one\n2\n3\n", "edit one")
+	main := currentCommit(t, repoPath).Hash
+
+	switchBranch(t, repoPath, "topic")
+
+	stop := rebaseOnto(t, repoPath, "main", nil, noEditor(t))
+	if stop != nil {
+		t.Fatalf("Expected the rebase to finish, stopped at %v", stop.Instruction)
+	}
+
+	assertHistory(t, repoPath, "edit three", "add b", "edit one", "base")
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n2\nthree\n")
+	testutil.AssertFile(t, repoPath, "b.txt", "b\n")
+
+	branch, err := refs.CurrentBranch(repoPath)
+	if err != nil || branch != "topic" {
+		t.Errorf("Expected topic to be checked out again, got %q (%v)", branch, err)
+	}
+
+	if InProgress(repoPath) {
+		t.Errorf("Expected the rebase state to be removed")
+	}
+
+	// Rebasing again has nothing left to replay
+	todo, err := Plan(repoPath, main)
+	if err != nil || len(todo) != 2 {
+		t.Errorf("Expected the two rebased commits to be planned, got %v (%v)", todo, err)
+	}
+
+	head := currentCommit(t, repoPath).Hash
+	stop = rebaseOnto(t, repoPath, "main", nil, noEditor(t))
+	if stop != nil || currentCommit(t, repoPath).Hash != head {
+		t.Errorf("Expected replaying commits onto their own parents to keep them, got %s", currentCommit(t, repoPath).Hash)
+	}
+}
+
+func TestInteractive(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "base.txt", "base\n", "base")
+	switchBranch(t, repoPath, "topic")
+	for _, n := range []string{"1", "2", "3", "4", "5"} {
+		commitFile(t, repoPath, n+".txt", n+"\n", "commit "+n)
+	}
+
+	var edited []string
+	edit := func(message string) (string, error) {
+		edited = append(edited, message)
+		if strings.HasPrefix(message, "commit 2") {
+			return "reworded 2", nil
+		}
+		return "squashed", nil
+	}
+
+	// Keep 1, fold 4 into it, reword 2, squash 3 and 5 into it
+	stop := rebaseOnto(t, repoPath, "main", func(todo []Instruction) []Instruction {
+		return []Instruction{
+			todo[0],
+			{Command: Fixup, Hash: todo[3].Hash},
+			{Command: Reword, Hash: todo[1].Hash},
+			{Command: Squash, Hash: todo[2].Hash},
+			{Command: Fixup, Hash: todo[4].Hash},
+		}
+	}, edit)
+	if stop != nil {
+		t.Fatalf("Expected the rebase to finish, stopped at %v", stop.Instruction)
+	}
+
+	assertHistory(t, repoPath, "squashed", "commit 1", "base")
+	for _, n := range []string{"1", "2", "3", "4", "5"} {
+		testutil.AssertFile(t, repoPath, n+".txt", n+"\n")
+	}
+
+	// Commit 4 was folded into commit 1
+	first, err := objects.ReadCommit(repoPath, currentCommit(t, repoPath).FirstParent())
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	entries, err := objects.GetTreeEntries(repoPath, first.Tree)
+	if err != nil {
+		t.Fatalf("Failed to read tree: %v", err)
+	}
+
+	if _, hasFour := entries["4.txt"]; !hasFour || len(entries) != 3 {
+		t.Errorf("Expected commit 1 to hold base, 1 and 4, got %v", entries)
+	}
+
+	// The reword and the end of the squash run each asked once
+	if len(edited) != 2 || edited[0] != "commit 2" || edited[1] != "reworded 2\n\ncommit 3" {
+		t.Errorf("Unexpected messages to edit: %q", edited)
+	}
+}
+
+func TestConflictContinueSkipAbort(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "a.txt", "1\n2\n3\n", "base")
+	switchBranch(t, repoPath, "topic")
+	commitFile(t, repoPath, "b.txt", "b\n", "add b")
+	commitFile(t, repoPath, "a.txt", "one\n2\n3\n", "lower one")
+	commitFile(t, repoPath, "c.txt", "c\n", "add c")
+	original := currentCommit(t, repoPath).Hash
+
+	switchBranch(t, repoPath, "main")
+	commitFile(t, repoPath, "a.txt", "ONE\n2\n3\n", "upper one")
+	switchBranch(t, repoPath, "topic")
+
+	stop := rebaseOnto(t, repoPath, "main", nil, noEditor(t))
+	if stop == nil || !stop.Conflicted() || stop.Instruction.Subject != "lower one" {
+		t.Fatalf("Expected the rebase to stop on the conflict, got %+v", stop)
+	}
+
+	state, err := ReadState(repoPath)
+	if err != nil || state.HeadName != "refs/heads/topic" || state.OrigHead != original || len(state.Todo) != 1 || len(state.Done) != 2 {
+		t.Fatalf("Unexpected rebase state %+v (%v)", state, err)
+	}
+
+	_, err = Continue(repoPath, signature, noEditor(t))
+	if err == nil {
+		t.Errorf("Expected continue to fail with unmerged paths")
+	}
+
+	err = Abort(repoPath)
+	if err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+
+	branch, err := refs.CurrentBranch(repoPath)
+	if err != nil || branch != "topic" || currentCommit(t, repoPath).Hash != original || InProgress(repoPath) {
+		t.Errorf("Expected abort to return to topic at %s, got %q at %s", original, branch, currentCommit(t, repoPath).Hash)
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n2\n3\n")
+
+	// Resolve the conflict and carry on
+	rebaseOnto(t, repoPath, "main", nil, noEditor(t))
+	testutil.Stage(t, repoPath, "a.txt", "One\n2\n3\n")
+
+	stop, err = Continue(repoPath, signature, noEditor(t))
+	if err != nil || stop != nil {
+		t.Fatalf("Expected continue to finish the rebase, got %+v (%v)", stop, err)
+	}
+
+	assertHistory(t, repoPath, "add c", "lower one", "add b", "upper one", "base")
+	testutil.AssertFile(t, repoPath, "a.txt", "One\n2\n3\n")
+
+	// Leave the conflicting commit out instead
+	rebasedTree := currentCommit(t, repoPath).Tree
+
+	err = refs.UpdateRef(repoPath, refs.HeadsPrefix+"topic", original)
+	if err != nil {
+		t.Fatalf("Failed to reset topic: %v", err)
+	}
+
+	err = worktree.ResetHard(repoPath, rebasedTree, currentCommit(t, repoPath).Tree)
+	if err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+
+	rebaseOnto(t, repoPath, "main", nil, noEditor(t))
+
+	stop, err = Skip(repoPath, signature, noEditor(t))
+	if err != nil || stop != nil {
+		t.Fatalf("Expected skip to finish the rebase, got %+v (%v)", stop, err)
+	}
+
+	assertHistory(t, repoPath, "add c", "add b", "upper one", "base")
+	testutil.AssertFile(t, repoPath, "a.txt", "ONE\n2\n3\n")
+}
+
+func TestEdit(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "a.txt", "a\n", "base")
+	switchBranch(t, repoPath, "topic")
+	commitFile(t, repoPath, "b.txt", "b\n", "add b")
+	commitFile(t, repoPath, "c.txt", "c\n", "add c")
+
+	stop := rebaseOnto(t, repoPath, "main", func(todo []Instruction) []Instruction {
+		todo[0].Command = Edit
+		return todo
+	}, noEditor(t))
+
+	if stop == nil || stop.Conflicted() || stop.Instruction.Subject != "add b" {
+		t.Fatalf("Expected the rebase to stop for the edit, got %+v", stop)
+	}
+
+	testutil.Stage(t, repoPath, "b.txt", "b\namended\n")
+
+	stop, err := Continue(repoPath, signature, noEditor(t))
+	if err != nil || stop != nil {
+		t.Fatalf("Expected continue to finish the rebase, got %+v (%v)", stop, err)
+	}
+
+	assertHistory(t, repoPath, "add c", "add b", "base")
+	testutil.AssertFile(t, repoPath, "b.txt", "b\namended\n")
+
+	amended, err := objects.ReadCommit(repoPath, currentCommit(t, repoPath).FirstParent())
+	if err != nil {
+		t.Fatalf("Failed to read amended commit: %v", err)
+	}
+
+	entries, err := objects.GetTreeEntries(repoPath, amended.Tree)
+	if err != nil || entries["b.txt"].Hash == "" {
+		t.Fatalf("Failed to read amended tree: %v", err)
+	}
+
+	if _, inAmended := entries["c.txt"]; inAmended {
+		t.Errorf("Expected the amended commit to hold only its own change")
+	}
+}
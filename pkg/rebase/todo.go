@@ -0,0 +1,135 @@
+package rebase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/revparse"
+)
+
+// Command says what to do with a commit in the todo list
+type Command string
+
+const (
+	// Pick replays the commit as it is
+	Pick Command = "pick"
+
+	// Reword replays the commit and lets the user change its message
+	Reword Command = "reword"
+
+	// Edit replays the commit and stops so it can be amended
+	Edit Command = "edit"
+
+	// Squash melds the commit into the one before it, combining both messages
+	Squash Command = "squash"
+
+	// Fixup melds the commit into the one before it, keeping only the earlier message
+	Fixup Command = "fixup"
+
+	// Drop leaves the commit out
+	Drop Command = "drop"
+)
+
+// commands maps every name and abbreviation accepted in a todo list to its command
+var commands = map[string]Command{
+	"pick": Pick, "p": Pick,
+	"reword": Reword, "r": Reword,
+	"edit": Edit, "e": Edit,
+	"squash": Squash, "s": Squash,
+	"fixup": Fixup, "f": Fixup,
+	"drop": Drop, "d": Drop,
+}
+
+// todoHelp is appended to the todo list shown to the user
+const todoHelp = `
+# Commands:
+# p, pick <commit> = use commit
+# r, reword <commit> = use commit, but edit the commit message
+# e, edit <commit> = use commit, but stop for amending
+# s, squash <commit> = use commit, but meld into previous commit
+# f, fixup <commit> = like "squash", but discard this commit's message
+# d, drop <commit> = remove commit
+#
+# These lines can be re-ordered; they are executed from top to bottom.
+# If you remove a line here THAT COMMIT WILL BE LOST.
+# However, if you remove everything, the rebase will be aborted.
+`
+
+// Instruction is a single line of the todo list
+type Instruction struct {
+	Command Command
+	Hash    string
+	Subject string // First line of the commit message, for display only
+}
+
+// String formats the instruction the way it appears in the todo list
+func (i Instruction) String() string {
+	return fmt.Sprintf("%s %s %s", i.Command, objects.ShortHash(i.Hash), i.Subject)
+}
+
+// line formats the instruction with its full hash, the way the rebase state stores it
+func (i Instruction) line() string {
+	return fmt.Sprintf("%s %s %s", i.Command, i.Hash, i.Subject)
+}
+
+// melds reports whether the instruction folds its commit into the previous one
+func (i Instruction) melds() bool {
+	return i.Command == Squash || i.Command == Fixup
+}
+
+// FormatTodo writes a todo list for the user to edit, with abbreviated hashes and help explaining the commands
+func FormatTodo(todo []Instruction) string {
+	var out strings.Builder
+	for _, instruction := range todo {
+		out.WriteString(instruction.String() + "\n")
+	}
+	out.WriteString(todoHelp)
+
+	return out.String()
+}
+
+// formatList writes instructions with full hashes, so they still resolve once more objects exist
+func formatList(todo []Instruction) string {
+	var out strings.Builder
+	for _, instruction := range todo {
+		out.WriteString(instruction.line() + "\n")
+	}
+	return out.String()
+}
+
+// ParseTodo reads a todo list, skipping blank lines and comments. Commit hashes may be abbreviated.
+func ParseTodo(repoPath, text string) ([]Instruction, error) {
+	var todo []Instruction
+
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		command, known := commands[fields[0]]
+		if !known {
+			return nil, fmt.Errorf("line %d: unknown command %q", n+1, fields[0])
+		}
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing commit after %q", n+1, fields[0])
+		}
+
+		hash, err := revparse.ResolveCommit(repoPath, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		commit, err := objects.ReadCommit(repoPath, hash)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		todo = append(todo, Instruction{Command: command, Hash: hash, Subject: objects.FirstLine(commit.Message)})
+	}
+
+	return todo, nil
+}
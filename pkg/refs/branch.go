@@ -0,0 +1,124 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidateBranchName checks that a branch name is usable as a ref
+func ValidateBranchName(name string) error {
+	return validateRefName(name, "branch")
+}
+
+// ListBranches returns the names of all branches, sorted
+func ListBranches(repoPath string) ([]string, error) {
+	branches, err := listRefs(repoPath, HeadsPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+// BranchExists reports whether a branch with the given name exists
+func BranchExists(repoPath, name string) bool {
+	return RefExists(repoPath, HeadsPrefix+name)
+}
+
+// ReadBranch returns the commit hash a branch points at
+func ReadBranch(repoPath, name string) (string, error) {
+	hash, err := ReadRef(repoPath, HeadsPrefix+name)
+	if errors.Is(err, ErrRefNotFound) {
+		return "", fmt.Errorf("branch %q not found", name)
+	}
+	return hash, err
+}
+
+// CreateBranch creates a branch pointing at the given commit
+func CreateBranch(repoPath, name, hash string, force bool) error {
+	err := ValidateBranchName(name)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		return UpdateRef(repoPath, HeadsPrefix+name, hash)
+	}
+
+	// Checked under the ref's lock, so a branch created meanwhile isn't overwritten
+	err = UpdateRefFrom(repoPath, HeadsPrefix+name, "", hash)
+	if errors.Is(err, ErrRefChanged) {
+		return fmt.Errorf("a branch named %q already exists", name)
+	}
+	return err
+}
+
+// DeleteBranch removes a branch, refusing to delete the checked out one
+func DeleteBranch(repoPath, name string) error {
+	current, err := CurrentBranch(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if current == name {
+		return fmt.Errorf("cannot delete branch %q checked out", name)
+	}
+
+	err = DeleteRef(repoPath, HeadsPrefix+name)
+	if errors.Is(err, ErrRefNotFound) {
+		return fmt.Errorf("branch %q not found", name)
+	}
+	return err
+}
+
+// RenameBranch renames a branch and keeps HEAD attached to it if it was checked out
+func RenameBranch(repoPath, oldName, newName string, force bool) error {
+	err := ValidateBranchName(newName)
+	if err != nil {
+		return err
+	}
+
+	current, err := CurrentBranch(repoPath)
+	if err != nil {
+		return err
+	}
+
+	hash, err := ReadRef(repoPath, HeadsPrefix+oldName)
+	if err != nil {
+		// Renaming the unborn current branch only needs HEAD to move
+		if errors.Is(err, ErrRefNotFound) && oldName == current {
+			return SetHEADToBranch(repoPath, newName)
+		}
+		if errors.Is(err, ErrRefNotFound) {
+			return fmt.Errorf("branch %q not found", oldName)
+		}
+		return err
+	}
+
+	if oldName == newName {
+		return nil
+	}
+
+	if force {
+		err = UpdateRef(repoPath, HeadsPrefix+newName, hash)
+	} else {
+		err = UpdateRefFrom(repoPath, HeadsPrefix+newName, "", hash)
+		if errors.Is(err, ErrRefChanged) {
+			return fmt.Errorf("a branch named %q already exists", newName)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = DeleteRef(repoPath, HeadsPrefix+oldName)
+	if err != nil {
+		return err
+	}
+
+	if current == oldName {
+		return SetHEADToBranch(repoPath, newName)
+	}
+
+	return nil
+}
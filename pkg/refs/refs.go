@@ -0,0 +1,382 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+const (
+	// HeadsPrefix is the namespace holding branch refs
+	HeadsPrefix = "refs/heads/"
+
+	// TagsPrefix is the namespace holding tag refs
+	TagsPrefix = "refs/tags/"
+
+	// DefaultBranch is the branch HEAD points at in a fresh repository
+	DefaultBranch = "main"
+
+	// symbolicPrefix marks a ref whose content names another ref
+	symbolicPrefix = "ref: "
+)
+
+var (
+	// ErrRefNotFound is returned when a ref file does not exist
+	ErrRefNotFound = errors.New("ref not found")
+
+	// ErrRefChanged is returned when a ref no longer holds the value its updater read, because another process moved it
+	ErrRefChanged = errors.New("ref was changed by another process")
+)
+
+// validateRefName checks that a short ref name such as a branch or tag name is usable as a ref
+func validateRefName(name, kind string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%s name cannot be empty", kind)
+	case name == "HEAD":
+		return fmt.Errorf("%q is not a valid %s name", name, kind)
+	case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "/"), strings.HasSuffix(name, "/"):
+		return fmt.Errorf("%q is not a valid %s name", name, kind)
+	case strings.HasSuffix(name, ".lock"), strings.HasSuffix(name, "."):
+		return fmt.Errorf("%q is not a valid %s name", name, kind)
+	case strings.Contains(name, ".."), strings.Contains(name, "//"), strings.Contains(name, "@{"):
+		return fmt.Errorf("%q is not a valid %s name", name, kind)
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return fmt.Errorf("%q is not a valid %s name", name, kind)
+		}
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("%q is not a valid %s name", name, kind)
+		}
+	}
+
+	return nil
+}
+
+// listRefs returns the names of all refs below a namespace such as refs/heads/, relative to it and sorted
+func listRefs(repoPath, prefix string) ([]string, error) {
+	namespaceDir := filepath.Join(repoPath, ".quill", filepath.FromSlash(prefix))
+
+	var names []string
+
+	err := filepath.Walk(namespaceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == namespaceDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		// Lock files of refs being updated are not refs
+		if info.IsDir() || strings.HasSuffix(info.Name(), lockfile.Suffix) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(namespaceDir, path)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListRefs returns the full names of every branch and tag ref, such as refs/heads/main, sorted
+func ListRefs(repoPath string) ([]string, error) {
+	names, err := listRefs(repoPath, "refs/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	for i, name := range names {
+		names[i] = "refs/" + name
+	}
+
+	return names, nil
+}
+
+// refPath returns the on-disk location of a ref such as "HEAD" or "refs/heads/main"
+func refPath(repoPath, name string) (string, error) {
+	quillPath := filepath.Join(repoPath, ".quill")
+	path := filepath.Clean(filepath.Join(quillPath, filepath.FromSlash(name)))
+
+	// Ensure the ref stays inside the .quill directory
+	if !strings.HasPrefix(path, quillPath+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid ref name %q", name)
+	}
+
+	if !repo.IsPathSafe(path) {
+		return "", fmt.Errorf("invalid file path: potential directory traversal attempt")
+	}
+
+	return path, nil
+}
+
+// readRefFile returns the trimmed content of a ref file
+func readRefFile(repoPath, name string) (string, error) {
+	path, err := refPath(repoPath, name)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrRefNotFound
+		}
+		return "", fmt.Errorf("failed to read ref %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// lockRef takes the lock on a ref file, creating parent directories as needed
+func lockRef(repoPath, name string) (*lockfile.Lock, error) {
+	path, err := refPath(repoPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), constants.DirectoryPerms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for ref %s: %w", name, err)
+	}
+
+	lock, err := lockfile.Acquire(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock ref %s: %w", name, err)
+	}
+
+	return lock, nil
+}
+
+// commitRef writes the new content of a ref through its lock, releasing it
+func commitRef(lock *lockfile.Lock, name, content string) error {
+	err := lock.Write([]byte(content + "\n"))
+	if err == nil {
+		err = lock.Commit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write ref %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// checkRef reports ErrRefChanged unless a ref file holds old, an empty old meaning the ref must not exist.
+// Callers hold the ref's lock, so the ref can't change between the check and their write.
+func checkRef(repoPath, name, old string) error {
+	current, err := readRefFile(repoPath, name)
+	if errors.Is(err, ErrRefNotFound) {
+		current, err = "", nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current == old:
+		return nil
+	case old == "":
+		return fmt.Errorf("%w: %s already exists", ErrRefChanged, name)
+	case current == "":
+		return fmt.Errorf("%w: %s no longer exists", ErrRefChanged, name)
+	}
+
+	return fmt.Errorf("%w: %s is at %s instead of %s", ErrRefChanged, name, current, old)
+}
+
+// writeRefFile replaces the content of a ref file under its lock, so concurrent updates can't interleave
+func writeRefFile(repoPath, name, content string) error {
+	lock, err := lockRef(repoPath, name)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	return commitRef(lock, name, content)
+}
+
+// ReadRef resolves a ref to a commit hash, following symbolic refs
+func ReadRef(repoPath, name string) (string, error) {
+	// Guard against symbolic ref loops
+	for depth := 0; depth < 5; depth++ {
+		content, err := readRefFile(repoPath, name)
+		if err != nil {
+			return "", err
+		}
+
+		target, symbolic := strings.CutPrefix(content, symbolicPrefix)
+		if !symbolic {
+			// Callers take the hash as it is, an empty or truncated ref file must not get through
+			if !hash.IsHash(content) {
+				return "", fmt.Errorf("ref %s is corrupt", name)
+			}
+			return content, nil
+		}
+		name = target
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic refs for %s", name)
+}
+
+// UpdateRef points a ref at the given commit hash, whatever it pointed at before
+func UpdateRef(repoPath, name, hash string) error {
+	return writeRefFile(repoPath, name, hash)
+}
+
+// UpdateRefFrom points a ref at the given commit hash if it still points at old, the value the caller read
+// before deciding on the update. An empty old means the ref must not exist yet. The check and the write
+// happen under the ref's lock, and ErrRefChanged is returned when the ref moved in between.
+func UpdateRefFrom(repoPath, name, old, hash string) error {
+	lock, err := lockRef(repoPath, name)
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	err = checkRef(repoPath, name, old)
+	if err != nil {
+		return err
+	}
+
+	return commitRef(lock, name, hash)
+}
+
+// DeleteRef removes a ref and any directories left empty by its removal
+func DeleteRef(repoPath, name string) error {
+	path, err := refPath(repoPath, name)
+	if err != nil {
+		return err
+	}
+
+	err = lockfile.Remove(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrRefNotFound
+		}
+		return fmt.Errorf("failed to delete ref %s: %w", name, err)
+	}
+
+	// Clean up empty namespace directories such as refs/heads/feature/
+	refsRoot := filepath.Join(repoPath, ".quill", "refs")
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, refsRoot+string(os.PathSeparator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// RefExists reports whether the given ref file exists
+func RefExists(repoPath, name string) bool {
+	_, err := readRefFile(repoPath, name)
+	return err == nil
+}
+
+// ReadHEAD returns the ref HEAD points at, or an empty string when HEAD is detached
+func ReadHEAD(repoPath string) (string, error) {
+	content, err := readRefFile(repoPath, "HEAD")
+	if err != nil {
+		if errors.Is(err, ErrRefNotFound) {
+			// Repositories created before branches existed have no HEAD until the first commit
+			return HeadsPrefix + DefaultBranch, nil
+		}
+		return "", err
+	}
+
+	target, symbolic := strings.CutPrefix(content, symbolicPrefix)
+	if !symbolic {
+		return "", nil
+	}
+
+	return target, nil
+}
+
+// ResolveHEAD returns the commit HEAD points at, through the checked out branch unless HEAD is detached.
+// It is empty before the first commit.
+func ResolveHEAD(repoPath string) (string, error) {
+	hash, err := ReadRef(repoPath, "HEAD")
+	if errors.Is(err, ErrRefNotFound) {
+		return "", nil
+	}
+
+	return hash, err
+}
+
+// CurrentBranch returns the name of the checked out branch, or an empty string when HEAD is detached
+func CurrentBranch(repoPath string) (string, error) {
+	target, err := ReadHEAD(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(target, HeadsPrefix), nil
+}
+
+// SetHEADToBranch makes HEAD a symbolic ref to the given branch
+func SetHEADToBranch(repoPath, branch string) error {
+	return writeRefFile(repoPath, "HEAD", symbolicPrefix+HeadsPrefix+branch)
+}
+
+// DetachHEAD points HEAD directly at a commit
+func DetachHEAD(repoPath, hash string) error {
+	return writeRefFile(repoPath, "HEAD", hash)
+}
+
+// AdvanceHEAD moves the current branch from old to the given commit, or HEAD itself when it is detached.
+// An empty old means the branch has no commits yet. HEAD stays locked throughout so it can't switch branches
+// meanwhile, and ErrRefChanged is returned when the branch or detached HEAD no longer points at old.
+func AdvanceHEAD(repoPath, old, hash string) error {
+	headLock, err := lockRef(repoPath, "HEAD")
+	if err != nil {
+		return err
+	}
+	defer headLock.Rollback()
+
+	target, err := ReadHEAD(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if target == "" {
+		err = checkRef(repoPath, "HEAD", old)
+		if err != nil {
+			return err
+		}
+		return commitRef(headLock, "HEAD", hash)
+	}
+
+	err = UpdateRefFrom(repoPath, target, old, hash)
+	if err != nil {
+		return err
+	}
+
+	// Give repositories without a HEAD file one pointing at the branch just created
+	if !RefExists(repoPath, "HEAD") {
+		return commitRef(headLock, "HEAD", symbolicPrefix+target)
+	}
+
+	return nil
+}
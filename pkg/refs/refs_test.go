@@ -0,0 +1,316 @@
+package refs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+const (
+	commitA = "1111111111111111111111111111111111111111111111111111111111111111"
+	commitB = "2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestResolveHEAD(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	assertHEAD := func(want string) {
+		t.Helper()
+
+		got, err := ResolveHEAD(repoPath)
+		if err != nil {
+			t.Fatalf("ResolveHEAD failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected HEAD to resolve to %q, got %q", want, got)
+		}
+	}
+
+	// The default branch has no commits yet
+	assertHEAD("")
+
+	err := UpdateRef(repoPath, HeadsPrefix+DefaultBranch, commitA)
+	if err != nil {
+		t.Fatalf("UpdateRef failed: %v", err)
+	}
+	assertHEAD(commitA)
+
+	err = DetachHEAD(repoPath, commitB)
+	if err != nil {
+		t.Fatalf("DetachHEAD failed: %v", err)
+	}
+	assertHEAD(commitB)
+
+	// Repositories from before branches existed may have no HEAD at all
+	err = os.Remove(filepath.Join(repoPath, ".quill", "HEAD"))
+	if err != nil {
+		t.Fatalf("Failed to remove HEAD: %v", err)
+	}
+	assertHEAD("")
+}
+
+func TestReadRefRejectsCorruptRefs(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	for _, content := range []string{"", "\n", commitA[:8], commitA + "0", strings.Repeat("A", 64), "not a hash"} {
+		err := os.WriteFile(filepath.Join(repoPath, ".quill", "HEAD"), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write HEAD: %v", err)
+		}
+
+		if _, err := ReadRef(repoPath, "HEAD"); err == nil {
+			t.Errorf("Expected ReadRef to refuse HEAD containing %q", content)
+		}
+
+		if _, err := ResolveHEAD(repoPath); err == nil {
+			t.Errorf("Expected ResolveHEAD to refuse HEAD containing %q", content)
+		}
+	}
+}
+
+func TestAdvanceHEADMovesCurrentBranch(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	err := AdvanceHEAD(repoPath, "", commitA)
+	if err != nil {
+		t.Fatalf("AdvanceHEAD failed: %v", err)
+	}
+
+	hash, err := ReadBranch(repoPath, DefaultBranch)
+	if err != nil {
+		t.Fatalf("ReadBranch failed: %v", err)
+	}
+	if hash != commitA {
+		t.Errorf("Expected main to point at %s, got %s", commitA, hash)
+	}
+
+	head, err := ResolveHEAD(repoPath)
+	if err != nil {
+		t.Fatalf("ResolveHEAD failed: %v", err)
+	}
+	if head != commitA {
+		t.Errorf("Expected HEAD to resolve to %s, got %s", commitA, head)
+	}
+}
+
+func TestAdvanceHEADRefusesMovedRef(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	err := AdvanceHEAD(repoPath, "", commitA)
+	if err != nil {
+		t.Fatalf("AdvanceHEAD failed: %v", err)
+	}
+
+	// Another process moved main after this one read it as unborn
+	err = AdvanceHEAD(repoPath, "", commitB)
+	if !errors.Is(err, ErrRefChanged) {
+		t.Errorf("Expected ErrRefChanged, got %v", err)
+	}
+
+	err = UpdateRefFrom(repoPath, HeadsPrefix+DefaultBranch, commitB, commitA)
+	if !errors.Is(err, ErrRefChanged) {
+		t.Errorf("Expected ErrRefChanged, got %v", err)
+	}
+
+	hash, err := ReadBranch(repoPath, DefaultBranch)
+	if err != nil {
+		t.Fatalf("ReadBranch failed: %v", err)
+	}
+	if hash != commitA {
+		t.Errorf("Expected main to stay at %s, got %s", commitA, hash)
+	}
+
+	// A held lock keeps the ref from being updated at all
+	lock, err := lockRef(repoPath, HeadsPrefix+DefaultBranch)
+	if err != nil {
+		t.Fatalf("Failed to lock main: %v", err)
+	}
+	defer lock.Rollback()
+
+	err = AdvanceHEAD(repoPath, commitA, commitB)
+	if !errors.Is(err, lockfile.ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+}
+
+func TestDetachedHEAD(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	err := DetachHEAD(repoPath, commitA)
+	if err != nil {
+		t.Fatalf("DetachHEAD failed: %v", err)
+	}
+
+	branch, err := CurrentBranch(repoPath)
+	if err != nil {
+		t.Fatalf("CurrentBranch failed: %v", err)
+	}
+	if branch != "" {
+		t.Errorf("Expected detached HEAD, got branch %q", branch)
+	}
+
+	// Committing on a detached HEAD moves HEAD only
+	err = AdvanceHEAD(repoPath, commitA, commitB)
+	if err != nil {
+		t.Fatalf("AdvanceHEAD failed: %v", err)
+	}
+
+	head, err := ReadRef(repoPath, "HEAD")
+	if err != nil {
+		t.Fatalf("ReadRef failed: %v", err)
+	}
+	if head != commitB {
+		t.Errorf("Expected HEAD to be %s, got %s", commitB, head)
+	}
+
+	if BranchExists(repoPath, DefaultBranch) {
+		t.Errorf("Expected main to remain unborn")
+	}
+}
+
+func TestBranchLifecycle(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	err := AdvanceHEAD(repoPath, "", commitA)
+	if err != nil {
+		t.Fatalf("AdvanceHEAD failed: %v", err)
+	}
+
+	err = CreateBranch(repoPath, "feature/login", commitB, false)
+	if err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	err = CreateBranch(repoPath, "feature/login", commitA, false)
+	if err == nil {
+		t.Errorf("Expected creating an existing branch to fail")
+	}
+
+	branches, err := ListBranches(repoPath)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(branches) != 2 || branches[0] != "feature/login" || branches[1] != "main" {
+		t.Errorf("Unexpected branches: %v", branches)
+	}
+
+	// Renaming the current branch keeps HEAD attached
+	err = RenameBranch(repoPath, "main", "trunk", false)
+	if err != nil {
+		t.Fatalf("RenameBranch failed: %v", err)
+	}
+
+	branch, err := CurrentBranch(repoPath)
+	if err != nil {
+		t.Fatalf("CurrentBranch failed: %v", err)
+	}
+	if branch != "trunk" {
+		t.Errorf("Expected current branch to be trunk, got %q", branch)
+	}
+
+	err = DeleteBranch(repoPath, "trunk")
+	if err == nil {
+		t.Errorf("Expected deleting the current branch to fail")
+	}
+
+	err = DeleteBranch(repoPath, "feature/login")
+	if err != nil {
+		t.Fatalf("DeleteBranch failed: %v", err)
+	}
+
+	// The emptied namespace directory is cleaned up
+	if _, err := os.Stat(filepath.Join(repoPath, ".quill", "refs", "heads", "feature")); !os.IsNotExist(err) {
+		t.Errorf("Expected empty feature directory to be removed")
+	}
+}
+
+func TestTagLifecycle(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	err := CreateTag(repoPath, "v1.0", commitA, false)
+	if err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	err = CreateTag(repoPath, "v1.0", commitB, false)
+	if err == nil {
+		t.Errorf("Expected creating an existing tag to fail")
+	}
+
+	// Forcing moves the tag
+	err = CreateTag(repoPath, "v1.0", commitB, true)
+	if err != nil {
+		t.Fatalf("CreateTag with force failed: %v", err)
+	}
+
+	hash, err := ReadTag(repoPath, "v1.0")
+	if err != nil {
+		t.Fatalf("ReadTag failed: %v", err)
+	}
+	if hash != commitB {
+		t.Errorf("Expected tag to point at %s, got %s", commitB, hash)
+	}
+
+	err = CreateTag(repoPath, "releases/v2", commitA, false)
+	if err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	tags, err := ListTags(repoPath)
+	if err != nil {
+		t.Fatalf("ListTags failed: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != "releases/v2" || tags[1] != "v1.0" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+
+	err = DeleteTag(repoPath, "releases/v2")
+	if err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+
+	err = DeleteTag(repoPath, "releases/v2")
+	if err == nil {
+		t.Errorf("Expected deleting a missing tag to fail")
+	}
+
+	err = CreateTag(repoPath, "bad tag", commitA, false)
+	if err == nil {
+		t.Errorf("Expected an invalid tag name to be rejected")
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"main", true},
+		{"feature/login", true},
+		{"release-1.0", true},
+		{"", false},
+		{"HEAD", false},
+		{"-flag", false},
+		{"has space", false},
+		{"a..b", false},
+		{"../escape", false},
+		{"trailing/", false},
+		{"name.lock", false},
+		{"feature/.hidden", false},
+		{"what?", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBranchName(tt.name)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateBranchName(%q) error = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		})
+	}
+}
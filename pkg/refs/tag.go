@@ -0,0 +1,63 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidateTagName checks that a tag name is usable as a ref
+func ValidateTagName(name string) error {
+	return validateRefName(name, "tag")
+}
+
+// ListTags returns the names of all tags, sorted
+func ListTags(repoPath string) ([]string, error) {
+	tags, err := listRefs(repoPath, TagsPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// TagExists reports whether a tag with the given name exists
+func TagExists(repoPath, name string) bool {
+	return RefExists(repoPath, TagsPrefix+name)
+}
+
+// ReadTag returns the object hash a tag points at, either a commit or a tag object
+func ReadTag(repoPath, name string) (string, error) {
+	hash, err := ReadRef(repoPath, TagsPrefix+name)
+	if errors.Is(err, ErrRefNotFound) {
+		return "", fmt.Errorf("tag %q not found", name)
+	}
+	return hash, err
+}
+
+// CreateTag creates a tag pointing at the given object
+func CreateTag(repoPath, name, hash string, force bool) error {
+	err := ValidateTagName(name)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		return UpdateRef(repoPath, TagsPrefix+name, hash)
+	}
+
+	// Checked under the ref's lock, so a tag created meanwhile isn't overwritten
+	err = UpdateRefFrom(repoPath, TagsPrefix+name, "", hash)
+	if errors.Is(err, ErrRefChanged) {
+		return fmt.Errorf("tag %q already exists", name)
+	}
+	return err
+}
+
+// DeleteTag removes a tag
+func DeleteTag(repoPath, name string) error {
+	err := DeleteRef(repoPath, TagsPrefix+name)
+	if errors.Is(err, ErrRefNotFound) {
+		return fmt.Errorf("tag %q not found", name)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
 )
 
 // CreateUserConfig creates the user's config when quill is initialized
@@ -64,21 +66,78 @@ func CreateUserConfig(name string, email string) error {
 
 // ReadUserConfig reads the user's name and email from the config file
 func ReadUserConfig(repoPath string) (string, string, error) {
-	configPath := filepath.Join(repoPath, ".quill", "config", "user")
+	configPath := filepath.Clean(filepath.Join(repoPath, ".quill", "config", "user"))
 
-	cleanPath := filepath.Clean(configPath)
-
-	if !IsPathSafe(cleanPath) {
+	if !IsPathSafe(configPath) {
 		return "", "", fmt.Errorf("invalid file path: potential directory traversal attempt")
 	}
 
-	file, err := os.Open(cleanPath)
+	values, err := readConfigFile(repoPath, "user")
 	if err != nil {
 		return "", "", fmt.Errorf("failed to open user config: %w", err)
 	}
+
+	name, email := values["name"], values["email"]
+	if name == "" || email == "" {
+		return "", "", fmt.Errorf("user name or email not found in config")
+	}
+
+	return name, email, nil
+}
+
+// CreateCoreConfig records repository wide settings such as the object format
+func CreateCoreConfig(repoPath, objectFormat string) error {
+	configPath := filepath.Clean(filepath.Join(repoPath, ".quill", "config", "core"))
+
+	err := os.MkdirAll(filepath.Dir(configPath), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	err = os.WriteFile(configPath, []byte(fmt.Sprintf("objectformat=%s\n", objectFormat)), constants.ConfigFilePerms)
+	if err != nil {
+		return fmt.Errorf("failed to write core config file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadObjectFormat returns the object hash algorithm recorded for the repository.
+// Repositories created before the format was recorded use SHA-256.
+func ReadObjectFormat(repoPath string) (string, error) {
+	values, err := readConfigFile(repoPath, "core")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return hash.DefaultAlgorithm, nil
+		}
+		return "", fmt.Errorf("failed to read core config: %w", err)
+	}
+
+	format, ok := values["objectformat"]
+	if !ok || format == "" {
+		return hash.DefaultAlgorithm, nil
+	}
+
+	return format, nil
+}
+
+// readConfigFile parses a key=value file from the .quill/config directory
+func readConfigFile(repoPath, name string) (map[string]string, error) {
+	configDir := filepath.Clean(filepath.Join(repoPath, ".quill", "config"))
+	cleanPath := filepath.Clean(filepath.Join(configDir, name))
+
+	// Ensure the config file stays inside the config directory
+	if !strings.HasPrefix(cleanPath, configDir+string(os.PathSeparator)) {
+		return nil, fmt.Errorf("invalid config file path: %s", cleanPath)
+	}
+
+	file, err := os.Open(cleanPath)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
-	var name, email string
+	values := make(map[string]string)
 	scanner := bufio.NewScanner(file)
 
 	for scanner.Scan() {
@@ -88,26 +147,14 @@ func ReadUserConfig(repoPath string) (string, string, error) {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "name":
-			name = value
-		case "email":
-			email = value
-		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 	}
 
 	if err := scanner.Err(); err != nil {
-		return "", "", fmt.Errorf("error reading config file: %w", err)
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
-	if name == "" || email == "" {
-		return "", "", fmt.Errorf("user name or email not found in config")
-	}
-
-	return name, email, nil
+	return values, nil
 }
 
 // isValidEmail checks if the given email follows a valid format
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
 )
 
 func TestCreateUserConfig(t *testing.T) {
@@ -113,3 +114,31 @@ func TestIsValidEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectFormat(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Repositories without a recorded format use the default
+	format, err := ReadObjectFormat(tempDir)
+	if err != nil {
+		t.Fatalf("ReadObjectFormat returned an error: %v", err)
+	}
+
+	if format != hash.DefaultAlgorithm {
+		t.Errorf("ReadObjectFormat = %q, want %q", format, hash.DefaultAlgorithm)
+	}
+
+	err = CreateCoreConfig(tempDir, hash.BLAKE2b)
+	if err != nil {
+		t.Fatalf("CreateCoreConfig returned an error: %v", err)
+	}
+
+	format, err = ReadObjectFormat(tempDir)
+	if err != nil {
+		t.Fatalf("ReadObjectFormat returned an error: %v", err)
+	}
+
+	if format != hash.BLAKE2b {
+		t.Errorf("ReadObjectFormat = %q, want %q", format, hash.BLAKE2b)
+	}
+}
@@ -9,13 +9,14 @@ import (
 	"github.com/tejastn10/quill/pkg/constants"
 )
 
-// CreateQuillRepository initializes a new Quill repository by creating a .quill directory structure with objects and config subdirectories
+// CreateQuillRepository initializes a new Quill repository by creating a .quill directory structure with objects, refs and config subdirectories
 func CreateQuillRepository(path string) error {
 	// Defining the Quill directory structure
 	directories := []string{
 		filepath.Join(path, ".quill"),
 		filepath.Join(path, ".quill", "config"),
 		filepath.Join(path, ".quill", "objects"),
+		filepath.Join(path, ".quill", "refs", "heads"),
 	}
 
 	// Creating directories
@@ -26,6 +27,15 @@ func CreateQuillRepository(path string) error {
 		}
 	}
 
+	// Point HEAD at the default branch, leaving an existing HEAD untouched
+	headPath := filepath.Join(path, ".quill", "HEAD")
+	if _, err := os.Stat(headPath); os.IsNotExist(err) {
+		err = os.WriteFile(headPath, []byte("ref: refs/heads/main\n"), constants.ConfigFilePerms)
+		if err != nil {
+			return fmt.Errorf("failed to create HEAD: %w", err)
+		}
+	}
+
 	return nil
 }
 
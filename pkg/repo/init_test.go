@@ -22,12 +22,23 @@ func TestCreateQuillRepository(t *testing.T) {
 		filepath.Join(testDir, ".quill"),
 		filepath.Join(testDir, ".quill", "objects"),
 		filepath.Join(testDir, ".quill", "config"),
+		filepath.Join(testDir, ".quill", "refs", "heads"),
 	}
 	for _, dir := range expectedDirs {
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			t.Errorf("Expected directory %s to exist, but it doesn't", dir)
 		}
 	}
+
+	// Verifying HEAD points at the default branch
+	head, err := os.ReadFile(filepath.Join(testDir, ".quill", "HEAD"))
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+
+	if string(head) != "ref: refs/heads/main\n" {
+		t.Errorf("Expected HEAD to point at main, got %q", head)
+	}
 }
 
 func TestCheckQuillExists(t *testing.T) {
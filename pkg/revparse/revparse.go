@@ -0,0 +1,181 @@
+package revparse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// ErrUnknownRevision is returned when a revision matches no ref or object
+var ErrUnknownRevision = errors.New("unknown revision")
+
+// Resolve returns the full hash of the object a revision names.
+//
+// A revision is a base followed by any number of ancestry suffixes. The base is
+// HEAD (or "@"), a full ref such as refs/heads/main, a tag or branch name, or a
+// full or abbreviated object hash. "~n" follows the first parent n times and
+// "^n" selects the n-th parent, so HEAD~2 and HEAD^^ name the same commit.
+func Resolve(repoPath, rev string) (string, error) {
+	if rev == "" {
+		return "", fmt.Errorf("empty revision")
+	}
+
+	base, suffixes := splitRevision(rev)
+
+	hash, err := resolveBase(repoPath, base)
+	if err != nil {
+		return "", err
+	}
+
+	for len(suffixes) > 0 {
+		operator := suffixes[0]
+		suffixes = suffixes[1:]
+
+		// Read the optional count following the operator
+		digits := 0
+		for digits < len(suffixes) && suffixes[digits] >= '0' && suffixes[digits] <= '9' {
+			digits++
+		}
+
+		count := 1
+		if digits > 0 {
+			count, err = strconv.Atoi(suffixes[:digits])
+			if err != nil {
+				return "", fmt.Errorf("invalid revision %q", rev)
+			}
+		}
+		suffixes = suffixes[digits:]
+
+		if operator == '~' {
+			for i := 0; i < count; i++ {
+				hash, err = nthParent(repoPath, hash, 1, rev)
+				if err != nil {
+					return "", err
+				}
+			}
+		} else {
+			hash, err = nthParent(repoPath, hash, count, rev)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hash, nil
+}
+
+// ResolveCommit resolves a revision and checks that it names a commit, peeling annotated tags
+func ResolveCommit(repoPath, rev string) (string, error) {
+	hash, err := Resolve(repoPath, rev)
+	if err != nil {
+		return "", err
+	}
+
+	hash, objType, err := objects.PeelTag(repoPath, hash)
+	if err != nil {
+		return "", err
+	}
+
+	if objType != constants.CommitObject {
+		return "", fmt.Errorf("%s is a %s, not a commit", rev, objType)
+	}
+
+	return hash, nil
+}
+
+// splitRevision separates the base name from its trailing ancestry suffixes
+func splitRevision(rev string) (string, string) {
+	cut := strings.IndexAny(rev, "~^")
+	if cut < 0 {
+		return rev, ""
+	}
+
+	return rev[:cut], rev[cut:]
+}
+
+// resolveBase resolves the part of a revision before any ancestry suffix
+func resolveBase(repoPath, name string) (string, error) {
+	if name == "HEAD" || name == "@" {
+		hash, err := refs.ResolveHEAD(repoPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to get HEAD: %v", err)
+		}
+
+		if hash == "" {
+			return "", fmt.Errorf("HEAD does not point at a commit yet")
+		}
+		return hash, nil
+	}
+
+	// Refs take precedence over hash prefixes, as with git
+	candidates := []string{refs.TagsPrefix + name, refs.HeadsPrefix + name}
+	if strings.HasPrefix(name, "refs/") {
+		candidates = []string{name}
+	}
+
+	for _, candidate := range candidates {
+		hash, err := refs.ReadRef(repoPath, candidate)
+		if err == nil {
+			return hash, nil
+		}
+
+		if !errors.Is(err, refs.ErrRefNotFound) {
+			return "", err
+		}
+	}
+
+	if isHex(name) && len(name) >= storage.MinHashPrefix {
+		hash, err := storage.ResolveHash(repoPath, name)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotFound) {
+				return "", fmt.Errorf("%w: %s", ErrUnknownRevision, name)
+			}
+			return "", err
+		}
+		return hash, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownRevision, name)
+}
+
+// nthParent returns the n-th parent of a commit; the zeroth parent is the commit itself
+func nthParent(repoPath, hash string, n int, rev string) (string, error) {
+	// Ancestry suffixes apply to the commit an annotated tag points at
+	hash, _, err := objects.PeelTag(repoPath, hash)
+	if err != nil {
+		return "", err
+	}
+
+	if n == 0 {
+		return hash, nil
+	}
+
+	commit, err := objects.ReadCommit(repoPath, hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %v", hash, err)
+	}
+
+	parents := commit.Parents
+	if n > len(parents) {
+		return "", fmt.Errorf("%w: %s (commit %s has no parent %d)", ErrUnknownRevision, rev, objects.ShortHash(hash), n)
+	}
+
+	return parents[n-1], nil
+}
+
+// isHex reports whether s only contains lowercase or uppercase hexadecimal digits
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,140 @@
+package revparse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// commitFile writes and stages a file, then commits it, returning the commit hash
+func commitFile(t *testing.T, repoPath, name, content string) string {
+	t.Helper()
+
+	testutil.Stage(t, repoPath, name, content)
+
+	commitHash, err := objects.CreateCommit(repoPath, content, "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return commitHash
+}
+
+func TestResolve(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	first := commitFile(t, repoPath, "a.txt", "one")
+	second := commitFile(t, repoPath, "a.txt", "two")
+	third := commitFile(t, repoPath, "a.txt", "three")
+
+	err := refs.CreateBranch(repoPath, "feature", second, false)
+	if err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	err = refs.UpdateRef(repoPath, refs.TagsPrefix+"v1.0", first)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	tests := []struct {
+		rev  string
+		want string
+	}{
+		{"HEAD", third},
+		{"@", third},
+		{"HEAD^", second},
+		{"HEAD~1", second},
+		{"HEAD~2", first},
+		{"HEAD^^", first},
+		{"HEAD~0", third},
+		{"HEAD^0", third},
+		{"main", third},
+		{"refs/heads/main", third},
+		{"feature", second},
+		{"feature~", first},
+		{"v1.0", first},
+		{third, third},
+		{third[:8], third},
+		{strings.ToUpper(second[:10]) + "^", first},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rev, func(t *testing.T) {
+			got, err := Resolve(repoPath, tt.rev)
+			if err != nil {
+				t.Fatalf("Resolve(%q) failed: %v", tt.rev, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %s, want %s", tt.rev, got, tt.want)
+			}
+		})
+	}
+
+	// Walking past the root commit or naming unknown revisions fails
+	for _, rev := range []string{"HEAD~3", "HEAD^2", "missing", "deadbeef", "abc"} {
+		_, err := Resolve(repoPath, rev)
+		if !errors.Is(err, ErrUnknownRevision) {
+			t.Errorf("Resolve(%q): expected ErrUnknownRevision, got %v", rev, err)
+		}
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	// HEAD has no commit to resolve to yet
+	_, err := ResolveCommit(repoPath, "HEAD")
+	if err == nil {
+		t.Errorf("Expected an error resolving HEAD without commits")
+	}
+
+	commitHash := commitFile(t, repoPath, "a.txt", "one")
+
+	commit, err := objects.ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	got, err := ResolveCommit(repoPath, commitHash[:6])
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+
+	if got != commitHash {
+		t.Errorf("ResolveCommit = %s, want %s", got, commitHash)
+	}
+
+	// Annotated tags peel to the commit they point at
+	tagHash, err := objects.CreateTag(repoPath, "v1.0", commitHash, "Test User <test@example.com>", "Release")
+	if err != nil {
+		t.Fatalf("Failed to create tag object: %v", err)
+	}
+
+	err = refs.CreateTag(repoPath, "v1.0", tagHash, false)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	for _, rev := range []string{"v1.0", "v1.0^0", tagHash[:8]} {
+		got, err = ResolveCommit(repoPath, rev)
+		if err != nil {
+			t.Fatalf("ResolveCommit(%q) failed: %v", rev, err)
+		}
+
+		if got != commitHash {
+			t.Errorf("ResolveCommit(%q) = %s, want %s", rev, got, commitHash)
+		}
+	}
+
+	// A tree is a valid object but not a commit
+	_, err = ResolveCommit(repoPath, commit.Tree)
+	if err == nil || !strings.Contains(err.Error(), "not a commit") {
+		t.Errorf("Expected a not a commit error, got %v", err)
+	}
+}
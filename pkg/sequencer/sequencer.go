@@ -0,0 +1,391 @@
+package sequencer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// Action says what to do with the change a commit made
+type Action string
+
+const (
+	// Pick applies the change onto HEAD, keeping the commit's author and message
+	Pick Action = "pick"
+
+	// Revert applies the inverse of the change with a message saying what it reverts
+	Revert Action = "revert"
+)
+
+const (
+	// stateDir holds a cherry-pick or revert stopped by conflicts
+	stateDir = "sequencer"
+
+	// headFile records the commit HEAD was at before the first step, for --abort
+	headFile = "head"
+
+	// todoFile lists the steps left, the one stopped on first
+	todoFile = "todo"
+)
+
+// ErrNoSequence is returned when there is no stopped cherry-pick or revert to continue or abort
+var ErrNoSequence = errors.New("no cherry-pick or revert in progress")
+
+// Step is a single commit to pick or revert
+type Step struct {
+	Action Action
+	Hash   string
+}
+
+// String formats the step the way it is stored in the todo list
+func (s Step) String() string {
+	return fmt.Sprintf("%s %s", s.Action, s.Hash)
+}
+
+// Outcome describes what applying a step did
+type Outcome struct {
+	Step    Step
+	Subject string // First line of the new commit's message
+
+	// Commit is the new commit, empty when the step stopped on conflicts or changed nothing
+	Commit string
+
+	// Result is the merge of the change onto HEAD, with any conflicts left in the working tree
+	Result *merge.Result
+}
+
+// Stopped reports whether the step left conflicts to resolve
+func (o *Outcome) Stopped() bool {
+	return !o.Result.Clean()
+}
+
+// statePath returns the location of a file in the sequencer state directory
+func statePath(repoPath, name string) string {
+	return filepath.Join(repoPath, ".quill", stateDir, name)
+}
+
+// InProgress reports whether a cherry-pick or revert is waiting for conflicts to be resolved
+func InProgress(repoPath string) bool {
+	_, err := os.Stat(statePath(repoPath, todoFile))
+	return err == nil
+}
+
+// ReadState returns the commit HEAD started at and the steps left, the stopped one first
+func ReadState(repoPath string) (string, []Step, error) {
+	head, err := os.ReadFile(statePath(repoPath, headFile))
+	if os.IsNotExist(err) {
+		return "", nil, ErrNoSequence
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read sequencer state: %w", err)
+	}
+
+	todo, err := os.ReadFile(statePath(repoPath, todoFile))
+	if os.IsNotExist(err) {
+		return "", nil, ErrNoSequence
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read sequencer todo: %w", err)
+	}
+
+	var steps []Step
+	for _, line := range strings.Split(strings.TrimSpace(string(todo)), "\n") {
+		action, hash, found := strings.Cut(strings.TrimSpace(line), " ")
+		if !found {
+			continue
+		}
+		steps = append(steps, Step{Action: Action(action), Hash: hash})
+	}
+
+	return strings.TrimSpace(string(head)), steps, nil
+}
+
+// writeState records the starting commit and the steps left. Run, Continue and Abort hold the index lock
+// while they read and write the state, which keeps other commands from changing it meanwhile.
+func writeState(repoPath, head string, steps []Step) error {
+	err := os.MkdirAll(filepath.Join(repoPath, ".quill", stateDir), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create sequencer directory: %w", err)
+	}
+
+	err = lockfile.WriteFile(statePath(repoPath, headFile), []byte(head+"\n"))
+	if err != nil {
+		return fmt.Errorf("failed to write sequencer state: %w", err)
+	}
+
+	var todo strings.Builder
+	for _, step := range steps {
+		todo.WriteString(step.String() + "\n")
+	}
+
+	err = lockfile.WriteFile(statePath(repoPath, todoFile), []byte(todo.String()))
+	if err != nil {
+		return fmt.Errorf("failed to write sequencer todo: %w", err)
+	}
+
+	return nil
+}
+
+// clearState removes the record of a stopped cherry-pick or revert
+func clearState(repoPath string) error {
+	err := os.RemoveAll(filepath.Join(repoPath, ".quill", stateDir))
+	if err != nil {
+		return fmt.Errorf("failed to remove sequencer state: %w", err)
+	}
+	return nil
+}
+
+// Run applies the steps onto HEAD one commit at a time. It stops at the first step that conflicts,
+// leaving the conflicts in the working tree and the remaining steps recorded for Continue and Abort.
+// The outcomes of the steps that ran are returned, the last one telling whether the run stopped.
+func Run(repoPath string, steps []Step, committer objects.Signature) ([]*Outcome, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if InProgress(repoPath) {
+		return nil, fmt.Errorf("a cherry-pick or revert is already in progress, use --continue or --abort")
+	}
+
+	head, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	return runSteps(repoPath, head, steps, committer)
+}
+
+// Continue commits the resolved step the sequence stopped on and runs the steps after it
+func Continue(repoPath string, committer objects.Signature) ([]*Outcome, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	head, steps, err := ReadState(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, clearState(repoPath)
+	}
+
+	outcome, err := CommitStep(repoPath, steps[0], committer)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes, err := runSteps(repoPath, head, steps[1:], committer)
+	return append([]*Outcome{outcome}, outcomes...), err
+}
+
+// Abort puts HEAD, the index and the working tree back to where they were before the sequence started
+func Abort(repoPath string) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	original, _, err := ReadState(repoPath)
+	if err != nil {
+		return err
+	}
+
+	current, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return err
+	}
+
+	originalTree, err := objects.TreeOf(repoPath, original)
+	if err != nil {
+		return err
+	}
+
+	err = worktree.ResetHard(repoPath, currentTree, originalTree)
+	if err != nil {
+		return err
+	}
+
+	if original != "" {
+		err = refs.AdvanceHEAD(repoPath, current, original)
+		if err != nil {
+			return fmt.Errorf("failed to update HEAD: %w", err)
+		}
+	}
+
+	return clearState(repoPath)
+}
+
+// runSteps applies steps until one conflicts, which is recorded together with the rest
+func runSteps(repoPath, head string, steps []Step, committer objects.Signature) ([]*Outcome, error) {
+	var outcomes []*Outcome
+
+	for i, step := range steps {
+		outcome, err := ApplyStep(repoPath, step, committer)
+		if err != nil {
+			return outcomes, err
+		}
+		outcomes = append(outcomes, outcome)
+
+		if outcome.Stopped() {
+			return outcomes, writeState(repoPath, head, steps[i:])
+		}
+	}
+
+	return outcomes, clearState(repoPath)
+}
+
+// ApplyStep merges the change of a single step onto HEAD and commits it when there are no conflicts.
+// The index and working tree must not have local changes to the paths the step touches.
+func ApplyStep(repoPath string, step Step, committer objects.Signature) (*Outcome, error) {
+	result, err := MergeStep(repoPath, step)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Clean() {
+		return &Outcome{Step: step, Result: result}, nil
+	}
+
+	committed, err := CommitStep(repoPath, step, committer)
+	if err != nil {
+		return nil, err
+	}
+	committed.Result = result
+
+	return committed, nil
+}
+
+// MergeStep merges the change of a single step into HEAD, updating the index and working tree without
+// committing. Conflicts are left in both for the caller to report.
+func MergeStep(repoPath string, step Step) (*merge.Result, error) {
+	commit, err := objects.ReadCommit(repoPath, step.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(commit.Parents) > 1 {
+		return nil, fmt.Errorf("commit %s is a merge, which can't be %s", objects.ShortHash(step.Hash), pastTense(step.Action))
+	}
+
+	parentTree, err := objects.TreeOf(repoPath, commit.FirstParent())
+	if err != nil {
+		return nil, err
+	}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	label := fmt.Sprintf("%s (%s)", objects.ShortHash(step.Hash), objects.FirstLine(commit.Message))
+
+	// A revert is the same three-way merge with the commit and its parent swapped
+	baseTree, theirsTree := parentTree, commit.Tree
+	if step.Action == Revert {
+		baseTree, theirsTree = commit.Tree, parentTree
+		label = "parent of " + label
+	}
+
+	result, err := merge.MergeTrees(repoPath, baseTree, currentTree, theirsTree, merge.Labels{Ours: "HEAD", Theirs: label})
+	if err != nil {
+		return nil, err
+	}
+
+	err = merge.Apply(repoPath, currentTree, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CommitStep commits the index as the result of a step, skipping the commit when the index matches HEAD
+func CommitStep(repoPath string, step Step, committer objects.Signature) (*Outcome, error) {
+	commit, err := objects.ReadCommit(repoPath, step.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	message, author, err := stepMessage(commit, step.Action, committer)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &Outcome{Step: step, Subject: objects.FirstLine(message), Result: &merge.Result{}}
+
+	currentTree, err := objects.HeadTree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return nil, fmt.Errorf("cannot continue with unmerged paths: %s", strings.Join(conflicts, ", "))
+	}
+
+	indexTree, err := objects.WriteIndexTree(repoPath, idx.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	// The change is already in HEAD, or was committed by hand after resolving it
+	if indexTree == currentTree {
+		return outcome, nil
+	}
+
+	outcome.Commit, err = objects.CreateMergeCommit(repoPath, message, author, committer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	return outcome, nil
+}
+
+// stepMessage returns the message and author of the commit a step makes. Picks keep both from the
+// original commit, reverts are authored by the committer and say which commit they revert.
+func stepMessage(commit *objects.Commit, action Action, committer objects.Signature) (string, objects.Signature, error) {
+	if action == Revert {
+		message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", objects.FirstLine(commit.Message), commit.Hash)
+		return message, committer, nil
+	}
+
+	author, err := commit.AuthorSignature()
+	if err != nil {
+		return "", objects.Signature{}, fmt.Errorf("failed to read author of %s: %w", commit.Hash, err)
+	}
+
+	return commit.Message, author, nil
+}
+
+// pastTense names what happens to a commit in an action, for messages
+func pastTense(action Action) string {
+	if action == Revert {
+		return "reverted"
+	}
+	return "cherry-picked"
+}
@@ -0,0 +1,234 @@
+package sequencer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+var (
+	committer = objects.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+	author    = objects.Signature{Name: "Ada Lovelace", Email: "ada@example.com", When: time.Unix(1700000000, 0).UTC()}
+)
+
+// commitFile writes a file, stages it and commits it with the given author, returning the new commit
+func commitFile(t *testing.T, repoPath, name, content, message string, by objects.Signature) string {
+	t.Helper()
+
+	testutil.Stage(t, repoPath, name, content)
+
+	hash, err := objects.CreateMergeCommit(repoPath, message, by, committer, nil)
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return hash
+}
+
+// headCommit reads the commit HEAD points at
+func headCommit(t *testing.T, repoPath string) *objects.Commit {
+	t.Helper()
+
+	hash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+
+	commit, err := objects.ReadCommit(repoPath, hash)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD commit: %v", err)
+	}
+
+	return commit
+}
+
+// resetTo moves HEAD, the index and the working tree to a commit
+func resetTo(t *testing.T, repoPath, hash string) {
+	t.Helper()
+
+	target, err := objects.ReadCommit(repoPath, hash)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", hash, err)
+	}
+
+	current := headCommit(t, repoPath)
+
+	err = worktree.ResetHard(repoPath, current.Tree, target.Tree)
+	if err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+
+	err = refs.AdvanceHEAD(repoPath, current.Hash, hash)
+	if err != nil {
+		t.Fatalf("Failed to move HEAD: %v", err)
+	}
+}
+
+func TestPickAndRevert(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	base := commitFile(t, repoPath, "a.txt", "a\n", "base", committer)
+
+	feature := commitFile(t, repoPath, "b.txt", "b\n", "add b\n\nWith a body.", author)
+
+	// Go back to the base commit and pick the feature onto it
+	resetTo(t, repoPath, base)
+	testutil.AssertFile(t, repoPath, "b.txt", "")
+
+	outcomes, err := Run(repoPath, []Step{{Action: Pick, Hash: feature}}, committer)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(outcomes) != 1 || outcomes[0].Stopped() || outcomes[0].Commit == "" {
+		t.Fatalf("Expected a single committed step, got %+v", outcomes)
+	}
+
+	picked := headCommit(t, repoPath)
+	if picked.Hash != outcomes[0].Commit || picked.FirstParent() != base {
+		t.Errorf("Expected HEAD to be the pick on top of %s, got %s with parents %v", base, picked.Hash, picked.Parents)
+	}
+
+	if picked.Message != "add b\n\nWith a body." {
+		t.Errorf("Expected the pick to keep the message, got %q", picked.Message)
+	}
+
+	pickedAuthor, err := picked.AuthorSignature()
+	if err != nil || pickedAuthor.String() != author.String() || !pickedAuthor.When.Equal(author.When) {
+		t.Errorf("Expected the pick to keep the author %s, got %+v (%v)", author, pickedAuthor, err)
+	}
+
+	pickedCommitter, err := picked.CommitterSignature()
+	if err != nil || pickedCommitter.String() != committer.String() {
+		t.Errorf("Expected the pick to be committed by %s, got %+v (%v)", committer, pickedCommitter, err)
+	}
+	testutil.AssertFile(t, repoPath, "b.txt", "b\n")
+
+	// Picking it again changes nothing
+	outcomes, err = Run(repoPath, []Step{{Action: Pick, Hash: feature}}, committer)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(outcomes) != 1 || outcomes[0].Commit != "" || headCommit(t, repoPath).Hash != picked.Hash {
+		t.Errorf("Expected picking a change already in HEAD to be skipped, got %+v", outcomes)
+	}
+
+	outcomes, err = Run(repoPath, []Step{{Action: Revert, Hash: picked.Hash}}, committer)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(outcomes) != 1 || outcomes[0].Commit == "" {
+		t.Fatalf("Expected the revert to be committed, got %+v", outcomes)
+	}
+
+	reverted := headCommit(t, repoPath)
+	want := "Revert \"add b\"\n\nThis reverts commit " + picked.Hash + "."
+	if reverted.Message != want {
+		t.Errorf("Expected revert message %q, got %q", want, reverted.Message)
+	}
+
+	revertAuthor, err := reverted.AuthorSignature()
+	if err != nil || revertAuthor.String() != committer.String() {
+		t.Errorf("Expected the revert to be authored by %s, got %+v (%v)", committer, revertAuthor, err)
+	}
+	testutil.AssertFile(t, repoPath, "b.txt", "")
+
+	if InProgress(repoPath) {
+		t.Errorf("Expected no sequence to be left in progress")
+	}
+}
+
+func TestConflictContinueAndAbort(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFile(t, repoPath, "a.txt", "1\n2\n3\n", "base", committer)
+	edit := commitFile(t, repoPath, "a.txt", "1\n2\nthree\n", "edit three", committer)
+	commitFile(t, repoPath, "a.txt", "1\n2\nTHREE\n", "shout three", committer)
+	extra := commitFile(t, repoPath, "b.txt", "b\n", "add b", committer)
+	start := headCommit(t, repoPath)
+
+	steps := []Step{{Action: Revert, Hash: edit}, {Action: Revert, Hash: extra}}
+
+	outcomes, err := Run(repoPath, steps, committer)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(outcomes) != 1 || !outcomes[0].Stopped() {
+		t.Fatalf("Expected the first revert to stop on conflicts, got %+v", outcomes)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repoPath, "a.txt"))
+	if err != nil || !strings.Contains(string(data), "<<<<<<< HEAD") || !strings.Contains(string(data), ">>>>>>> parent of "+edit[:8]) {
+		t.Errorf("Expected conflict markers in a.txt, got %q (%v)", data, err)
+	}
+
+	head, remaining, err := ReadState(repoPath)
+	if err != nil || head != start.Hash || len(remaining) != 2 || remaining[0] != steps[0] || remaining[1] != steps[1] {
+		t.Errorf("Expected the state to record %s and both steps, got %s %v (%v)", start.Hash, head, remaining, err)
+	}
+
+	_, err = Run(repoPath, steps, committer)
+	if err == nil {
+		t.Errorf("Expected a second run to be refused while one is in progress")
+	}
+
+	// Continuing with the conflict unresolved fails
+	_, err = Continue(repoPath, committer)
+	if err == nil {
+		t.Errorf("Expected continue to fail with unmerged paths")
+	}
+
+	err = Abort(repoPath)
+	if err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+
+	if headCommit(t, repoPath).Hash != start.Hash || InProgress(repoPath) {
+		t.Errorf("Expected abort to return to %s and clear the state", start.Hash)
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "1\n2\nTHREE\n")
+
+	// Stop again, resolve the conflict and carry on
+	_, err = Run(repoPath, steps, committer)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	testutil.Stage(t, repoPath, "a.txt", "1\n2\n3\n")
+
+	outcomes, err = Continue(repoPath, committer)
+	if err != nil {
+		t.Fatalf("Continue failed: %v", err)
+	}
+
+	if len(outcomes) != 2 || outcomes[0].Commit == "" || outcomes[1].Commit == "" {
+		t.Fatalf("Expected both reverts to be committed, got %+v", outcomes)
+	}
+
+	last := headCommit(t, repoPath)
+	if last.Hash != outcomes[1].Commit {
+		t.Errorf("Expected HEAD to be the last revert, got %s", last.Hash)
+	}
+
+	first, err := objects.ReadCommit(repoPath, last.FirstParent())
+	if err != nil || first.Hash != outcomes[0].Commit || first.FirstParent() != start.Hash {
+		t.Errorf("Expected the reverts to be stacked on %s, got %+v (%v)", start.Hash, first, err)
+	}
+
+	testutil.AssertFile(t, repoPath, "a.txt", "1\n2\n3\n")
+	testutil.AssertFile(t, repoPath, "b.txt", "")
+
+	if InProgress(repoPath) {
+		t.Errorf("Expected the state to be cleared")
+	}
+}
@@ -0,0 +1,264 @@
+package stash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/merge"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// Parts are the commits a stash entry is made of
+type Parts struct {
+	Base      *objects.Commit // HEAD when the stash was made
+	Index     *objects.Commit
+	Working   *objects.Commit // The stash entry itself
+	Untracked *objects.Commit // Nil unless untracked files were stashed
+}
+
+// ReadParts reads the commits making up the stash entry with the given hash
+func ReadParts(repoPath, hash string) (*Parts, error) {
+	working, err := objects.ReadCommit(repoPath, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(working.Parents) < 2 || len(working.Parents) > 3 {
+		return nil, fmt.Errorf("%s is not a stash entry", hash)
+	}
+
+	parts := &Parts{Working: working}
+
+	parts.Base, err = objects.ReadCommit(repoPath, working.Parents[0])
+	if err != nil {
+		return nil, err
+	}
+
+	parts.Index, err = objects.ReadCommit(repoPath, working.Parents[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(working.Parents) == 3 {
+		parts.Untracked, err = objects.ReadCommit(repoPath, working.Parents[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
+
+// Apply reapplies the changes of stash@{n} on top of HEAD, restoring what was staged to the index and
+// the rest to the working tree. Changes HEAD has made since are merged with the stashed ones, and conflicts
+// are left in the index and the working tree for the caller to report. Paths the stash changes must not
+// have local changes, and stashed untracked files must not exist.
+func Apply(repoPath string, n int) (*merge.Result, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	hash, err := Get(repoPath, n)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := ReadParts(repoPath, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	headHash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	var headTree string
+	if headHash != "" {
+		head, err := objects.ReadCommit(repoPath, headHash)
+		if err != nil {
+			return nil, err
+		}
+		headTree = head.Tree
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return nil, fmt.Errorf("cannot apply a stash with unmerged paths: %s", strings.Join(conflicts, ", "))
+	}
+
+	trees := make(map[string]map[string]objects.TreeEntry)
+	for name, treeHash := range map[string]string{"base": parts.Base.Tree, "index": parts.Index.Tree, "working": parts.Working.Tree, "head": headTree} {
+		trees[name], err = objects.GetTreeEntries(repoPath, treeHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	base, staged, working, head := trees["base"], trees["index"], trees["working"], trees["head"]
+
+	var untracked map[string]objects.TreeEntry
+	if parts.Untracked != nil {
+		untracked, err = objects.GetTreeEntries(repoPath, parts.Untracked.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := merge.MergeTrees(repoPath, parts.Base.Tree, headTree, parts.Working.Tree, merge.Labels{Ours: "Updated upstream", Theirs: "Stashed changes"})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every path the stash touches, whether in the working tree or only in the index
+	changed := make(map[string]bool)
+	for _, entries := range []map[string]objects.TreeEntry{base, staged, head, result.Entries} {
+		for path := range entries {
+			if !sameEntry(head, result.Entries, path) || !sameEntry(base, staged, path) {
+				changed[path] = true
+			}
+		}
+	}
+
+	var blocked []string
+	for path := range changed {
+		clean, err := unchanged(repoPath, idx, head, path)
+		if err != nil {
+			return nil, err
+		}
+		if !clean {
+			blocked = append(blocked, path)
+		}
+	}
+	for path := range untracked {
+		_, _, err := worktree.HashFile(repoPath, path)
+		if err == nil {
+			blocked = append(blocked, path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if len(blocked) > 0 {
+		sort.Strings(blocked)
+		return nil, fmt.Errorf("your local changes to the following files would be overwritten by applying the stash:\n\t%s\nCommit your changes or stash them first", strings.Join(blocked, "\n\t"))
+	}
+
+	err = merge.Apply(repoPath, headTree, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// The merge staged everything, put back what was only in the working tree
+	idx, err = index.LoadIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index: %w", err)
+	}
+
+	for path := range changed {
+		if _, conflicted := result.Conflicts[path]; conflicted {
+			continue
+		}
+
+		restored, restoredExists := result.Entries[path]
+		switch {
+		case sameEntry(base, staged, path):
+			// Not staged when stashed
+			restored, restoredExists = head[path]
+		case sameEntry(staged, working, path):
+			// Staged as it was in the working tree, keep the merged version
+		case sameEntry(base, head, path):
+			// Staged, then changed again in the working tree
+			restored, restoredExists = staged[path]
+		}
+
+		if !restoredExists {
+			delete(idx.Entries, path)
+			continue
+		}
+
+		current, inHead := head[path]
+		idx.Entries[path] = index.IndexEntry{
+			Path:   path,
+			Hash:   restored.Hash,
+			Mode:   restored.Mode,
+			Staged: !inHead || current.Hash != restored.Hash || current.Mode != restored.Mode,
+		}
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+
+	for path, entry := range untracked {
+		err = worktree.WriteFile(repoPath, path, entry.Hash, entry.Mode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Drop removes stash@{n} from the stack and returns its hash
+func Drop(repoPath string, n int) (string, error) {
+	var dropped string
+
+	err := updateList(repoPath, func(entries []string) ([]string, error) {
+		if n < 0 || n >= len(entries) {
+			return nil, fmt.Errorf("%w: stash@{%d}", ErrNoEntry, n)
+		}
+
+		dropped = entries[n]
+		return append(entries[:n], entries[n+1:]...), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return dropped, nil
+}
+
+// sameEntry reports whether a path has the same content and mode, or is missing, in both trees
+func sameEntry(a, b map[string]objects.TreeEntry, path string) bool {
+	aEntry, inA := a[path]
+	bEntry, inB := b[path]
+
+	if inA != inB {
+		return false
+	}
+
+	return !inA || (aEntry.Hash == bEntry.Hash && aEntry.Mode == bEntry.Mode)
+}
+
+// unchanged reports whether a path is the same in the index and the working tree as in HEAD
+func unchanged(repoPath string, idx *index.Index, head map[string]objects.TreeEntry, path string) (bool, error) {
+	headEntry, inHead := head[path]
+	indexEntry, inIndex := idx.Entries[path]
+
+	if inHead != inIndex || (inHead && (headEntry.Hash != indexEntry.Hash || headEntry.Mode != indexEntry.Mode)) {
+		return false, nil
+	}
+
+	fileHash, mode, err := worktree.HashFile(repoPath, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return !inHead, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return inHead && fileHash == headEntry.Hash && mode == headEntry.Mode, nil
+}
@@ -0,0 +1,301 @@
+package stash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/ignore"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/lockfile"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/storage"
+	"github.com/tejastn10/quill/pkg/worktree"
+)
+
+// A stash entry is a commit whose tree holds the tracked files as they were in the working tree.
+// Its parents are the HEAD commit the stash was made on, a commit of the index and, when untracked
+// files were stashed, a root commit holding just those files.
+const (
+	// Ref points at the most recent stash entry
+	Ref = "refs/stash"
+
+	// logFile lists every stash entry newest first, since the ref alone only reaches the top of the stack
+	logFile = "logs/refs/stash"
+)
+
+var (
+	// ErrNoChanges is returned by Push when there is nothing to stash
+	ErrNoChanges = errors.New("no local changes to save")
+
+	// ErrNoEntry is returned when a stash entry doesn't exist
+	ErrNoEntry = errors.New("no such stash entry")
+)
+
+// logPath returns the location of the stash stack
+func logPath(repoPath string) string {
+	return filepath.Join(repoPath, ".quill", filepath.FromSlash(logFile))
+}
+
+// List returns the hashes of the stash entries, stash@{0} first
+func List(repoPath string) ([]string, error) {
+	data, err := os.ReadFile(logPath(repoPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read stash list: %w", err)
+	}
+
+	return strings.Fields(string(data)), nil
+}
+
+// Get returns the hash of stash@{n}
+func Get(repoPath string, n int) (string, error) {
+	entries, err := List(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	if n < 0 || n >= len(entries) {
+		return "", fmt.Errorf("%w: stash@{%d}", ErrNoEntry, n)
+	}
+
+	return entries[n], nil
+}
+
+// updateList changes the stash stack while holding the lock of its list, so entries another process pushes
+// or drops meanwhile aren't lost. refs/stash is moved to the new top, and both are removed once the stack is empty.
+func updateList(repoPath string, change func(entries []string) ([]string, error)) error {
+	err := os.MkdirAll(filepath.Dir(logPath(repoPath)), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for stash list: %w", err)
+	}
+
+	lock, err := lockfile.Acquire(logPath(repoPath))
+	if err != nil {
+		return err
+	}
+	defer lock.Rollback()
+
+	entries, err := List(repoPath)
+	if err != nil {
+		return err
+	}
+
+	top, err := refs.ReadRef(repoPath, Ref)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return err
+	}
+
+	entries, err = change(entries)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		err := os.Remove(logPath(repoPath))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stash list: %w", err)
+		}
+
+		err = refs.DeleteRef(repoPath, Ref)
+		if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	err = lock.Write([]byte(strings.Join(entries, "\n") + "\n"))
+	if err == nil {
+		err = lock.Commit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write stash list: %w", err)
+	}
+
+	return refs.UpdateRefFrom(repoPath, Ref, top, entries[0])
+}
+
+// Push records the index and the working tree changes to tracked files as a new stash@{0} and
+// resets both to HEAD. With includeUntracked, untracked files that aren't ignored are stashed and
+// removed as well. An empty message describes the entry by the commit it was made on.
+func Push(repoPath, message string, includeUntracked bool, author, committer objects.Signature) (string, error) {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	headHash, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	if headHash == "" {
+		return "", fmt.Errorf("cannot stash before the initial commit")
+	}
+
+	head, err := objects.ReadCommit(repoPath, headHash)
+	if err != nil {
+		return "", err
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load index: %w", err)
+	}
+
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return "", fmt.Errorf("cannot stash with unmerged paths: %s", strings.Join(conflicts, ", "))
+	}
+
+	indexTree, err := objects.WriteIndexTree(repoPath, idx.Entries)
+	if err != nil {
+		return "", fmt.Errorf("failed to write index tree: %w", err)
+	}
+
+	// The working tree version of every tracked file, leaving out deleted ones
+	working := make(map[string]index.IndexEntry, len(idx.Entries))
+	for path := range idx.Entries {
+		entry, exists, err := storeWorkingFile(repoPath, path)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			working[path] = entry
+		}
+	}
+
+	workingTree, err := objects.WriteIndexTree(repoPath, working)
+	if err != nil {
+		return "", fmt.Errorf("failed to write working tree: %w", err)
+	}
+
+	var untracked []string
+	if includeUntracked {
+		untracked, err = untrackedFiles(repoPath, idx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if indexTree == head.Tree && workingTree == head.Tree && len(untracked) == 0 {
+		return "", ErrNoChanges
+	}
+
+	branch, err := refs.CurrentBranch(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if branch == "" {
+		branch = "(no branch)"
+	}
+
+	onCommit := fmt.Sprintf("%s: %s %s", branch, objects.ShortHash(headHash), objects.FirstLine(head.Message))
+	if message == "" {
+		message = "WIP on " + onCommit
+	} else {
+		message = fmt.Sprintf("On %s: %s", branch, message)
+	}
+
+	indexCommit, err := objects.CommitTree(repoPath, indexTree, []string{headHash}, "index on "+onCommit, author, committer)
+	if err != nil {
+		return "", err
+	}
+	parents := []string{headHash, indexCommit}
+
+	if len(untracked) > 0 {
+		entries := make(map[string]index.IndexEntry, len(untracked))
+		for _, path := range untracked {
+			entries[path], _, err = storeWorkingFile(repoPath, path)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		untrackedTree, err := objects.WriteIndexTree(repoPath, entries)
+		if err != nil {
+			return "", fmt.Errorf("failed to write untracked files tree: %w", err)
+		}
+
+		untrackedCommit, err := objects.CommitTree(repoPath, untrackedTree, nil, "untracked files on "+onCommit, author, committer)
+		if err != nil {
+			return "", err
+		}
+		parents = append(parents, untrackedCommit)
+	}
+
+	stashCommit, err := objects.CommitTree(repoPath, workingTree, parents, message, author, committer)
+	if err != nil {
+		return "", err
+	}
+
+	err = updateList(repoPath, func(entries []string) ([]string, error) {
+		return append([]string{stashCommit}, entries...), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Everything is saved, so the local changes can go
+	err = worktree.ResetHard(repoPath, head.Tree, head.Tree)
+	if err != nil {
+		return "", err
+	}
+
+	for _, path := range untracked {
+		err = worktree.RemoveFile(repoPath, path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return stashCommit, nil
+}
+
+// storeWorkingFile stores the working tree version of a file as a blob and returns its index entry
+func storeWorkingFile(repoPath, path string) (index.IndexEntry, bool, error) {
+	data, err := worktree.ReadFile(repoPath, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index.IndexEntry{}, false, nil
+	}
+	if err != nil {
+		return index.IndexEntry{}, false, err
+	}
+
+	info, err := os.Stat(filepath.Join(repoPath, path))
+	if err != nil {
+		return index.IndexEntry{}, false, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	blobHash, err := storage.CreateObject(repoPath, constants.BlobObject, data)
+	if err != nil {
+		return index.IndexEntry{}, false, fmt.Errorf("failed to store %q: %w", path, err)
+	}
+
+	return index.IndexEntry{Path: path, Hash: blobHash, Mode: worktree.FileMode(info)}, true, nil
+}
+
+// untrackedFiles returns the sorted files that are neither tracked nor ignored
+func untrackedFiles(repoPath string, idx *index.Index) ([]string, error) {
+	files, err := worktree.ListFiles(repoPath, ignore.NewMatcher(repoPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan working tree: %w", err)
+	}
+
+	var untracked []string
+	for path := range files {
+		if _, tracked := idx.Entries[path]; !tracked {
+			untracked = append(untracked, path)
+		}
+	}
+	sort.Strings(untracked)
+
+	return untracked, nil
+}
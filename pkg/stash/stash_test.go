@@ -0,0 +1,199 @@
+package stash
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+var signature = objects.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+
+// commit records the staged files
+func commit(t *testing.T, repoPath, message string) {
+	t.Helper()
+
+	_, err := objects.CreateCommit(repoPath, message, signature.String())
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+}
+
+// indexHash returns the hash the index records for a path, or an empty string when it isn't tracked
+func indexHash(t *testing.T, repoPath, name string) string {
+	t.Helper()
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	return idx.Entries[name].Hash
+}
+
+func TestPushAndApply(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, "a.txt", "a\n")
+	testutil.Stage(t, repoPath, "b.txt", "b\n")
+	commit(t, repoPath, "base")
+	committedA := indexHash(t, repoPath, "a.txt")
+
+	// A staged change with more on top, an unstaged change, a new file and an untracked file
+	testutil.Stage(t, repoPath, "a.txt", "a\nstaged\n")
+	stagedA := indexHash(t, repoPath, "a.txt")
+	testutil.WriteFile(t, repoPath, "a.txt", "a\nstaged\nunstaged\n")
+	testutil.WriteFile(t, repoPath, "b.txt", "b\nunstaged\n")
+	testutil.Stage(t, repoPath, "new.txt", "new\n")
+	testutil.WriteFile(t, repoPath, "untracked.txt", "untracked\n")
+
+	hash, err := Push(repoPath, "work in progress", true, signature, signature)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	entries, err := List(repoPath)
+	if err != nil || len(entries) != 1 || entries[0] != hash {
+		t.Fatalf("Expected a single stash entry %s, got %v (%v)", hash, entries, err)
+	}
+
+	top, err := refs.ReadRef(repoPath, Ref)
+	if err != nil || top != hash {
+		t.Errorf("Expected %s to point at the new entry, got %q (%v)", Ref, top, err)
+	}
+
+	// The working tree and index are back at HEAD
+	testutil.AssertFile(t, repoPath, "a.txt", "a\n")
+	testutil.AssertFile(t, repoPath, "b.txt", "b\n")
+	testutil.AssertFile(t, repoPath, "new.txt", "")
+	testutil.AssertFile(t, repoPath, "untracked.txt", "")
+
+	if indexHash(t, repoPath, "a.txt") != committedA || indexHash(t, repoPath, "new.txt") != "" {
+		t.Errorf("Expected the index to be reset to HEAD")
+	}
+
+	_, err = Push(repoPath, "", false, signature, signature)
+	if !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected a clean tree to have nothing to stash, got %v", err)
+	}
+
+	result, err := Apply(repoPath, 0)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if !result.Clean() {
+		t.Fatalf("Expected a clean apply, got %v", result.Messages)
+	}
+
+	testutil.AssertFile(t, repoPath, "a.txt", "a\nstaged\nunstaged\n")
+	testutil.AssertFile(t, repoPath, "b.txt", "b\nunstaged\n")
+	testutil.AssertFile(t, repoPath, "new.txt", "new\n")
+	testutil.AssertFile(t, repoPath, "untracked.txt", "untracked\n")
+
+	// What was staged is staged again, what wasn't is only in the working tree
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if idx.Entries["a.txt"].Hash != stagedA || !idx.Entries["a.txt"].Staged {
+		t.Errorf("Expected the staged version of a.txt to be restored, got %+v", idx.Entries["a.txt"])
+	}
+
+	if idx.Entries["b.txt"].Staged {
+		t.Errorf("Expected b.txt to stay unstaged, got %+v", idx.Entries["b.txt"])
+	}
+
+	if _, tracked := idx.Entries["new.txt"]; !tracked {
+		t.Errorf("Expected new.txt to be staged again")
+	}
+
+	if _, tracked := idx.Entries["untracked.txt"]; tracked {
+		t.Errorf("Expected untracked.txt to stay untracked")
+	}
+
+	// The entry is kept until it is dropped
+	dropped, err := Drop(repoPath, 0)
+	if err != nil || dropped != hash {
+		t.Errorf("Expected to drop %s, got %q (%v)", hash, dropped, err)
+	}
+
+	if refs.RefExists(repoPath, Ref) {
+		t.Errorf("Expected %s to be removed with the last entry", Ref)
+	}
+
+	_, err = Drop(repoPath, 0)
+	if !errors.Is(err, ErrNoEntry) {
+		t.Errorf("Expected dropping from an empty stack to fail, got %v", err)
+	}
+}
+
+func TestStack(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, "a.txt", "a\n")
+	commit(t, repoPath, "base")
+
+	testutil.WriteFile(t, repoPath, "a.txt", "first\n")
+	first, err := Push(repoPath, "", false, signature, signature)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	testutil.WriteFile(t, repoPath, "a.txt", "second\n")
+	second, err := Push(repoPath, "", false, signature, signature)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	entries, err := List(repoPath)
+	if err != nil || len(entries) != 2 || entries[0] != second || entries[1] != first {
+		t.Fatalf("Expected the newest entry first, got %v (%v)", entries, err)
+	}
+
+	// Local changes to a path the stash touches block the apply
+	testutil.WriteFile(t, repoPath, "a.txt", "local\n")
+	_, err = Apply(repoPath, 1)
+	if err == nil {
+		t.Errorf("Expected local changes to block the apply")
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "local\n")
+
+	// A conflicting commit leaves conflict markers and the entry in place
+	testutil.Stage(t, repoPath, "a.txt", "committed\n")
+	commit(t, repoPath, "conflicting")
+
+	result, err := Apply(repoPath, 1)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if result.Clean() {
+		t.Errorf("Expected a conflict")
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if conflicts := idx.Conflicts(); len(conflicts) != 1 || conflicts[0] != "a.txt" {
+		t.Errorf("Expected a.txt to be conflicted, got %v", conflicts)
+	}
+
+	// Dropping an older entry keeps the newer one on top
+	_, err = Drop(repoPath, 1)
+	if err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+
+	top, err := refs.ReadRef(repoPath, Ref)
+	if err != nil || top != second {
+		t.Errorf("Expected %s to still point at %s, got %q (%v)", Ref, second, top, err)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tejastn10/quill/pkg/pack"
+)
+
+// packCache keeps the opened packs of the last pack directory used, since loading indexes on every read is slow
+var packCache struct {
+	sync.Mutex
+	dir   string
+	names []string
+	packs []*pack.Pack
+}
+
+// PackDir returns the directory holding the packs of a repository
+func PackDir(repoPath string) string {
+	return filepath.Join(repoPath, ".quill", "objects", "pack")
+}
+
+// Packs returns the packs of a repository, reloading them whenever the set of pack indexes changes
+func Packs(repoPath string) ([]*pack.Pack, error) {
+	dir := PackDir(repoPath)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pack directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name, isIndex := strings.CutSuffix(entry.Name(), pack.IndexExt); isIndex && strings.HasPrefix(name, "pack-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	packCache.Lock()
+	defer packCache.Unlock()
+
+	if packCache.dir == dir && slices.Equal(packCache.names, names) {
+		return packCache.packs, nil
+	}
+
+	packs := make([]*pack.Pack, 0, len(names))
+	for _, name := range names {
+		p, err := pack.Open(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+
+	packCache.dir, packCache.names, packCache.packs = dir, names, packs
+	return packs, nil
+}
+
+// findPacked returns the pack holding an object
+func findPacked(repoPath, hash string) (*pack.Pack, bool) {
+	packs, err := Packs(repoPath)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, p := range packs {
+		if p.Contains(hash) {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// ListPackedObjects returns the hashes of all objects stored in packs, sorted
+func ListPackedObjects(repoPath string) ([]string, error) {
+	packs, err := Packs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make(map[string]bool)
+	for _, p := range packs {
+		for _, hash := range p.Hashes() {
+			unique[hash] = true
+		}
+	}
+
+	hashes := make([]string, 0, len(unique))
+	for hash := range unique {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	return hashes, nil
+}
+
+// RemovePack deletes a pack, its index first so readers stop looking in it
+func RemovePack(repoPath, name string) error {
+	dir := PackDir(repoPath)
+
+	for _, ext := range []string{pack.IndexExt, pack.PackExt} {
+		err := os.Remove(filepath.Join(dir, name+ext))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove pack %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
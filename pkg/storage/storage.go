@@ -1,62 +1,386 @@
 package storage
 
 import (
+	"bytes"
+	"compress/zlib"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tejastn10/quill/pkg/constants"
 	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/lockfile"
 	"github.com/tejastn10/quill/pkg/repo"
 )
 
-// Writing a file's contents as a blob in the .quill/objects directory.
-func CreateObject(repoPath string, hash string, data []byte) error {
-	// Constructing object path: .quill/objects/<first_two_hash_chars>/<rest_of_hash>
-	objectDir := filepath.Join(repoPath, ".quill", "objects", hash[:2])
-	objectPath := filepath.Join(objectDir, hash[2:])
+// MinHashPrefix is the shortest abbreviated hash accepted when resolving objects
+const MinHashPrefix = 4
 
-	_, err := os.Stat(objectPath)
-	// Object already exists
-	if err == nil {
-		return nil
+// ErrObjectNotFound is returned when no object matches a hash
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrHashMismatch is returned when the content of an object doesn't hash to its name
+var ErrHashMismatch = errors.New("hash mismatch")
+
+// VerifyOnRead makes ReadObject re-hash every object it reads and fail on a mismatch
+var VerifyOnRead = false
+
+// hashers caches the hasher of each repository by path. The object format is fixed when a repository is
+// created, so the config only needs reading once rather than for every object hashed.
+var (
+	hashers      = make(map[string]hash.Hasher)
+	hashersMutex sync.Mutex
+)
+
+// ObjectHasher returns the hasher for the object format recorded in the repository config
+func ObjectHasher(repoPath string) (hash.Hasher, error) {
+	hashersMutex.Lock()
+	defer hashersMutex.Unlock()
+
+	if hasher, cached := hashers[repoPath]; cached {
+		return hasher, nil
+	}
+
+	format, err := repo.ReadObjectFormat(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := hash.NewHasher(format)
+	if err != nil {
+		return nil, err
+	}
+
+	hashers[repoPath] = hasher
+	return hasher, nil
+}
+
+// HashObject computes the hash of an object from its typed header and content
+func HashObject(hasher hash.Hasher, objType string, data []byte) string {
+	return hasher.Sum(append(objectHeader(objType, data), data...))
+}
+
+// objectHeader returns the "<type> <size>\x00" header stored in front of object content
+func objectHeader(objType string, data []byte) []byte {
+	return []byte(fmt.Sprintf("%s %d\x00", objType, len(data)))
+}
+
+// objectPath returns the location of a loose object: .quill/objects/<first_two_hash_chars>/<rest_of_hash>
+func objectPath(repoPath, hash string) (string, error) {
+	if len(hash) < 3 {
+		return "", fmt.Errorf("invalid object hash %q", hash)
+	}
+
+	return filepath.Join(repoPath, ".quill", "objects", hash[:2], hash[2:]), nil
+}
+
+// CreateObject stores content as a zlib compressed object of the given type and returns its hash.
+func CreateObject(repoPath string, objType string, data []byte) (string, error) {
+	hasher, err := ObjectHasher(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	objectHash := HashObject(hasher, objType, data)
+
+	// Object already exists, loose or packed
+	if ObjectExists(repoPath, objectHash) {
+		return objectHash, nil
+	}
+
+	return WriteLooseObject(repoPath, objType, data)
+}
+
+// WriteLooseObject stores an object as a loose file even when a pack already holds it, returning its hash
+func WriteLooseObject(repoPath string, objType string, data []byte) (string, error) {
+	hasher, err := ObjectHasher(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	objectHash := HashObject(hasher, objType, data)
+
+	path, err := objectPath(repoPath, objectHash)
+	if err != nil {
+		return "", err
 	}
 
 	// Creating the subdirectory if it doesn't exist
-	err = os.Mkdir(objectDir, constants.DirectoryPerms) // Secure directory permissions
+	err = os.Mkdir(filepath.Dir(path), constants.DirectoryPerms) // Secure directory permissions
 	if err != nil && !os.IsExist(err) {
-		return fmt.Errorf("failed to create object directory: %v", err)
+		return "", fmt.Errorf("failed to create object directory: %v", err)
 	}
 
-	// Writing the blob
-	err = os.WriteFile(objectPath, data, constants.ConfigFilePerms) // Secure file permissions
+	// Compressing the header and content
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+
+	_, err = writer.Write(objectHeader(objType, data))
+	if err == nil {
+		_, err = writer.Write(data)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to write object: %v", err)
+		return "", fmt.Errorf("failed to compress object: %v", err)
 	}
 
-	return nil
+	// Writing the object through a temporary file so a crash never leaves a truncated object behind
+	err = lockfile.WriteAtomic(path, buffer.Bytes(), constants.ConfigFilePerms) // Secure file permissions
+	if err != nil {
+		return "", fmt.Errorf("failed to write object: %v", err)
+	}
+
+	return objectHash, nil
 }
 
+// ObjectExists reports whether an object is stored, either loose or in a pack
 func ObjectExists(repoPath string, hash string) bool {
-	objectPath := filepath.Join(repoPath, ".quill", "objects", hash[:2], hash[2:])
-	_, err := os.Stat(objectPath)
-	return err == nil
+	path, err := objectPath(repoPath, hash)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return true
+	}
+
+	_, found := findPacked(repoPath, hash)
+	return found
+}
+
+// StatObject returns the file info of a loose object, which gives its on-disk size and when it was written
+func StatObject(repoPath, hash string) (os.FileInfo, error) {
+	path, err := objectPath(repoPath, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object %s: %w", hash, err)
+	}
+
+	return info, nil
 }
 
-func ReadObject(repoPath, hash string) ([]byte, error) {
-	objectPath := filepath.Join(repoPath, ".quill", "objects", hash[:2], hash[2:])
+// RemoveObject deletes a loose object, and its fan-out directory once it is empty
+func RemoveObject(repoPath, hash string) error {
+	path, err := objectPath(repoPath, hash)
+	if err != nil {
+		return err
+	}
 
-	cleanPath := filepath.Clean(objectPath)
+	err = os.Remove(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove object %s: %w", hash, err)
+	}
+
+	// Fails harmlessly while other objects share the directory
+	_ = os.Remove(filepath.Dir(path))
+
+	return nil
+}
+
+// ListObjects returns the hashes of all loose objects, sorted.
+// Leftover temporary files and other names that aren't hashes are skipped.
+func ListObjects(repoPath string) ([]string, error) {
+	objectsDir := filepath.Join(repoPath, ".quill", "objects")
+
+	dirs, err := os.ReadDir(objectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read object directory: %w", err)
+	}
+
+	var hashes []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 || !isHex(dir.Name()) {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(objectsDir, dir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && isHex(entry.Name()) {
+				hashes = append(hashes, dir.Name()+entry.Name())
+			}
+		}
+	}
+
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// isHex reports whether s is a non-empty lowercase hexadecimal string
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ResolveHash expands an abbreviated object hash to the full hash of the single object it matches
+func ResolveHash(repoPath, prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+
+	if len(prefix) < MinHashPrefix {
+		return "", fmt.Errorf("hash prefix %q is too short, use at least %d characters", prefix, MinHashPrefix)
+	}
+
+	if !isHex(prefix) {
+		return "", fmt.Errorf("%q is not a valid object hash", prefix)
+	}
+
+	// Scan the fan-out directory for names starting with the rest of the prefix
+	objectDir := filepath.Join(repoPath, ".quill", "objects", prefix[:2])
+	entries, err := os.ReadDir(objectDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read object directory: %w", err)
+	}
+
+	unique := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix[2:]) {
+			unique[prefix[:2]+entry.Name()] = true
+		}
+	}
+
+	packs, err := Packs(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range packs {
+		for _, match := range p.HashesWithPrefix(prefix) {
+			unique[match] = true
+		}
+	}
+
+	matches := make([]string, 0, len(unique))
+	for match := range unique {
+		matches = append(matches, match)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrObjectNotFound, prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", fmt.Errorf("short hash %s is ambiguous, candidates are:\n\t%s", prefix, strings.Join(matches, "\n\t"))
+	}
+}
+
+// ReadObject reads and decompresses an object, returning its type and content.
+// The content is checked against the hash when VerifyOnRead is set.
+func ReadObject(repoPath, hash string) (string, []byte, error) {
+	if VerifyOnRead {
+		return ReadVerifiedObject(repoPath, hash)
+	}
+
+	return readObject(repoPath, hash)
+}
+
+// ReadVerifiedObject reads an object like ReadObject and checks that its content hashes to the requested hash
+func ReadVerifiedObject(repoPath, hash string) (string, []byte, error) {
+	objType, data, err := readObject(repoPath, hash)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hasher, err := ObjectHasher(repoPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	actual := HashObject(hasher, objType, data)
+	if actual != hash {
+		return "", nil, fmt.Errorf("%w: object %s hashes to %s", ErrHashMismatch, hash, actual)
+	}
+
+	return objType, data, nil
+}
+
+// readObject reads and decompresses the object file for a hash
+func readObject(repoPath, hash string) (string, []byte, error) {
+	path, err := objectPath(repoPath, hash)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanPath := filepath.Clean(path)
 
 	if !repo.IsPathSafe(cleanPath) {
-		return nil, fmt.Errorf("invalid file path: potential directory traversal attempt")
+		return "", nil, fmt.Errorf("invalid file path: potential directory traversal attempt")
+	}
+	compressed, err := os.ReadFile(cleanPath)
+	if os.IsNotExist(err) {
+		// Not loose, so it has to be packed
+		p, found := findPacked(repoPath, hash)
+		if !found {
+			return "", nil, fmt.Errorf("failed to read object: %w: %s", ErrObjectNotFound, hash)
+		}
+		return p.Read(hash)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read object: %w", err)
 	}
-	data, err := os.ReadFile(cleanPath)
+
+	return decodeObject(compressed)
+}
+
+// decodeObject decompresses a stored object and splits it into its type and content
+func decodeObject(compressed []byte) (string, []byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read object: %w", err)
+		return "", nil, fmt.Errorf("corrupt object: %w", err)
 	}
-	return data, nil
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("corrupt object: %w", err)
+	}
+
+	// Parse the "<type> <size>\x00" header
+	header, content, found := bytes.Cut(raw, []byte{0})
+	if !found {
+		return "", nil, fmt.Errorf("corrupt object: missing header")
+	}
+
+	objType, sizeField, found := strings.Cut(string(header), " ")
+	if !found || objType == "" {
+		return "", nil, fmt.Errorf("corrupt object: malformed header %q", header)
+	}
+
+	size, err := strconv.Atoi(sizeField)
+	if err != nil || size != len(content) {
+		return "", nil, fmt.Errorf("corrupt object: header declares %q bytes but found %d", sizeField, len(content))
+	}
+
+	return objType, content, nil
 }
 
 func WriteTree(repoPath string) (string, error) {
@@ -97,10 +421,9 @@ func WriteTree(repoPath string) (string, error) {
 			if err != nil {
 				return err
 			}
-			objectHash = hash.ComputeSHA256(data)
 
 			// Store as a blob
-			err = CreateObject(repoPath, objectHash, data)
+			objectHash, err = CreateObject(repoPath, constants.BlobObject, data)
 			if err != nil {
 				return err
 			}
@@ -119,10 +442,9 @@ func WriteTree(repoPath string) (string, error) {
 
 	// Serialize tree contents
 	treeData := strings.Join(entries, "\n")
-	treeHash := hash.ComputeSHA256([]byte(treeData))
 
 	// Store tree object
-	err = CreateObject(repoPath, treeHash, []byte(treeData))
+	treeHash, err := CreateObject(repoPath, constants.TreeObject, []byte(treeData))
 	if err != nil {
 		return "", err
 	}
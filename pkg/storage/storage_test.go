@@ -1,42 +1,261 @@
 package storage
 
 import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/hash"
+	"github.com/tejastn10/quill/pkg/repo"
 )
 
 func TestStorageFunctions(t *testing.T) {
 	// Create a temporary directory to act as the repository
-	tempDir := t.TempDir()
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
 
 	// Create the .quill directory
-	err := os.MkdirAll(filepath.Join(tempDir, ".quill", "objects"), os.ModePerm)
+	err = os.MkdirAll(filepath.Join(tempDir, ".quill", "objects"), os.ModePerm)
 	if err != nil {
 		t.Fatalf("Failed to create .quill directory: %v", err)
 	}
 
+	// Reading objects requires running inside the repository
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+
+	defer func() {
+		if err := os.Chdir(originalDir); err != nil {
+			t.Errorf("Failed to restore original directory: %v", err)
+		}
+	}()
+
 	// Initialize test data
 	repoPath := tempDir
-	hash := "abcd1234efgh5678ijkl9101mnopqrst"
 	data := []byte("This is a test blob")
+	expectedHash := hash.ComputeSHA256([]byte("blob 19\x00This is a test blob"))
 
 	// Test CreateObject
 	t.Run("CreateObject", func(t *testing.T) {
-		err := CreateObject(repoPath, hash, data)
+		objectHash, err := CreateObject(repoPath, constants.BlobObject, data)
 		if err != nil {
 			t.Fatalf("CreateObject failed: %v", err)
 		}
 
-		// Verify the object file was created with the correct content
-		objectPath := filepath.Join(repoPath, ".quill", "objects", hash[:2], hash[2:])
-		content, err := os.ReadFile(objectPath)
+		// The hash covers the typed header as well as the content
+		if objectHash != expectedHash {
+			t.Errorf("Object hash mismatch: got %s, want %s", objectHash, expectedHash)
+		}
+
+		// Verify the object file was created with the compressed header and content
+		objectPath := filepath.Join(repoPath, ".quill", "objects", objectHash[:2], objectHash[2:])
+		compressed, err := os.ReadFile(objectPath)
 		if err != nil {
 			t.Fatalf("Failed to read object file: %v", err)
 		}
 
+		reader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("Object is not zlib compressed: %v", err)
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to decompress object: %v", err)
+		}
+
+		if string(content) != "blob 19\x00This is a test blob" {
+			t.Errorf("Object content mismatch: got %q", content)
+		}
+	})
+
+	// Test ReadObject
+	t.Run("ReadObject", func(t *testing.T) {
+		objType, content, err := ReadObject(repoPath, expectedHash)
+		if err != nil {
+			t.Fatalf("ReadObject failed: %v", err)
+		}
+
+		if objType != constants.BlobObject {
+			t.Errorf("Object type mismatch: got %q, want %q", objType, constants.BlobObject)
+		}
+
 		if string(content) != string(data) {
 			t.Errorf("Object content mismatch: got %q, want %q", content, data)
 		}
 	})
+
+	// Test reading an object whose header doesn't match its content
+	t.Run("CorruptObject", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := zlib.NewWriter(&buffer)
+		_, _ = writer.Write([]byte("blob 100\x00short"))
+		_ = writer.Close()
+
+		corruptHash := "ff" + expectedHash[2:]
+		err := os.MkdirAll(filepath.Join(repoPath, ".quill", "objects", "ff"), os.ModePerm)
+		if err != nil {
+			t.Fatalf("Failed to create object directory: %v", err)
+		}
+
+		err = os.WriteFile(filepath.Join(repoPath, ".quill", "objects", "ff", corruptHash[2:]), buffer.Bytes(), 0600)
+		if err != nil {
+			t.Fatalf("Failed to write corrupt object: %v", err)
+		}
+
+		_, _, err = ReadObject(repoPath, corruptHash)
+		if err == nil {
+			t.Errorf("Expected ReadObject to reject an object with a wrong size")
+		}
+	})
+
+	// Test resolving abbreviated hashes
+	t.Run("ResolveHash", func(t *testing.T) {
+		resolved, err := ResolveHash(repoPath, expectedHash[:8])
+		if err != nil {
+			t.Fatalf("ResolveHash failed: %v", err)
+		}
+
+		if resolved != expectedHash {
+			t.Errorf("Resolved hash mismatch: got %s, want %s", resolved, expectedHash)
+		}
+
+		_, err = ResolveHash(repoPath, expectedHash[:MinHashPrefix-1])
+		if err == nil {
+			t.Errorf("Expected ResolveHash to reject a prefix shorter than %d characters", MinHashPrefix)
+		}
+
+		_, err = ResolveHash(repoPath, "0000000")
+		if !errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("Expected ErrObjectNotFound for an unknown prefix, got %v", err)
+		}
+
+		// Two objects sharing a prefix make it ambiguous
+		sibling := expectedHash[:4] + strings.Repeat("0", len(expectedHash)-4)
+		if sibling == expectedHash {
+			sibling = expectedHash[:4] + strings.Repeat("1", len(expectedHash)-4)
+		}
+
+		err = os.WriteFile(filepath.Join(repoPath, ".quill", "objects", sibling[:2], sibling[2:]), nil, 0600)
+		if err != nil {
+			t.Fatalf("Failed to write sibling object: %v", err)
+		}
+
+		_, err = ResolveHash(repoPath, expectedHash[:4])
+		if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+			t.Errorf("Expected an ambiguity error, got %v", err)
+		}
+	})
+
+	// Test that objects are hashed with the repository's object format
+	t.Run("VerifyObject", func(t *testing.T) {
+		// A valid object stored under the wrong name still decodes
+		stored, err := os.ReadFile(filepath.Join(repoPath, ".quill", "objects", expectedHash[:2], expectedHash[2:]))
+		if err != nil {
+			t.Fatalf("Failed to read object: %v", err)
+		}
+
+		wrongHash := "ee" + expectedHash[2:]
+		err = os.MkdirAll(filepath.Join(repoPath, ".quill", "objects", "ee"), os.ModePerm)
+		if err != nil {
+			t.Fatalf("Failed to create object directory: %v", err)
+		}
+
+		err = os.WriteFile(filepath.Join(repoPath, ".quill", "objects", "ee", wrongHash[2:]), stored, 0600)
+		if err != nil {
+			t.Fatalf("Failed to write object: %v", err)
+		}
+
+		_, _, err = ReadObject(repoPath, wrongHash)
+		if err != nil {
+			t.Errorf("Expected ReadObject not to verify by default, got %v", err)
+		}
+
+		_, _, err = ReadVerifiedObject(repoPath, wrongHash)
+		if !errors.Is(err, ErrHashMismatch) {
+			t.Errorf("Expected ErrHashMismatch, got %v", err)
+		}
+
+		_, _, err = ReadVerifiedObject(repoPath, expectedHash)
+		if err != nil {
+			t.Errorf("ReadVerifiedObject failed on a valid object: %v", err)
+		}
+
+		// Temporary files from interrupted writes are not objects
+		err = os.WriteFile(filepath.Join(repoPath, ".quill", "objects", "ee", "tmp_partial"), nil, 0600)
+		if err != nil {
+			t.Fatalf("Failed to write temporary file: %v", err)
+		}
+
+		hashes, err := ListObjects(repoPath)
+		if err != nil {
+			t.Fatalf("ListObjects failed: %v", err)
+		}
+
+		found := map[string]bool{}
+		for _, h := range hashes {
+			found[h] = true
+		}
+		if !found[expectedHash] || !found[wrongHash] || found["eetmp_partial"] {
+			t.Errorf("Unexpected object list: %v", hashes)
+		}
+	})
+
+	t.Run("ObjectFormat", func(t *testing.T) {
+		blakeRepo := filepath.Join(repoPath, "blake")
+
+		err := os.MkdirAll(filepath.Join(blakeRepo, ".quill", "objects"), os.ModePerm)
+		if err != nil {
+			t.Fatalf("Failed to create .quill directory: %v", err)
+		}
+
+		err = repo.CreateCoreConfig(blakeRepo, hash.BLAKE2b)
+		if err != nil {
+			t.Fatalf("Failed to write core config: %v", err)
+		}
+
+		objectHash, err := CreateObject(blakeRepo, constants.BlobObject, data)
+		if err != nil {
+			t.Fatalf("CreateObject failed: %v", err)
+		}
+
+		expected, err := hash.ComputeBLAKE2([]byte("blob 19\x00This is a test blob"))
+		if err != nil {
+			t.Fatalf("Failed to compute BLAKE2 hash: %v", err)
+		}
+
+		if objectHash != expected {
+			t.Errorf("Object hash mismatch: got %s, want %s", objectHash, expected)
+		}
+
+		// The format is read once per repository, not for every object
+		err = os.RemoveAll(filepath.Join(blakeRepo, ".quill", "config"))
+		if err != nil {
+			t.Fatalf("Failed to remove config: %v", err)
+		}
+
+		hasher, err := ObjectHasher(blakeRepo)
+		if err != nil {
+			t.Fatalf("ObjectHasher failed: %v", err)
+		}
+
+		if hasher.Name() != hash.BLAKE2b {
+			t.Errorf("Expected the cached %s hasher, got %s", hash.BLAKE2b, hasher.Name())
+		}
+	})
 }
@@ -0,0 +1,105 @@
+// Package testutil holds the fixtures shared by the package tests: throwaway repositories and the
+// working tree files and index entries tests set up in them.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/repo"
+)
+
+// NewRepo creates a fresh repository and makes it the working directory for the test
+func NewRepo(t *testing.T) string {
+	t.Helper()
+
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	err = repo.CreateQuillRepository(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(originalDir); err != nil {
+			t.Errorf("Failed to restore original directory: %v", err)
+		}
+	})
+
+	return tempDir
+}
+
+// WriteFile replaces the content of a working tree file, creating its directories
+func WriteFile(t *testing.T, repoPath, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(repoPath, name)
+
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", name, err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+// Stage writes a file and adds it to the index
+func Stage(t *testing.T, repoPath, name, content string) {
+	t.Helper()
+
+	WriteFile(t, repoPath, name, content)
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	err = idx.AddFile(repoPath, filepath.Join(repoPath, name))
+	if err != nil {
+		t.Fatalf("Failed to add %s: %v", name, err)
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+}
+
+// AssertFile checks the content of a working tree file, or that it is missing for an empty want
+func AssertFile(t *testing.T, repoPath, name, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(repoPath, name))
+	if want == "" {
+		if !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be missing, got %q", name, data)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+
+	if string(data) != want {
+		t.Errorf("Expected %s to contain %q, got %q", name, want, data)
+	}
+}
@@ -0,0 +1,117 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/ignore"
+	"github.com/tejastn10/quill/pkg/index"
+)
+
+// AddOptions controls which working tree changes AddPath stages
+type AddOptions struct {
+	// Force stages paths matched by the ignore rules
+	Force bool
+
+	// Update only stages modifications and removals of files the index already tracks
+	Update bool
+}
+
+// AddPath stages the file or directory at relPath in idx. Tracked files at or below relPath that no longer
+// exist have their removal staged, and their paths are returned.
+func AddPath(repoPath string, idx *index.Index, ignores *ignore.Matcher, relPath string, options AddOptions) ([]string, error) {
+	absPath := filepath.Join(repoPath, relPath)
+
+	info, err := os.Stat(absPath)
+	if os.IsNotExist(err) {
+		// A vanished path stages the removal of whatever was tracked there
+		if len(idx.PathsUnder(relPath)) == 0 {
+			return nil, fmt.Errorf("pathspec %q did not match any files", relPath)
+		}
+		return idx.RemoveMissing(repoPath, relPath), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %q: %v", absPath, err)
+	}
+
+	// Explicitly named paths that are ignored need Force, like git
+	ignored, err := ignores.IsIgnored(relPath, info.IsDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ignore rules for %q: %v", relPath, err)
+	}
+
+	_, tracked := idx.Entries[relPath]
+	if ignored && !tracked && !options.Force && !options.Update && relPath != "." {
+		return nil, fmt.Errorf("the path %q is ignored by %s, use --force to add it", relPath, ignore.FileName)
+	}
+
+	if !info.IsDir() {
+		if options.Update && !tracked {
+			return nil, nil
+		}
+
+		err = idx.AddFile(repoPath, absPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %q: %v", absPath, err)
+		}
+		return nil, nil
+	}
+
+	// Recursively add files in the directory
+	err = filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(repoPath, path)
+		if err != nil {
+			return err
+		}
+
+		// The repository metadata is never added, even with Force
+		first, _, _ := strings.Cut(filepath.ToSlash(relPath), "/")
+		if first == ".quill" {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		_, tracked := idx.Entries[relPath]
+
+		// Update only looks at files the index already knows
+		if options.Update && !info.IsDir() && !tracked {
+			return nil
+		}
+
+		if !options.Force && path != absPath {
+			ignored, err := ignores.IsIgnored(relPath, info.IsDir())
+			if err != nil {
+				return err
+			}
+
+			if ignored && info.IsDir() {
+				return filepath.SkipDir
+			}
+			if ignored && !tracked {
+				return nil
+			}
+		}
+
+		if info.Mode().IsRegular() {
+			err = idx.AddFile(repoPath, path)
+			if err != nil {
+				return fmt.Errorf("failed to add %q: %v", path, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add directory %q: %v", absPath, err)
+	}
+
+	return idx.RemoveMissing(repoPath, relPath), nil
+}
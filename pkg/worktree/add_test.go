@@ -0,0 +1,183 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/ignore"
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// stagePath stages relPath and saves the index like the add command, returning it and the paths whose removal was staged
+func stagePath(t *testing.T, repoPath, relPath string, options AddOptions) (*index.Index, []string) {
+	t.Helper()
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	removed, err := AddPath(repoPath, idx, ignore.NewMatcher(repoPath), relPath, options)
+	if err != nil {
+		t.Fatalf("AddPath failed: %v", err)
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	return idx, removed
+}
+
+func indexPaths(idx *index.Index) []string {
+	var paths []string
+	for path := range idx.Entries {
+		paths = append(paths, filepath.ToSlash(path))
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func TestAddPathStagesEverything(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, "kept.txt", "kept\n")
+	testutil.Stage(t, repoPath, filepath.Join("dir", "gone.txt"), "gone\n")
+
+	err := os.Remove(filepath.Join(repoPath, "dir", "gone.txt"))
+	if err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	testutil.WriteFile(t, repoPath, "new.txt", "new\n")
+	testutil.WriteFile(t, repoPath, ignore.FileName, "*.log\n")
+	testutil.WriteFile(t, repoPath, "debug.log", "noise\n")
+
+	// Adding the whole working tree, which is what -A does, picks up new files and removals alike
+	idx, removed := stagePath(t, repoPath, ".", AddOptions{})
+
+	if !reflect.DeepEqual(removed, []string{filepath.Join("dir", "gone.txt")}) {
+		t.Errorf("Expected the removal of dir/gone.txt to be staged, got %v", removed)
+	}
+
+	want := []string{ignore.FileName, "kept.txt", "new.txt"}
+	if got := indexPaths(idx); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected index %v, got %v", want, got)
+	}
+}
+
+func TestAddPathUpdate(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, "changed.txt", "one\n")
+	testutil.Stage(t, repoPath, "gone.txt", "gone\n")
+
+	before, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	testutil.WriteFile(t, repoPath, "changed.txt", "two\n")
+	testutil.WriteFile(t, repoPath, "untracked.txt", "new\n")
+	err = os.Remove(filepath.Join(repoPath, "gone.txt"))
+	if err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	// -u updates and removes tracked files but leaves untracked ones alone
+	idx, removed := stagePath(t, repoPath, ".", AddOptions{Update: true})
+
+	if !reflect.DeepEqual(removed, []string{"gone.txt"}) {
+		t.Errorf("Expected the removal of gone.txt to be staged, got %v", removed)
+	}
+
+	if got := indexPaths(idx); !reflect.DeepEqual(got, []string{"changed.txt"}) {
+		t.Errorf("Expected only changed.txt to be tracked, got %v", got)
+	}
+
+	if idx.Entries["changed.txt"].Hash == before.Entries["changed.txt"].Hash {
+		t.Errorf("Expected the modification of changed.txt to be staged")
+	}
+
+	// Naming an untracked file with -u stages nothing either
+	idx, _ = stagePath(t, repoPath, "untracked.txt", AddOptions{Update: true})
+	if _, ok := idx.Entries["untracked.txt"]; ok {
+		t.Errorf("Expected untracked.txt to stay untracked")
+	}
+}
+
+func TestAddPathDirectoryRemovals(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	testutil.Stage(t, repoPath, filepath.Join("src", "a.txt"), "a\n")
+	testutil.Stage(t, repoPath, filepath.Join("src", "b.txt"), "b\n")
+	testutil.Stage(t, repoPath, "other.txt", "other\n")
+
+	for _, name := range []string{filepath.Join("src", "b.txt"), "other.txt"} {
+		err := os.Remove(filepath.Join(repoPath, name))
+		if err != nil {
+			t.Fatalf("Failed to remove %s: %v", name, err)
+		}
+	}
+
+	// Only removals inside the added directory are staged
+	idx, removed := stagePath(t, repoPath, "src", AddOptions{})
+
+	if !reflect.DeepEqual(removed, []string{filepath.Join("src", "b.txt")}) {
+		t.Errorf("Expected the removal of src/b.txt to be staged, got %v", removed)
+	}
+
+	want := []string{"other.txt", "src/a.txt"}
+	if got := indexPaths(idx); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected index %v, got %v", want, got)
+	}
+
+	// A directory that vanished entirely stages the removal of everything below it
+	err := os.RemoveAll(filepath.Join(repoPath, "src"))
+	if err != nil {
+		t.Fatalf("Failed to remove src: %v", err)
+	}
+
+	idx, removed = stagePath(t, repoPath, "src", AddOptions{})
+	if !reflect.DeepEqual(removed, []string{filepath.Join("src", "a.txt")}) {
+		t.Errorf("Expected the removal of src/a.txt to be staged, got %v", removed)
+	}
+	if _, ok := idx.Entries[filepath.Join("src", "a.txt")]; ok {
+		t.Errorf("Expected src/a.txt to be dropped from the index")
+	}
+}
+
+func TestAddPathErrors(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	ignores := ignore.NewMatcher(repoPath)
+
+	_, err = AddPath(repoPath, idx, ignores, "missing.txt", AddOptions{})
+	if err == nil {
+		t.Errorf("Expected an error for a path that matches nothing")
+	}
+
+	testutil.WriteFile(t, repoPath, ignore.FileName, "*.log\n")
+	testutil.WriteFile(t, repoPath, "debug.log", "noise\n")
+
+	_, err = AddPath(repoPath, idx, ignores, "debug.log", AddOptions{})
+	if err == nil {
+		t.Errorf("Expected an error for an ignored path")
+	}
+
+	_, err = AddPath(repoPath, idx, ignores, "debug.log", AddOptions{Force: true})
+	if err != nil {
+		t.Fatalf("AddPath with Force failed: %v", err)
+	}
+	if _, ok := idx.Entries["debug.log"]; !ok {
+		t.Errorf("Expected Force to stage the ignored file")
+	}
+}
@@ -0,0 +1,201 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// fileState describes a path in HEAD, the index, the working tree or the target tree
+type fileState struct {
+	Exists bool
+	Hash   string
+	Mode   string
+}
+
+// ConflictError lists the paths whose local changes a checkout would overwrite
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("your local changes to the following files would be overwritten:\n\t%s\nCommit your changes or use --force to discard them", strings.Join(e.Paths, "\n\t"))
+}
+
+// treeState returns the state of a path in a tree
+func treeState(entries map[string]objects.TreeEntry, path string) fileState {
+	entry, exists := entries[path]
+	return fileState{Exists: exists, Hash: entry.Hash, Mode: entry.Mode}
+}
+
+// indexState returns the state of a path in the index
+func indexState(idx *index.Index, path string) fileState {
+	entry, exists := idx.Entries[path]
+	return fileState{Exists: exists, Hash: entry.Hash, Mode: entry.Mode}
+}
+
+// workingState returns the state of a path in the working tree
+func workingState(repoPath, path string) (fileState, error) {
+	cleanPath, err := resolvePath(repoPath, path)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	info, err := os.Stat(cleanPath)
+	if os.IsNotExist(err) || (err == nil && !info.Mode().IsRegular()) {
+		return fileState{}, nil
+	}
+	if err != nil {
+		return fileState{}, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	fileHash, mode, err := HashFile(repoPath, path)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{Exists: true, Hash: fileHash, Mode: mode}, nil
+}
+
+// Checkout replaces the working tree and index contents of headTree with those of targetTree.
+// Without force, paths that differ between the two trees must not carry local changes; local
+// changes to every other path are kept. With force, the index and tracked files are reset to
+// targetTree outright.
+func Checkout(repoPath, headTree, targetTree string, force bool) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	headEntries, err := objects.GetTreeEntries(repoPath, headTree)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	targetEntries, err := objects.GetTreeEntries(repoPath, targetTree)
+	if err != nil {
+		return fmt.Errorf("failed to read target tree: %w", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	// Collect every path that might need to change
+	pathSet := make(map[string]bool)
+	for path := range headEntries {
+		pathSet[path] = true
+	}
+	for path := range targetEntries {
+		pathSet[path] = true
+	}
+	if force {
+		for path := range idx.Entries {
+			pathSet[path] = true
+		}
+	}
+
+	var paths []string
+	for path := range pathSet {
+		if force || treeState(headEntries, path) != treeState(targetEntries, path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	if !force {
+		var conflicts []string
+
+		for _, path := range paths {
+			safe, err := canOverwrite(repoPath, idx, path, treeState(headEntries, path), treeState(targetEntries, path))
+			if err != nil {
+				return err
+			}
+			if !safe {
+				conflicts = append(conflicts, path)
+			}
+		}
+
+		if len(conflicts) > 0 {
+			return &ConflictError{Paths: conflicts}
+		}
+	}
+
+	// Bring the working tree and index in line with the target tree
+	for _, path := range paths {
+		target := treeState(targetEntries, path)
+
+		if !target.Exists {
+			// Only files committed in HEAD are removed, anything else just becomes untracked
+			if treeState(headEntries, path).Exists {
+				err = RemoveFile(repoPath, path)
+				if err != nil {
+					return err
+				}
+			}
+			delete(idx.Entries, path)
+			continue
+		}
+
+		working, err := workingState(repoPath, path)
+		if err != nil {
+			return err
+		}
+
+		if working != target {
+			err = WriteFile(repoPath, path, target.Hash, target.Mode)
+			if err != nil {
+				return err
+			}
+		}
+
+		idx.Entries[path] = index.IndexEntry{
+			Path: path,
+			Hash: target.Hash,
+			Mode: target.Mode,
+		}
+	}
+
+	idx.LastCommitTree = targetTree
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	return nil
+}
+
+// canOverwrite reports whether a path can move from its HEAD state to its target state without losing local changes
+func canOverwrite(repoPath string, idx *index.Index, path string, head, target fileState) (bool, error) {
+	staged := indexState(idx, path)
+
+	working, err := workingState(repoPath, path)
+	if err != nil {
+		return false, err
+	}
+
+	// The working tree already holds the target version
+	if working == target && (staged == head || staged == target) {
+		return true, nil
+	}
+
+	// Staged changes would be lost
+	if staged != head {
+		return false, nil
+	}
+
+	// An untracked file would be overwritten
+	if !staged.Exists {
+		return !working.Exists || !target.Exists, nil
+	}
+
+	// Unstaged modifications would be lost; a deleted file is simply restored
+	return !working.Exists || working == staged, nil
+}
@@ -0,0 +1,132 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+// commitFiles writes the given files, stages them and commits, returning the commit tree
+func commitFiles(t *testing.T, repoPath string, files map[string]string) string {
+	t.Helper()
+
+	for name, content := range files {
+		testutil.Stage(t, repoPath, name, content)
+	}
+
+	commitHash, err := objects.CreateCommit(repoPath, "test", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	commit, err := objects.ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	return commit.Tree
+}
+
+// removeFromIndex drops a path from the index and the working tree
+func removeFromIndex(t *testing.T, repoPath, name string) {
+	t.Helper()
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	delete(idx.Entries, name)
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	err = os.Remove(filepath.Join(repoPath, name))
+	if err != nil {
+		t.Fatalf("Failed to remove %s: %v", name, err)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n", "dir/b.txt": "b\n"})
+	removeFromIndex(t, repoPath, "dir/b.txt")
+	secondTree := commitFiles(t, repoPath, map[string]string{"a.txt": "two\n", "c.txt": "c\n"})
+
+	// Go back to the first tree
+	err := Checkout(repoPath, secondTree, firstTree, false)
+	if err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n")
+	testutil.AssertFile(t, repoPath, "dir/b.txt", "b\n")
+
+	if _, err := os.Stat(filepath.Join(repoPath, "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected c.txt to be removed")
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if len(idx.Entries) != 2 || idx.LastCommitTree != firstTree {
+		t.Errorf("Index does not match the checked out tree: %+v", idx)
+	}
+}
+
+func TestCheckoutRefusesToOverwriteLocalChanges(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n"})
+	secondTree := commitFiles(t, repoPath, map[string]string{"a.txt": "two\n"})
+
+	err := os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("local\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to modify a.txt: %v", err)
+	}
+
+	err = Checkout(repoPath, secondTree, firstTree, false)
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Paths) != 1 || conflict.Paths[0] != "a.txt" {
+		t.Fatalf("Expected a conflict on a.txt, got %v", err)
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "local\n")
+
+	// Forcing discards the local change
+	err = Checkout(repoPath, secondTree, firstTree, true)
+	if err != nil {
+		t.Fatalf("Forced checkout failed: %v", err)
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n")
+}
+
+func TestCheckoutKeepsUnrelatedLocalChanges(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n", "notes.txt": "n\n"})
+	secondTree := commitFiles(t, repoPath, map[string]string{"a.txt": "two\n"})
+
+	err := os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("edited\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to modify notes.txt: %v", err)
+	}
+
+	err = Checkout(repoPath, secondTree, firstTree, false)
+	if err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n")
+	testutil.AssertFile(t, repoPath, "notes.txt", "edited\n")
+}
@@ -0,0 +1,149 @@
+package worktree
+
+import (
+	"fmt"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+)
+
+// ResetIndex replaces the index with the contents of a tree, leaving the working tree untouched
+func ResetIndex(repoPath, treeHash string) error {
+	entries, err := objects.GetTreeEntries(repoPath, treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read target tree: %w", err)
+	}
+
+	idx := &index.Index{
+		Entries:        make(map[string]index.IndexEntry, len(entries)),
+		LastCommitTree: treeHash,
+	}
+
+	for path, entry := range entries {
+		idx.Entries[path] = index.IndexEntry{Path: path, Hash: entry.Hash, Mode: entry.Mode}
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	return nil
+}
+
+// ResetHard makes the index and the tracked files of the working tree match a tree, discarding local changes.
+// Files staged since headTree that the target doesn't have are removed; untracked files are left alone.
+func ResetHard(repoPath, headTree, treeHash string) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	headEntries, err := objects.GetTreeEntries(repoPath, headTree)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	targetEntries, err := objects.GetTreeEntries(repoPath, treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read target tree: %w", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	err = Checkout(repoPath, headTree, treeHash, true)
+	if err != nil {
+		return err
+	}
+
+	// Checkout keeps newly staged files as untracked, a hard reset discards them
+	for path := range idx.Entries {
+		_, inHead := headEntries[path]
+		_, inTarget := targetEntries[path]
+
+		if !inHead && !inTarget {
+			err = RemoveFile(repoPath, path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreStaged resets the index entries of the given paths to their version in a tree, unstaging them
+func RestoreStaged(repoPath, treeHash string, paths []string) error {
+	unlock, err := index.Lock(repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := objects.GetTreeEntries(repoPath, treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	for _, path := range paths {
+		entry, exists := entries[path]
+		if !exists {
+			// A newly added file becomes untracked again
+			delete(idx.Entries, path)
+			continue
+		}
+
+		idx.Entries[path] = index.IndexEntry{Path: path, Hash: entry.Hash, Mode: entry.Mode}
+	}
+
+	err = idx.SaveIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreFiles discards working tree changes to the given paths by writing their index version
+func RestoreFiles(repoPath string, paths []string) error {
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	for _, path := range paths {
+		entry, exists := idx.Entries[path]
+		if !exists {
+			return fmt.Errorf("path %q is not in the index", path)
+		}
+
+		if entry.Conflict != nil {
+			return fmt.Errorf("path %q is unmerged", path)
+		}
+
+		working, err := workingState(repoPath, path)
+		if err != nil {
+			return err
+		}
+
+		if working == indexState(idx, path) {
+			continue
+		}
+
+		err = WriteFile(repoPath, path, entry.Hash, entry.Mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
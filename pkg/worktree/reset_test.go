@@ -0,0 +1,159 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/index"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/refs"
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+func TestResetIndex(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n"})
+	commitFiles(t, repoPath, map[string]string{"a.txt": "two\n", "b.txt": "b\n"})
+
+	err := ResetIndex(repoPath, firstTree)
+	if err != nil {
+		t.Fatalf("ResetIndex failed: %v", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if len(idx.Entries) != 1 || idx.LastCommitTree != firstTree {
+		t.Errorf("Index does not match the reset tree: %+v", idx)
+	}
+
+	// The working tree keeps the newer content
+	testutil.AssertFile(t, repoPath, "a.txt", "two\n")
+	testutil.AssertFile(t, repoPath, "b.txt", "b\n")
+}
+
+func TestResetHard(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n"})
+	secondTree := commitFiles(t, repoPath, map[string]string{"a.txt": "two\n", "b.txt": "b\n"})
+
+	err := os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("local\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to modify a.txt: %v", err)
+	}
+	testutil.Stage(t, repoPath, "staged.txt", "staged\n")
+
+	err = os.WriteFile(filepath.Join(repoPath, "untracked.txt"), []byte("keep\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write untracked.txt: %v", err)
+	}
+
+	err = ResetHard(repoPath, secondTree, firstTree)
+	if err != nil {
+		t.Fatalf("ResetHard failed: %v", err)
+	}
+
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n")
+	testutil.AssertFile(t, repoPath, "untracked.txt", "keep\n")
+
+	for _, name := range []string{"b.txt", "staged.txt"} {
+		if _, err := os.Stat(filepath.Join(repoPath, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	headTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n"})
+	testutil.Stage(t, repoPath, "a.txt", "two\n")
+	testutil.Stage(t, repoPath, "new.txt", "new\n")
+
+	err := RestoreStaged(repoPath, headTree, []string{"a.txt", "new.txt"})
+	if err != nil {
+		t.Fatalf("RestoreStaged failed: %v", err)
+	}
+
+	idx, err := index.LoadIndex(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if _, staged := idx.Entries["new.txt"]; staged {
+		t.Errorf("Expected new.txt to be unstaged")
+	}
+
+	// Unstaging leaves the working tree alone
+	testutil.AssertFile(t, repoPath, "a.txt", "two\n")
+
+	err = RestoreFiles(repoPath, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("RestoreFiles failed: %v", err)
+	}
+	testutil.AssertFile(t, repoPath, "a.txt", "one\n")
+
+	err = RestoreFiles(repoPath, []string{"new.txt"})
+	if err == nil {
+		t.Errorf("Expected restoring an untracked path to fail")
+	}
+}
+
+func TestCommitAfterSoftReset(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	firstTree := commitFiles(t, repoPath, map[string]string{"a.txt": "one\n"})
+	first, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+
+	commitFiles(t, repoPath, map[string]string{"a.txt": "two\n", "b.txt": "b\n"})
+	second, err := refs.ResolveHEAD(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+
+	// reset --soft HEAD~1 only moves the branch, the index keeps the undone commit staged
+	err = refs.AdvanceHEAD(repoPath, second, first)
+	if err != nil {
+		t.Fatalf("Failed to move HEAD: %v", err)
+	}
+
+	// HEAD has no b.txt, so unstaging it drops it from the index
+	err = RestoreStaged(repoPath, firstTree, []string{"b.txt"})
+	if err != nil {
+		t.Fatalf("RestoreStaged failed: %v", err)
+	}
+
+	changed, err := objects.HasStagedChanges(repoPath)
+	if err != nil {
+		t.Fatalf("HasStagedChanges failed: %v", err)
+	}
+	if !changed {
+		t.Fatalf("Expected the reset commit's changes to be staged")
+	}
+
+	commitHash, err := objects.CreateCommit(repoPath, "redo", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to commit after a soft reset: %v", err)
+	}
+
+	commit, err := objects.ReadCommit(repoPath, commitHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	entries, err := objects.GetTreeEntries(repoPath, commit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to read tree: %v", err)
+	}
+	if _, exists := entries["b.txt"]; exists || len(entries) != 1 || commit.FirstParent() != first {
+		t.Errorf("Expected a child of the first commit with only a.txt, got %+v with %v", commit, entries)
+	}
+}
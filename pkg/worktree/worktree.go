@@ -0,0 +1,220 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tejastn10/quill/pkg/constants"
+	"github.com/tejastn10/quill/pkg/ignore"
+	"github.com/tejastn10/quill/pkg/objects"
+	"github.com/tejastn10/quill/pkg/repo"
+	"github.com/tejastn10/quill/pkg/storage"
+)
+
+// FileMode formats the permission bits of a file the way index entries record them
+func FileMode(info os.FileInfo) string {
+	return fmt.Sprintf("%o", info.Mode().Perm())
+}
+
+// ListFiles returns every regular file in the working tree keyed by its path relative to the repository root.
+// Paths matched by ignores are skipped, pass nil to list everything.
+func ListFiles(repoPath string, ignores *ignore.Matcher) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+
+	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == repoPath {
+			return nil
+		}
+
+		// Never descend into the repository metadata
+		if info.IsDir() && info.Name() == ".quill" {
+			return filepath.SkipDir
+		}
+
+		relPath, err := filepath.Rel(repoPath, path)
+		if err != nil {
+			return err
+		}
+
+		if ignores != nil {
+			ignored, err := ignores.IsIgnored(relPath, info.IsDir())
+			if err != nil {
+				return err
+			}
+
+			if ignored && info.IsDir() {
+				return filepath.SkipDir
+			}
+			if ignored {
+				return nil
+			}
+		}
+
+		if info.Mode().IsRegular() {
+			files[relPath] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// resolvePath returns the absolute location of a working tree path, rejecting paths that escape the working tree
+func resolvePath(repoPath, relPath string) (string, error) {
+	if !filepath.IsLocal(relPath) || strings.SplitN(filepath.ToSlash(relPath), "/", 2)[0] == ".quill" {
+		return "", fmt.Errorf("invalid path %q: outside the working tree", relPath)
+	}
+
+	cleanPath := filepath.Clean(filepath.Join(repoPath, relPath))
+
+	if !repo.IsPathSafe(cleanPath) {
+		return "", fmt.Errorf("invalid file path: potential directory traversal attempt")
+	}
+
+	return cleanPath, nil
+}
+
+// ReadFile returns the content of a file in the working tree
+func ReadFile(repoPath, relPath string) ([]byte, error) {
+	cleanPath, err := resolvePath(repoPath, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", relPath, err)
+	}
+
+	return data, nil
+}
+
+// HashFile computes the hash and mode of a file in the working tree
+func HashFile(repoPath, relPath string) (string, string, error) {
+	cleanPath, err := resolvePath(repoPath, relPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to stat %q: %w", relPath, err)
+	}
+
+	data, err := os.ReadFile(cleanPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %q: %w", relPath, err)
+	}
+
+	hasher, err := storage.ObjectHasher(repoPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	return storage.HashObject(hasher, constants.BlobObject, data), FileMode(info), nil
+}
+
+// WriteFile materializes a blob into the working tree with the recorded mode
+func WriteFile(repoPath, relPath, blobHash, mode string) error {
+	cleanPath, err := resolvePath(repoPath, relPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := objects.ReadBlob(repoPath, blobHash)
+	if err != nil {
+		return fmt.Errorf("failed to read blob for %q: %w", relPath, err)
+	}
+
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid mode %q for %q: %w", mode, relPath, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(cleanPath), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", relPath, err)
+	}
+
+	// Replace rather than truncate, so a read-only file can be rewritten
+	err = os.Remove(cleanPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %q: %w", relPath, err)
+	}
+
+	err = os.WriteFile(cleanPath, data, constants.ConfigFilePerms)
+	if err != nil {
+		return fmt.Errorf("failed to write %q: %w", relPath, err)
+	}
+
+	// Restore the permissions recorded in the tree
+	// #nosec G302 - The mode comes from the committed tree entry
+	err = os.Chmod(cleanPath, os.FileMode(perm).Perm())
+	if err != nil {
+		return fmt.Errorf("failed to set mode of %q: %w", relPath, err)
+	}
+
+	return nil
+}
+
+// RemoveFile deletes a file from the working tree along with any directories left empty
+func RemoveFile(repoPath, relPath string) error {
+	cleanPath, err := resolvePath(repoPath, relPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(cleanPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %q: %w", relPath, err)
+	}
+
+	removeEmptyParents(repoPath, cleanPath)
+	return nil
+}
+
+// MoveFile renames a file or directory in the working tree, creating the parent directories of the destination
+func MoveFile(repoPath, src, dst string) error {
+	srcPath, err := resolvePath(repoPath, src)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := resolvePath(repoPath, dst)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dstPath), constants.DirectoryPerms)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", dst, err)
+	}
+
+	err = os.Rename(srcPath, dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to move %q to %q: %w", src, dst, err)
+	}
+
+	removeEmptyParents(repoPath, srcPath)
+	return nil
+}
+
+// removeEmptyParents deletes the directories above path that are left empty, stopping at the repository root
+func removeEmptyParents(repoPath, path string) {
+	root := filepath.Clean(repoPath)
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // Not empty
+		}
+	}
+}
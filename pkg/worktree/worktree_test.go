@@ -0,0 +1,31 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tejastn10/quill/pkg/testutil"
+)
+
+func TestMoveFile(t *testing.T) {
+	repoPath := testutil.NewRepo(t)
+
+	commitFiles(t, repoPath, map[string]string{"dir/sub/a.txt": "a\n"})
+
+	err := MoveFile(repoPath, "dir", filepath.Join("nested", "moved"))
+	if err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+
+	testutil.AssertFile(t, repoPath, filepath.Join("nested", "moved", "sub", "a.txt"), "a\n")
+
+	if _, err := os.Stat(filepath.Join(repoPath, "dir")); !os.IsNotExist(err) {
+		t.Errorf("Expected dir to be gone")
+	}
+
+	err = MoveFile(repoPath, "nested", filepath.Join("..", "escaped"))
+	if err == nil {
+		t.Errorf("Expected moving outside the working tree to fail")
+	}
+}